@@ -50,8 +50,48 @@ func BatchOrLegacySingle(cfg *config.Configuration, inobj *ObjectResource, opera
 	return nil, "", fmt.Errorf("Object not found")
 }
 
-// Batch calls the batch API and returns object results
+// Batch calls the batch API and returns object results. If there are more
+// objects than lfs.batch.maxobjects allows in one request, they are sent in
+// several requests, one after the other, and their results are merged. An
+// error from any of them fails the whole call.
 func Batch(cfg *config.Configuration, objects []*ObjectResource, operation string, transferAdapters []string) (objs []*ObjectResource, transferAdapter string, e error) {
+	max := cfg.BatchMaxObjects()
+	if max < 1 || len(objects) <= max {
+		return batch(cfg, objects, operation, transferAdapters)
+	}
+
+	chunks := (len(objects) + max - 1) / max
+	objs = make([]*ObjectResource, 0, len(objects))
+	for i := 0; i < chunks; i++ {
+		chunk := objects[i*max:]
+		if len(chunk) > max {
+			chunk = chunk[:max]
+		}
+
+		tracerx.Printf("api: batch request %d of %d", i+1, chunks)
+		chunkObjs, adapterName, err := batch(cfg, chunk, operation, transferAdapters)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "batch request %d of %d", i+1, chunks)
+		}
+
+		if i == 0 {
+			// The objects are all transferred with the same adapter,
+			// so the rest of the requests only offer the one the
+			// server picked for the first
+			transferAdapter = adapterName
+			if len(adapterName) == 0 {
+				adapterName = "basic"
+			}
+			transferAdapters = []string{adapterName}
+		}
+		objs = append(objs, chunkObjs...)
+	}
+
+	return objs, transferAdapter, nil
+}
+
+// batch makes a single batch API request for all of the given objects.
+func batch(cfg *config.Configuration, objects []*ObjectResource, operation string, transferAdapters []string) (objs []*ObjectResource, transferAdapter string, e error) {
 	if len(objects) == 0 {
 		return nil, "", nil
 	}
@@ -93,7 +133,7 @@ func Batch(cfg *config.Configuration, objects []*ObjectResource, operation strin
 
 		if errors.IsAuthError(err) {
 			httputil.SetAuthType(cfg, req, res)
-			return Batch(cfg, objects, operation, transferAdapters)
+			return batch(cfg, objects, operation, transferAdapters)
 		}
 
 		switch res.StatusCode {
@@ -113,6 +153,41 @@ func Batch(cfg *config.Configuration, objects []*ObjectResource, operation strin
 	return bresp.Objects, bresp.TransferAdapterName, nil
 }
 
+// BatchCheck asks the server which of objects it can provide, without
+// downloading them, and returns the OIDs of those it has. It makes a batch
+// request for each chunkSize objects, stopping at the first that fails.
+func BatchCheck(cfg *config.Configuration, objects []*ObjectResource, chunkSize int) ([]string, error) {
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	present := make([]string, 0, len(objects))
+	for len(objects) > 0 {
+		n := chunkSize
+		if n > len(objects) {
+			n = len(objects)
+		}
+
+		objs, _, err := Batch(cfg, objects[:n], "download", nil)
+		if err != nil {
+			return present, err
+		}
+
+		for _, o := range objs {
+			if o.Error != nil {
+				tracerx.Printf("api: %s not available: %s", o.Oid, o.Error)
+				continue
+			}
+			if _, ok := o.Rel("download"); ok {
+				present = append(present, o.Oid)
+			}
+		}
+		objects = objects[n:]
+	}
+
+	return present, nil
+}
+
 // Legacy calls the legacy API serially and returns ObjectResources
 // TODO LEGACY API: remove when legacy API removed
 func Legacy(cfg *config.Configuration, objects []*ObjectResource, operation string) ([]*ObjectResource, error) {
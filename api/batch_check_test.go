@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchCheckReportsPresentObjects(t *testing.T) {
+	present := map[string]bool{"oid1": true, "oid3": true, "oid4": true}
+	var requests [][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Operation string                `json:"operation"`
+			Objects   []*api.ObjectResource `json:"objects"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		assert.Equal(t, "download", req.Operation)
+
+		oids := make([]string, 0, len(req.Objects))
+		objs := make([]*api.ObjectResource, 0, len(req.Objects))
+		for _, o := range req.Objects {
+			oids = append(oids, o.Oid)
+			obj := &api.ObjectResource{Oid: o.Oid, Size: o.Size}
+			if present[o.Oid] {
+				obj.Actions = map[string]*api.LinkRelation{
+					"download": &api.LinkRelation{Href: "https://example.com/" + o.Oid},
+				}
+			} else {
+				obj.Error = &api.ObjectError{Code: 404, Message: "Object does not exist"}
+			}
+			objs = append(objs, obj)
+		}
+		requests = append(requests, oids)
+
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": objs})
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url": server.URL + "/media",
+		},
+	})
+
+	objects := []*api.ObjectResource{
+		{Oid: "oid1", Size: 1},
+		{Oid: "oid2", Size: 2},
+		{Oid: "oid3", Size: 3},
+		{Oid: "oid4", Size: 4},
+		{Oid: "oid5", Size: 5},
+	}
+
+	oids, err := api.BatchCheck(cfg, objects, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"oid1", "oid3", "oid4"}, oids)
+	assert.Equal(t, [][]string{
+		{"oid1", "oid2"},
+		{"oid3", "oid4"},
+		{"oid5"},
+	}, requests)
+}
+
+func TestBatchCheckReturnsRequestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url": server.URL + "/media",
+		},
+	})
+
+	oids, err := api.BatchCheck(cfg, []*api.ObjectResource{{Oid: "oid1", Size: 1}}, 100)
+	assert.NotNil(t, err)
+	assert.Empty(t, oids)
+}
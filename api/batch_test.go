@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchSplitsRequestsOverMaxObjects(t *testing.T) {
+	var sizes []int
+	var transfers [][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Objects   []*api.ObjectResource `json:"objects"`
+			Transfers []string              `json:"transfers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		sizes = append(sizes, len(req.Objects))
+		transfers = append(transfers, req.Transfers)
+
+		for _, o := range req.Objects {
+			o.Actions = map[string]*api.LinkRelation{
+				"download": &api.LinkRelation{Href: "https://example.com/" + o.Oid},
+			}
+		}
+
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"transfer": "custom",
+			"objects":  req.Objects,
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url":              server.URL + "/media",
+			"lfs.batch.maxobjects": "100",
+		},
+	})
+
+	objects := make([]*api.ObjectResource, 0, 250)
+	for i := 0; i < 250; i++ {
+		objects = append(objects, &api.ObjectResource{Oid: fmt.Sprintf("oid%d", i), Size: 1})
+	}
+
+	objs, adapter, err := api.Batch(cfg, objects, "download", []string{"basic", "custom"})
+	require.Nil(t, err)
+	assert.Equal(t, "custom", adapter)
+	assert.Equal(t, []int{100, 100, 50}, sizes)
+	assert.Equal(t, [][]string{
+		{"basic", "custom"},
+		{"custom"},
+		{"custom"},
+	}, transfers)
+
+	require.Len(t, objs, 250)
+	for i, o := range objs {
+		assert.Equal(t, fmt.Sprintf("oid%d", i), o.Oid)
+	}
+}
+
+func TestBatchFailsIfAnyChunkFails(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 2 {
+			w.WriteHeader(500)
+			return
+		}
+
+		var req struct {
+			Objects []*api.ObjectResource `json:"objects"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": req.Objects})
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url":              server.URL + "/media",
+			"lfs.batch.maxobjects": "2",
+		},
+	})
+
+	objects := []*api.ObjectResource{
+		{Oid: "oid1", Size: 1},
+		{Oid: "oid2", Size: 2},
+		{Oid: "oid3", Size: 3},
+		{Oid: "oid4", Size: 4},
+		{Oid: "oid5", Size: 5},
+	}
+
+	objs, _, err := api.Batch(cfg, objects, "download", nil)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "batch request 2 of 3")
+	assert.Empty(t, objs)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/rubyist/tracerx"
+)
+
+// apiHosts limits the number of API requests in flight to each host, across
+// all the transfer queues in the process.
+var apiHosts = newHostLimiter()
+
+// hostLimiter is a set of semaphores keyed by host, used to cap the number of
+// concurrent control-plane connections to each LFS API server.
+type hostLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{hosts: make(map[string]chan struct{})}
+}
+
+// acquire blocks until a request to the host of req may be made, and returns
+// a func which must be called once the response has been read. If limit is
+// zero or less requests are not limited. The limit for a host is fixed by the
+// first request made to it.
+func (l *hostLimiter) acquire(req *http.Request, limit int) func() {
+	if limit < 1 {
+		return func() {}
+	}
+
+	host := req.URL.Host
+
+	l.mu.Lock()
+	sem, ok := l.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		l.hosts[host] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+	default:
+		tracerx.Printf("api: waiting for a connection to %s", host)
+		sem <- struct{}{}
+	}
+
+	return func() { <-sem }
+}
+
+// acquireApiConnection limits API requests per host according to
+// lfs.api.maxconnsperhost.
+func acquireApiConnection(cfg *config.Configuration, req *http.Request) func() {
+	return apiHosts.acquire(req, cfg.ApiMaxConnsPerHost())
+}
@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRespectsMaxConnsPerHost(t *testing.T) {
+	var inflight, maxInflight, calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		atomic.AddInt32(&calls, 1)
+
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+
+		// hold the connection so concurrent requests overlap
+		time.Sleep(20 * time.Millisecond)
+
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"objects": []*api.ObjectResource{{Oid: "oid", Size: 1}},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url":                 server.URL + "/media",
+			"lfs.api.maxconnsperhost": "2",
+		},
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := api.Batch(cfg, []*api.ObjectResource{{Oid: "oid", Size: 1}}, "download", nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err)
+	}
+	assert.EqualValues(t, 10, atomic.LoadInt32(&calls))
+	assert.True(t, maxInflight <= 2, "expected at most 2 concurrent requests, got %d", maxInflight)
+	assert.True(t, maxInflight > 0)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -81,6 +82,43 @@ func (s *LockService) Search(req *LockSearchRequest) (*RequestSchema, *LockList)
 	}, &resp
 }
 
+// Refresh generates a *RequestSchema that is used to extend the given lock
+// before it expires, by re-issuing the "attempt lock" API method for the same
+// path on behalf of the same committer.
+//
+// Servers which support lock expiry will respond with the lock and its new
+// ExpiresAt. Servers which don't will report that the lock already exists in
+// the Err field of the LockResponse.
+func (s *LockService) Refresh(lock *Lock) (*RequestSchema, *LockResponse) {
+	return s.Lock(&LockRequest{
+		Path:               lock.Path,
+		LatestRemoteCommit: lock.CommitSHA,
+		Committer:          lock.Committer,
+	})
+}
+
+// RefreshLock extends the given lock through the LockService's Refresh method,
+// and updates it in place with the server's copy, including its new ExpiresAt.
+//
+// If the server was unable to extend the lock, an error will be returned and
+// the lock will be left unchanged.
+func (c *Client) RefreshLock(lock *Lock) error {
+	s, resp := c.Locks.Refresh(lock)
+	if _, err := c.Do(s); err != nil {
+		return err
+	}
+
+	if len(resp.Err) > 0 {
+		return errors.New(resp.Err)
+	}
+	if resp.Lock == nil {
+		return fmt.Errorf("api: no lock returned when refreshing %q", lock.Path)
+	}
+
+	*lock = *resp.Lock
+	return nil
+}
+
 // Unlock generates a *RequestSchema that is used to preform the "unlock" API
 // method, against a particular lock potentially with --force.
 //
@@ -126,6 +164,11 @@ type Lock struct {
 	// the server can either a) not send this field, or b) send the
 	// zero-value of time.Time.
 	UnlockedAt time.Time `json:"unlocked_at,omitempty"`
+	// ExpiresAt is an optional parameter that represents the instant in
+	// time at which the server will release the lock unless it is
+	// refreshed. Servers which don't expire locks either don't send this
+	// field, or send the zero-value of time.Time.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
 }
 
 // Active returns whether or not the given lock is still active against the file
@@ -134,6 +177,20 @@ func (l *Lock) Active() bool {
 	return l.UnlockedAt.IsZero()
 }
 
+// ExpiresWithin returns whether or not the given lock is still active and will
+// expire within d, unless it is refreshed.
+func (l *Lock) ExpiresWithin(d time.Duration) bool {
+	if !l.Active() || l.ExpiresAt.IsZero() {
+		return false
+	}
+	return l.ExpiresAt.Sub(time.Now()) < d
+}
+
+// Age returns how long ago the lock was created, according to LockedAt.
+func (l *Lock) Age() time.Duration {
+	return time.Since(l.LockedAt)
+}
+
 // Committer represents a "First Last <email@domain.com>" pair.
 type Committer struct {
 	// Name is the name of the individual who would like to obtain the
@@ -254,3 +311,27 @@ type LockList struct {
 	// of nil will be passed here.
 	Err string `json:"error,omitempty"`
 }
+
+// OlderThan returns the locks in this list which were created more than d ago,
+// such as locks which may have been abandoned.
+func (l *LockList) OlderThan(d time.Duration) []Lock {
+	locks := make([]Lock, 0, len(l.Locks))
+	for _, lock := range l.Locks {
+		if lock.Age() > d {
+			locks = append(locks, lock)
+		}
+	}
+	return locks
+}
+
+// ExpiringWithin returns the locks in this list which will expire within d
+// unless they are refreshed.
+func (l *LockList) ExpiringWithin(d time.Duration) []Lock {
+	locks := make([]Lock, 0, len(l.Locks))
+	for _, lock := range l.Locks {
+		if lock.ExpiresWithin(d) {
+			locks = append(locks, lock)
+		}
+	}
+	return locks
+}
@@ -1,11 +1,14 @@
 package api_test
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/git-lfs/git-lfs/api"
 	"github.com/git-lfs/git-lfs/api/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var LockService api.LockService
@@ -74,6 +77,129 @@ func TestLockSearchWithLimit(t *testing.T) {
 	}, got)
 }
 
+func TestLockListOlderThan(t *testing.T) {
+	now := time.Now()
+	list := &api.LockList{
+		Locks: []api.Lock{
+			{Id: "week", LockedAt: now.Add(-7 * 24 * time.Hour)},
+			{Id: "day", LockedAt: now.Add(-25 * time.Hour)},
+			{Id: "hour", LockedAt: now.Add(-time.Hour)},
+			{Id: "new", LockedAt: now},
+		},
+	}
+
+	ids := func(locks []api.Lock) []string {
+		ids := make([]string, 0, len(locks))
+		for _, l := range locks {
+			ids = append(ids, l.Id)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"week", "day"}, ids(list.OlderThan(24*time.Hour)))
+	assert.Equal(t, []string{"week", "day", "hour"}, ids(list.OlderThan(30*time.Minute)))
+	assert.Empty(t, list.OlderThan(30*24*time.Hour))
+	assert.Empty(t, new(api.LockList).OlderThan(time.Hour))
+}
+
+func TestRefreshingALock(t *testing.T) {
+	lock := &api.Lock{
+		Id:        "some-lock-id",
+		Path:      "/path/to/file",
+		Committer: api.Committer{Name: "Jane Doe", Email: "jane@example.com"},
+		CommitSHA: "deadbeef",
+	}
+
+	got, body := LockService.Refresh(lock)
+
+	AssertRequestSchema(t, &api.RequestSchema{
+		Method:    "POST",
+		Path:      "/locks",
+		Operation: api.UploadOperation,
+		Body: &api.LockRequest{
+			Path:               "/path/to/file",
+			LatestRemoteCommit: "deadbeef",
+			Committer:          lock.Committer,
+		},
+		Into: body,
+	}, got)
+}
+
+func TestClientRefreshLockUpdatesLock(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Round(time.Second)
+	lock := &api.Lock{
+		Id:        "some-lock-id",
+		Path:      "/path/to/file",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	req := new(http.Request)
+	resp := new(api.HttpResponse)
+
+	lifecycle := new(MockLifecycle)
+	lifecycle.On("Build", mock.AnythingOfType("*api.RequestSchema")).Return(req, nil).Once()
+	lifecycle.On("Execute", req, mock.AnythingOfType("*api.LockResponse")).Run(func(args mock.Arguments) {
+		args.Get(1).(*api.LockResponse).Lock = &api.Lock{
+			Id:        "some-lock-id",
+			Path:      "/path/to/file",
+			ExpiresAt: expiresAt,
+		}
+	}).Return(resp, nil).Once()
+	lifecycle.On("Cleanup", resp).Return(nil).Once()
+
+	err := api.NewClient(lifecycle).RefreshLock(lock)
+
+	lifecycle.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.Equal(t, expiresAt, lock.ExpiresAt)
+	assert.False(t, lock.ExpiresWithin(30*time.Minute))
+}
+
+func TestClientRefreshLockLeavesLockOnError(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute)
+	lock := &api.Lock{Id: "some-lock-id", ExpiresAt: expiresAt}
+
+	req := new(http.Request)
+	resp := new(api.HttpResponse)
+
+	lifecycle := new(MockLifecycle)
+	lifecycle.On("Build", mock.AnythingOfType("*api.RequestSchema")).Return(req, nil).Once()
+	lifecycle.On("Execute", req, mock.AnythingOfType("*api.LockResponse")).Run(func(args mock.Arguments) {
+		args.Get(1).(*api.LockResponse).Err = "lock already created"
+	}).Return(resp, nil).Once()
+	lifecycle.On("Cleanup", resp).Return(nil).Once()
+
+	err := api.NewClient(lifecycle).RefreshLock(lock)
+
+	lifecycle.AssertExpectations(t)
+	assert.EqualError(t, err, "lock already created")
+	assert.Equal(t, expiresAt, lock.ExpiresAt)
+}
+
+func TestLockListExpiringWithin(t *testing.T) {
+	now := time.Now()
+	list := &api.LockList{
+		Locks: []api.Lock{
+			{Id: "soon", ExpiresAt: now.Add(5 * time.Minute)},
+			{Id: "later", ExpiresAt: now.Add(2 * time.Hour)},
+			{Id: "never"},
+			{Id: "unlocked", ExpiresAt: now.Add(5 * time.Minute), UnlockedAt: now},
+			{Id: "expired", ExpiresAt: now.Add(-time.Minute)},
+		},
+	}
+
+	ids := func(locks []api.Lock) []string {
+		ids := make([]string, 0, len(locks))
+		for _, l := range locks {
+			ids = append(ids, l.Id)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"soon", "expired"}, ids(list.ExpiringWithin(time.Hour)))
+	assert.Equal(t, []string{"soon", "later", "expired"}, ids(list.ExpiringWithin(3*time.Hour)))
+}
+
 func TestUnlockingALock(t *testing.T) {
 	got, body := LockService.Unlock("some-lock-id", true)
 
@@ -129,6 +255,21 @@ func TestLockResponseWithUnlockedLock(t *testing.T) {
 	})
 }
 
+func TestLockResponseWithExpiringLock(t *testing.T) {
+	schema.Validate(t, schema.LockResponseSchema, &api.LockResponse{
+		Lock: &api.Lock{
+			Id:   "some-lock-id",
+			Path: "/lock/path",
+			Committer: api.Committer{
+				Name:  "Jane Doe",
+				Email: "jane@example.com",
+			},
+			LockedAt:  time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	})
+}
+
 func TestLockResponseWithError(t *testing.T) {
 	schema.Validate(t, schema.LockResponseSchema, &api.LockResponse{
 		Err: "some error",
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/httputil"
 )
 
@@ -46,6 +47,17 @@ func (o *ObjectResource) NewRequest(relation, method string) (*http.Request, err
 	return req, nil
 }
 
+// rewriteUrls applies the configured `lfs.urlrewrite` rules to the urls of this
+// object's actions, before they are used to transfer it.
+func (o *ObjectResource) rewriteUrls(cfg *config.Configuration) {
+	for _, rel := range o.Actions {
+		rel.Href = cfg.RewriteActionUrl(rel.Href)
+	}
+	for _, rel := range o.Links {
+		rel.Href = cfg.RewriteActionUrl(rel.Href)
+	}
+}
+
 func (o *ObjectResource) Rel(name string) (*LinkRelation, bool) {
 	var rel *LinkRelation
 	var ok bool
@@ -1,11 +1,16 @@
 package api_test
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestObjectsWithNoActionsAreNotExpired(t *testing.T) {
@@ -51,3 +56,45 @@ func TestObjectsWithExpirationDatesAreExpired(t *testing.T) {
 	assert.Equal(t, expires, expiredAt)
 	assert.True(t, expired)
 }
+
+func TestBatchRewritesActionUrls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"objects": []*api.ObjectResource{
+				{
+					Oid: "internal",
+					Actions: map[string]*api.LinkRelation{
+						"download": {Href: "http://internal.example.com/objects/internal"},
+					},
+				},
+				{
+					Oid: "external",
+					Actions: map[string]*api.LinkRelation{
+						"download": {Href: "https://storage.example.com/objects/external"},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url": server.URL + "/media",
+			"lfs.urlrewrite.http://internal.example.com/.to": "https://lfs.example.com/prefix/",
+		},
+	})
+
+	objs, _, err := api.Batch(cfg, []*api.ObjectResource{{Oid: "internal"}, {Oid: "external"}}, "download", nil)
+	require.Nil(t, err)
+	require.Len(t, objs, 2)
+
+	rel, ok := objs[0].Rel("download")
+	require.True(t, ok)
+	assert.Equal(t, "https://lfs.example.com/prefix/objects/internal", rel.Href)
+
+	rel, ok = objs[1].Rel("download")
+	require.True(t, ok)
+	assert.Equal(t, "https://storage.example.com/objects/external", rel.Href)
+}
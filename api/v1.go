@@ -19,6 +19,9 @@ const (
 
 // doLegacyApiRequest runs the request to the LFS legacy API.
 func DoLegacyRequest(cfg *config.Configuration, req *http.Request) (*http.Response, *ObjectResource, error) {
+	release := acquireApiConnection(cfg, req)
+	defer release()
+
 	via := make([]*http.Request, 0, 4)
 	res, err := httputil.DoHttpRequestWithRedirects(cfg, req, via, true)
 	if err != nil {
@@ -33,6 +36,7 @@ func DoLegacyRequest(cfg *config.Configuration, req *http.Request) (*http.Respon
 		return nil, nil, err
 	}
 
+	obj.rewriteUrls(cfg)
 	return res, obj, nil
 }
 
@@ -51,6 +55,9 @@ type batchResponse struct {
 // re-run. When the repo is marked as having private access, credentials will
 // be retrieved.
 func DoBatchRequest(cfg *config.Configuration, req *http.Request) (*http.Response, *batchResponse, error) {
+	release := acquireApiConnection(cfg, req)
+	defer release()
+
 	res, err := DoRequest(req, cfg.PrivateAccess(auth.GetOperationForRequest(req)))
 
 	if err != nil {
@@ -65,6 +72,10 @@ func DoBatchRequest(cfg *config.Configuration, req *http.Request) (*http.Respons
 
 	if err != nil {
 		httputil.SetErrorResponseContext(cfg, err, res)
+	} else {
+		for _, obj := range resp.Objects {
+			obj.rewriteUrls(cfg)
+		}
 	}
 
 	return res, resp, err
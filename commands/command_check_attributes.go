@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkAttributesConsistencyArg bool
+)
+
+func checkAttributesCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	// --consistency is the only check so far, make it explicit so that others
+	// can be added without changing the meaning of a bare invocation
+	if !checkAttributesConsistencyArg {
+		Exit("Usage: git lfs check-attributes --consistency [<ref>]")
+	}
+
+	var ref *git.Ref
+	var err error
+	if len(args) > 0 {
+		ref, err = git.ResolveRef(args[0])
+		if err != nil {
+			Panic(err, "Invalid ref argument: %v", args[0])
+		}
+	} else {
+		ref, err = git.CurrentRef()
+		if err != nil {
+			Panic(err, "Could not check attributes")
+		}
+	}
+
+	conflicts, err := lfs.ScanAttributeConflicts(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not check attributes")
+	}
+
+	for _, c := range conflicts {
+		Print("%s", c)
+	}
+
+	if len(conflicts) > 0 {
+		Exit("%d file(s) stored inconsistently with .gitattributes at %v", len(conflicts), ref.Name)
+	}
+	Print("Git LFS files at %v are consistent with .gitattributes", ref.Name)
+}
+
+func init() {
+	RegisterCommand("check-attributes", checkAttributesCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&checkAttributesConsistencyArg, "consistency", "", false, "Report files stored inconsistently with .gitattributes")
+	})
+}
@@ -2,9 +2,11 @@ package commands
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 
 	"github.com/git-lfs/git-lfs/errors"
@@ -12,6 +14,7 @@ import (
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
 	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/transfer"
 	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
@@ -64,11 +67,12 @@ func checkoutFromFetchChan(filter *filepathfilter.Filter, in chan *lfs.WrappedPo
 	// Launch git update-index
 	c := make(chan *lfs.WrappedPointer)
 
+	var failed int
 	var wait sync.WaitGroup
 	wait.Add(1)
 
 	go func() {
-		checkoutWithChan(c)
+		failed = checkoutWithChan(c)
 		wait.Done()
 	}()
 
@@ -81,6 +85,10 @@ func checkoutFromFetchChan(filter *filepathfilter.Filter, in chan *lfs.WrappedPo
 	}
 	close(c)
 	wait.Wait()
+
+	if failed > 0 {
+		Exit("Could not update the git index for %d file(s).", failed)
+	}
 }
 
 func checkoutWithIncludeExclude(filter *filepathfilter.Filter) {
@@ -94,13 +102,14 @@ func checkoutWithIncludeExclude(filter *filepathfilter.Filter) {
 		Panic(err, "Could not scan for Git LFS files")
 	}
 
+	var failed int
 	var wait sync.WaitGroup
 	wait.Add(1)
 
 	c := make(chan *lfs.WrappedPointer, 1)
 
 	go func() {
-		checkoutWithChan(c)
+		failed = checkoutWithChan(c)
 		wait.Done()
 	}()
 
@@ -112,6 +121,7 @@ func checkoutWithIncludeExclude(filter *filepathfilter.Filter) {
 
 	logPath, _ := cfg.Os.Get("GIT_LFS_PROGRESS")
 	progress := progress.NewProgressMeter(len(pointers), totalBytes, false, logPath)
+	progress.SetRefreshInterval(cfg.ProgressRefreshInterval())
 	progress.Start()
 	totalBytes = 0
 	for _, pointer := range pointers {
@@ -131,6 +141,9 @@ func checkoutWithIncludeExclude(filter *filepathfilter.Filter) {
 	wait.Wait()
 	progress.Finish()
 
+	if failed > 0 {
+		Exit("Could not update the git index for %d file(s).", failed)
+	}
 }
 
 // Populate the working copy with the real content of objects where the file is
@@ -140,7 +153,10 @@ func checkoutWithIncludeExclude(filter *filepathfilter.Filter) {
 // without waiting for this function to shut down.  If the process exits while
 // update-index is in the middle of processing a file the git index can be left
 // in a locked state.
-func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
+//
+// It returns the number of files which were written, but couldn't be added to
+// the index. Each of them has already been reported.
+func checkoutWithChan(in <-chan *lfs.WrappedPointer) int {
 	// Get a converter from repo-relative to cwd-relative
 	// Since writing data & calling git update-index must be relative to cwd
 	repopathchan := make(chan string, 1)
@@ -149,13 +165,20 @@ func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
 		Panic(err, "Could not convert file paths")
 	}
 
-	// Don't fire up the update-index command until we have at least one file to
-	// give it. Otherwise git interprets the lack of arguments to mean param-less update-index
-	// which can trigger entire working copy to be re-examined, which triggers clean filters
-	// and which has unexpected side effects (e.g. downloading filtered-out files)
-	var cmd *exec.Cmd
-	var updateIdxStdin io.WriteCloser
-	var updateIdxOut bytes.Buffer
+	var convertMu sync.Mutex
+	toCwd := func(path string) string {
+		convertMu.Lock()
+		defer convertMu.Unlock()
+
+		repopathchan <- path
+		return <-cwdpathchan
+	}
+
+	// Files are written by several workers at once, but update-index reads
+	// paths one at a time, so only adding to the index is serialized
+	indexer := &gitIndexer{}
+	var indexMu sync.Mutex
+	var failed int
 
 	// From this point on, git update-index is running. Code in this loop MUST
 	// NOT Panic() or otherwise cause the process to exit. If the process exits
@@ -166,65 +189,176 @@ func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
 
 	manifest := TransferManifest()
 
-	for pointer := range in {
-
-		// Check the content - either missing or still this pointer (not exist is ok)
-		filepointer, err := lfs.DecodePointerFromFile(pointer.Name)
-		if err != nil && !os.IsNotExist(err) {
-			if errors.IsNotAPointerError(err) {
-				// File has non-pointer content, leave it alone
-				continue
+	var workers sync.WaitGroup
+	for i := 0; i < cfg.CheckoutWorkers(); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			for pointer := range in {
+				cwdfilepath, ok := checkoutPointer(pointer, toCwd, manifest)
+				if !ok {
+					continue
+				}
+
+				indexMu.Lock()
+				if err := indexer.Add(cwdfilepath); err != nil {
+					LoggedError(err, "Could not update the index for %v", pointer.Name)
+					failed++
+				}
+				indexMu.Unlock()
 			}
-			LoggedError(err, "Problem accessing %v", pointer.Name)
-			continue
+		}()
+	}
+	workers.Wait()
+	close(repopathchan)
+
+	for path, err := range indexer.Close() {
+		LoggedError(err, "Error updating the git index for %v", path)
+		failed++
+	}
+	return failed
+}
+
+// checkoutPointer writes the content of the given pointer to its file, if the
+// file is missing or still contains the pointer, using toCwd to find the path
+// of the file relative to the current directory. It returns that path, and
+// whether the file should be updated in the index.
+func checkoutPointer(pointer *lfs.WrappedPointer, toCwd func(string) string, manifest *transfer.Manifest) (string, bool) {
+	// Symlinks are written verbatim by Git, never as Git LFS content
+	if stat, err := os.Lstat(pointer.Name); err == nil && stat.Mode()&os.ModeSymlink != 0 {
+		tracerx.Printf("checkout: skipping symlink %v", pointer.Name)
+		return "", false
+	}
+
+	// Check the content - either missing or still this pointer (not exist is ok)
+	filepointer, err := lfs.DecodePointerFromFile(pointer.Name)
+	if err != nil && !os.IsNotExist(err) {
+		if errors.IsNotAPointerError(err) {
+			// File has non-pointer content, leave it alone
+			return "", false
 		}
+		LoggedError(err, "Problem accessing %v", pointer.Name)
+		return "", false
+	}
+
+	if filepointer != nil && filepointer.Oid != pointer.Oid {
+		// User has probably manually reset a file to another commit
+		// while leaving it a pointer; don't mess with this
+		return "", false
+	}
 
-		if filepointer != nil && filepointer.Oid != pointer.Oid {
-			// User has probably manually reset a file to another commit
-			// while leaving it a pointer; don't mess with this
-			continue
+	cwdfilepath := toCwd(pointer.Name)
+
+	err = lfs.PointerSmudgeToFile(cwdfilepath, pointer.Pointer, false, manifest, nil)
+	if err != nil {
+		if errors.IsDownloadDeclinedError(err) {
+			// acceptable error, data not local (fetch not run or include/exclude)
+			LoggedError(err, "Skipped checkout for %v, content not local. Use fetch to download.", pointer.Name)
+		} else {
+			LoggedError(err, "Could not checkout file")
+			return "", false
 		}
+	}
 
-		repopathchan <- pointer.Name
-		cwdfilepath := <-cwdpathchan
+	return cwdfilepath, true
+}
+
+// gitIndexer feeds paths to a single `git update-index` process as they are
+// checked out, so that the index matches the files written.
+type gitIndexer struct {
+	cmd    *exec.Cmd
+	input  io.WriteCloser
+	output bytes.Buffer
+	paths  []string
+}
 
-		err = lfs.PointerSmudgeToFile(cwdfilepath, pointer.Pointer, false, manifest, nil)
+// Add sends the given path, relative to the working directory, to be updated
+// in the index. update-index isn't started until the first path is added,
+// since without any paths git would re-examine the entire working copy, which
+// runs the clean filter and has unexpected side effects (e.g. downloading
+// filtered-out files).
+func (i *gitIndexer) Add(path string) error {
+	if i.cmd == nil {
+		cmd := exec.Command("git", "update-index", "-q", "--refresh", "--stdin")
+		cmd.Stdout = &i.output
+		cmd.Stderr = &i.output
+		input, err := cmd.StdinPipe()
 		if err != nil {
-			if errors.IsDownloadDeclinedError(err) {
-				// acceptable error, data not local (fetch not run or include/exclude)
-				LoggedError(err, "Skipped checkout for %v, content not local. Use fetch to download.", pointer.Name)
-			} else {
-				LoggedError(err, "Could not checkout file")
-				continue
-			}
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
 		}
+		i.cmd, i.input = cmd, input
+	}
 
-		if cmd == nil {
-			// Fire up the update-index command
-			cmd = exec.Command("git", "update-index", "-q", "--refresh", "--stdin")
-			cmd.Stdout = &updateIdxOut
-			cmd.Stderr = &updateIdxOut
-			updateIdxStdin, err = cmd.StdinPipe()
-			if err != nil {
-				Panic(err, "Could not update the index")
-			}
+	i.paths = append(i.paths, path)
+
+	// If update-index has already given up, the write fails, but Close
+	// retries every path anyway
+	i.input.Write([]byte(path + "\n"))
+	return nil
+}
+
+// Close waits for update-index to finish, returning the paths which couldn't
+// be updated, and why. update-index stops at the first path it fails on, and
+// leaves the index alone, so if it failed, the rest of the paths are given to
+// a new update-index without the path which failed, until they all succeed.
+func (i *gitIndexer) Close() map[string]error {
+	if i.cmd == nil {
+		return nil
+	}
+
+	i.input.Close()
+	err := i.cmd.Wait()
+	out := i.output.String()
+
+	failed := make(map[string]error)
+	remaining := i.paths
+	for err != nil {
+		ferr := fmt.Errorf("%v: %s", err, strings.TrimSpace(out))
 
-			if err := cmd.Start(); err != nil {
-				Panic(err, "Could not update the index")
+		path, _ := updateIndexFailedPath(out)
+		rest := make([]string, 0, len(remaining))
+		for _, p := range remaining {
+			if p != path {
+				rest = append(rest, p)
 			}
+		}
+		if len(rest) == len(remaining) {
+			// There's no telling which of the paths was at fault
+			for _, p := range remaining {
+				failed[p] = ferr
+			}
+			break
+		}
 
+		failed[path] = ferr
+		if len(rest) == 0 {
+			break
 		}
+		tracerx.Printf("checkout: update-index failed on %q, retrying the rest: %v", path, err)
+		remaining = rest
 
-		updateIdxStdin.Write([]byte(cwdfilepath + "\n"))
+		cmd := exec.Command("git", "update-index", "-q", "--refresh", "--stdin")
+		cmd.Stdin = strings.NewReader(strings.Join(remaining, "\n") + "\n")
+		output, cerr := cmd.CombinedOutput()
+		out, err = string(output), cerr
 	}
-	close(repopathchan)
+	return failed
+}
 
-	if cmd != nil && updateIdxStdin != nil {
-		updateIdxStdin.Close()
-		if err := cmd.Wait(); err != nil {
-			LoggedError(err, "Error updating the git index:\n%s", updateIdxOut.String())
+// updateIndexFailedPath returns the path which update-index gave up on, from
+// its output.
+func updateIndexFailedPath(out string) (string, bool) {
+	const prefix = "fatal: Unable to process path "
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix), true
 		}
 	}
+	return "", false
 }
 
 func init() {
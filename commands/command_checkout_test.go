@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitIndexerContinuesPastFailedPaths(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	indexed := []string{"a.txt", "c.txt", "e.txt"}
+	for _, name := range indexed {
+		require.Nil(t, ioutil.WriteFile(name, []byte(name), 0644))
+	}
+	test.RunGitCommand(t, true, append([]string{"add"}, indexed...)...)
+	test.RunGitCommand(t, true, "commit", "-m", "add files")
+
+	// make the index stale, as checking out does
+	later := time.Now().Add(time.Minute)
+	for _, name := range indexed {
+		require.Nil(t, os.Chtimes(name, later, later))
+	}
+
+	// b.txt and d.txt aren't in the index, so update-index fails on them
+	for _, name := range []string{"b.txt", "d.txt"} {
+		require.Nil(t, ioutil.WriteFile(name, []byte(name), 0644))
+	}
+
+	indexer := &gitIndexer{}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		assert.Nil(t, indexer.Add(name))
+	}
+
+	failed := indexer.Close()
+	assert.Len(t, failed, 2)
+	if assert.NotNil(t, failed["b.txt"]) {
+		assert.Contains(t, failed["b.txt"].Error(), "b.txt")
+	}
+	if assert.NotNil(t, failed["d.txt"]) {
+		assert.Contains(t, failed["d.txt"].Error(), "d.txt")
+	}
+
+	stale := strings.TrimSpace(test.RunGitCommand(t, true, "diff-files", "--name-only"))
+	assert.Empty(t, stale)
+}
+
+func TestUpdateIndexFailedPath(t *testing.T) {
+	path, ok := updateIndexFailedPath("error: b.txt: cannot add to the index - missing --add option?\nfatal: Unable to process path b.txt\n")
+	assert.True(t, ok)
+	assert.Equal(t, "b.txt", path)
+
+	_, ok = updateIndexFailedPath("fatal: index file corrupt\n")
+	assert.False(t, ok)
+}
+
+func TestGitIndexerWithoutPaths(t *testing.T) {
+	indexer := &gitIndexer{}
+
+	assert.Empty(t, indexer.Close())
+}
@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/git-lfs/git-lfs/errors"
@@ -18,8 +22,23 @@ import (
 // `commands.Panic`.
 //
 // If the object read from "from" is _already_ a clean pointer, then it will be
-// written out verbatim to "to", without trying to make it a pointer again.
+// written out verbatim to "to", without trying to make it a pointer again. The
+// same goes for objects smaller than lfs.clean.minsize, see readBelowMinSize.
 func clean(to io.Writer, from io.Reader, fileName string) error {
+	if minSize := cfg.CleanMinSize(); minSize > 0 {
+		small, rest, err := readBelowMinSize(from, minSize)
+		if err != nil {
+			return err
+		}
+
+		if small != nil {
+			Debug("%s is smaller than lfs.clean.minsize, keeping it in Git", fileName)
+			_, err = to.Write(small)
+			return err
+		}
+		from = rest
+	}
+
 	var cb progress.CopyCallback
 	var file *os.File
 	var fileSize int64
@@ -84,6 +103,31 @@ func clean(to io.Writer, from io.Reader, fileName string) error {
 	return err
 }
 
+// readBelowMinSize reads the start of "from" to find whether it is smaller than
+// minSize bytes, in which case the whole of it is returned, to be written to
+// Git as it is. Smudging passes such content through unchanged, so it comes
+// back out as it went in.
+//
+// Content which is already stored as an LFS object is cleaned as usual
+// though, so that a small file committed as a pointer before lfs.clean.minsize
+// was set isn't seen as modified. Otherwise, a reader over the whole of "from"
+// is returned.
+func readBelowMinSize(from io.Reader, minSize int64) ([]byte, io.Reader, error) {
+	head, err := ioutil.ReadAll(io.LimitReader(from, minSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if int64(len(head)) < minSize {
+		sum := sha256.Sum256(head)
+		if !lfs.ObjectExistsOfSize(hex.EncodeToString(sum[:]), int64(len(head))) {
+			return head, nil, nil
+		}
+	}
+
+	return nil, io.MultiReader(bytes.NewReader(head), from), nil
+}
+
 func cleanCommand(cmd *cobra.Command, args []string) {
 	requireStdin("This command should be run by the Git 'clean' filter")
 	lfs.InstallHooks(false)
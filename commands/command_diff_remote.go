@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffRemoteFetchableArg bool
+	diffRemoteJsonArg      bool
+)
+
+// diffRemoteObject is an object reported by diff-remote. Name is only set for
+// fetchable objects, which are found through the pointers at the current ref.
+type diffRemoteObject struct {
+	Name string `json:"name,omitempty"`
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+type diffRemoteReport struct {
+	Remote    string              `json:"remote"`
+	Unpushed  []*diffRemoteObject `json:"unpushed"`
+	Fetchable []*diffRemoteObject `json:"fetchable,omitempty"`
+}
+
+// diffRemoteCommand compares the objects in the local store with those on a
+// remote. It lists local objects the remote does not have and, with
+// --fetchable, objects referenced at the current ref which are missing locally
+// but can be downloaded from the remote.
+func diffRemoteCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) > 0 {
+		if err := git.ValidateRemote(args[0]); err != nil {
+			Exit("Invalid remote name %q", args[0])
+		}
+		cfg.CurrentRemote = args[0]
+	} else {
+		remote, err := defaultRemote()
+		if err != nil {
+			Exit("No default remote: %v", err)
+		}
+		cfg.CurrentRemote = remote
+	}
+
+	var local []*diffRemoteObject
+	for obj := range lfs.ScanObjectsChan() {
+		local = append(local, &diffRemoteObject{Oid: obj.Oid, Size: obj.Size})
+	}
+
+	var missing []*diffRemoteObject
+	if diffRemoteFetchableArg {
+		ref, err := git.CurrentRef()
+		if err != nil {
+			Panic(err, "Could not diff remote")
+		}
+
+		pointers, err := pointersToFetchForRef(ref.Sha)
+		if err != nil {
+			Panic(err, "Could not scan for Git LFS files")
+		}
+
+		seen := tools.NewStringSet()
+		for _, p := range pointers {
+			if seen.Contains(p.Oid) || lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+				continue
+			}
+			seen.Add(p.Oid)
+			missing = append(missing, &diffRemoteObject{Name: p.Name, Oid: p.Oid, Size: p.Size})
+		}
+	}
+
+	onRemote := diffRemoteCheck(append(append([]*diffRemoteObject{}, local...), missing...))
+
+	report := &diffRemoteReport{
+		Remote:   cfg.CurrentRemote,
+		Unpushed: make([]*diffRemoteObject, 0, len(local)),
+	}
+	for _, obj := range local {
+		if !onRemote.Contains(obj.Oid) {
+			report.Unpushed = append(report.Unpushed, obj)
+		}
+	}
+	if diffRemoteFetchableArg {
+		report.Fetchable = make([]*diffRemoteObject, 0, len(missing))
+		for _, obj := range missing {
+			if onRemote.Contains(obj.Oid) {
+				report.Fetchable = append(report.Fetchable, obj)
+			}
+		}
+	}
+
+	if diffRemoteJsonArg {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	Print("%d local object(s) not on %v", len(report.Unpushed), report.Remote)
+	for _, obj := range report.Unpushed {
+		Print(" * %v (%v)", obj.Oid, humanizeBytes(obj.Size))
+	}
+
+	if diffRemoteFetchableArg {
+		Print("%d object(s) fetchable from %v", len(report.Fetchable), report.Remote)
+		for _, obj := range report.Fetchable {
+			Print(" * %v (%v)", obj.Name, obj.Oid)
+		}
+	}
+}
+
+// diffRemoteCheck asks the current remote about the given objects without
+// downloading them, returning the OIDs of those it has. It exits if the remote
+// can't say whether it has any of them.
+func diffRemoteCheck(objects []*diffRemoteObject) tools.StringSet {
+	pointers := make([]*lfs.WrappedPointer, 0, len(objects))
+	for _, obj := range objects {
+		pointers = append(pointers, &lfs.WrappedPointer{
+			Pointer: lfs.NewPointer(obj.Oid, obj.Size, nil),
+		})
+	}
+
+	found, _, err := checkRemoteObjects(pointers)
+	if err != nil {
+		ExitWithError(err)
+	}
+	return found
+}
+
+func init() {
+	RegisterCommand("diff-remote", diffRemoteCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&diffRemoteFetchableArg, "fetchable", "f", false, "Also list objects at the current ref that can be fetched")
+		cmd.Flags().BoolVarP(&diffRemoteJsonArg, "json", "j", false, "Print the comparison as JSON")
+	})
+}
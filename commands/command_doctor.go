@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+// doctorValue is a resolved setting shown by `git lfs doctor`.
+type doctorValue struct {
+	Name  string
+	Value string
+}
+
+// doctorReport is what `git lfs doctor` found: the effective value of the
+// settings most often involved in problems, and anything that looks wrong.
+type doctorReport struct {
+	Values   []*doctorValue
+	Problems []string
+}
+
+func (r *doctorReport) add(name, format string, args ...interface{}) {
+	r.Values = append(r.Values, &doctorValue{Name: name, Value: fmt.Sprintf(format, args...)})
+}
+
+func (r *doctorReport) problem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// doctorCommand prints the effective Git LFS configuration, and any likely
+// problems with it. Unlike env, it shows values after defaults and limits have
+// been applied, rather than everything in the environment.
+func doctorCommand(cmd *cobra.Command, args []string) {
+	gitV, err := git.Config.Version()
+	if err != nil {
+		gitV = "Error getting git version: " + err.Error()
+	}
+
+	report := gatherDoctorReport(cfg, gitV, lfs.LocalMediaDir())
+	for _, v := range report.Values {
+		Print("%s: %s", v.Name, v.Value)
+	}
+
+	Print("")
+	if len(report.Problems) == 0 {
+		Print("No problems found.")
+		return
+	}
+
+	Print("%d possible problem(s):", len(report.Problems))
+	for _, p := range report.Problems {
+		Print(" * %s", p)
+	}
+}
+
+// gatherDoctorReport builds the report for `git lfs doctor` from cfg, given
+// the version of git and the local object directory, which don't come from
+// the configuration.
+func gatherDoctorReport(cfg *config.Configuration, gitVersion, mediaDir string) *doctorReport {
+	report := &doctorReport{}
+
+	report.add("Git version", "%s", gitVersion)
+
+	endpoint := cfg.Endpoint("download")
+	if len(endpoint.Url) > 0 {
+		report.add("Endpoint", "%s (auth=%s)", endpoint.Url, cfg.EndpointAccess(endpoint))
+	} else {
+		report.add("Endpoint", "none")
+		report.problem("No Git LFS endpoint could be found. Add a remote, or set lfs.url.")
+	}
+	for _, remote := range cfg.Remotes() {
+		e := cfg.RemoteEndpoint(remote, "download")
+		report.add(fmt.Sprintf("Endpoint (%s)", remote), "%s (auth=%s)", e.Url, cfg.EndpointAccess(e))
+	}
+
+	transfers := cfg.ConcurrentTransfers()
+	report.add("Concurrent transfers", "%d", transfers)
+	if raw, ok := cfg.Git.Get("lfs.concurrenttransfers"); ok && raw != fmt.Sprintf("%d", transfers) {
+		report.problem("lfs.concurrenttransfers is %q, but %d is used instead.", raw, transfers)
+	}
+
+	report.add("Batch API", "%v", cfg.BatchTransfer())
+	if !cfg.BatchTransfer() {
+		report.problem("lfs.batch is false. Most servers only support the batch API.")
+	}
+
+	report.add("Fetch include", "%s", doctorList(cfg.FetchIncludePaths()))
+	report.add("Fetch exclude", "%s", doctorList(cfg.FetchExcludePaths()))
+
+	if len(mediaDir) > 0 {
+		report.add("Object storage", "%s", mediaDir)
+	} else {
+		report.add("Object storage", "none (not in a repository)")
+	}
+
+	name, _ := cfg.Git.Get("user.name")
+	email, _ := cfg.Git.Get("user.email")
+	report.add("Committer", "%s <%s>", name, email)
+	if len(name) == 0 || len(email) == 0 {
+		report.problem("user.name and user.email should both be set, so that locks record who holds them.")
+	}
+
+	for _, key := range []string{"filter.lfs.smudge", "filter.lfs.clean"} {
+		if value, _ := cfg.Git.Get(key); len(value) == 0 {
+			report.problem("%s isn't set. Run `git lfs install`.", key)
+		}
+	}
+
+	return report
+}
+
+func doctorList(paths []string) string {
+	if len(paths) == 0 {
+		return "none"
+	}
+	return strings.Join(paths, ", ")
+}
+
+func init() {
+	RegisterCommand("doctor", doctorCommand, nil)
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatherDoctorReport(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.url":                 "https://example.com/repo.git/info/lfs",
+			"lfs.concurrenttransfers": "5",
+			"lfs.fetchinclude":        "images,docs",
+			"user.name":               "Jane Doe",
+			"user.email":              "jane@example.com",
+			"filter.lfs.smudge":       "git-lfs smudge -- %f",
+			"filter.lfs.clean":        "git-lfs clean -- %f",
+		},
+	})
+
+	report := gatherDoctorReport(cfg, "git version 2.11.0", "/repo/.git/lfs/objects")
+
+	assert.Equal(t, []*doctorValue{
+		{Name: "Git version", Value: "git version 2.11.0"},
+		{Name: "Endpoint", Value: "https://example.com/repo.git/info/lfs (auth=none)"},
+		{Name: "Concurrent transfers", Value: "5"},
+		{Name: "Batch API", Value: "true"},
+		{Name: "Fetch include", Value: "images, docs"},
+		{Name: "Fetch exclude", Value: "none"},
+		{Name: "Object storage", Value: "/repo/.git/lfs/objects"},
+		{Name: "Committer", Value: "Jane Doe <jane@example.com>"},
+	}, report.Values)
+	assert.Empty(t, report.Problems)
+}
+
+func TestGatherDoctorReportFindsProblems(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.concurrenttransfers": "1000",
+			"user.name":               "Jane Doe",
+		},
+	})
+
+	report := gatherDoctorReport(cfg, "git version 2.11.0", "")
+
+	assert.Equal(t, []string{
+		"No Git LFS endpoint could be found. Add a remote, or set lfs.url.",
+		`lfs.concurrenttransfers is "1000", but 64 is used instead.`,
+		"user.name and user.email should both be set, so that locks record who holds them.",
+		"filter.lfs.smudge isn't set. Run `git lfs install`.",
+		"filter.lfs.clean isn't set. Run `git lfs install`.",
+	}, report.Problems)
+}
@@ -2,8 +2,12 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"time"
 
+	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/filepathfilter"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
@@ -13,9 +17,28 @@ import (
 )
 
 var (
-	fetchRecentArg bool
-	fetchAllArg    bool
-	fetchPruneArg  bool
+	fetchRecentArg    bool
+	fetchAllArg       bool
+	fetchPruneArg     bool
+	fetchTagsArg      bool
+	fetchResumeArg    bool
+	fetchKeepGoingArg bool
+	fetchCheckArg     bool
+
+	fetchExcludeRefsArg []string
+	fetchJSONArg        bool
+
+	// fetchJSON writes the output of --json, and is nil without it
+	fetchJSON *fetchJSONReporter
+
+	// The number of objects which couldn't be fetched because the server
+	// doesn't have them, and for other reasons
+	fetchMissingCount int
+	fetchFailedCount  int
+
+	// The refs which couldn't be scanned or fetched, reported at the end
+	// with --keep-going
+	fetchFailedRefs []string
 )
 
 func getIncludeExcludeArgs(cmd *cobra.Command) (include, exclude *string) {
@@ -34,6 +57,16 @@ func getIncludeExcludeArgs(cmd *cobra.Command) (include, exclude *string) {
 func fetchCommand(cmd *cobra.Command, args []string) {
 	requireInRepo()
 
+	if fetchCheckArg && (fetchPruneArg || fetchJSONArg) {
+		Exit("Cannot combine --check with --prune or --json")
+	}
+
+	if fetchJSONArg {
+		// Keep stdout for the JSON records
+		fetchJSON = newFetchJSONReporter(os.Stdout)
+		OutputWriter = io.MultiWriter(os.Stderr, ErrorBuffer)
+	}
+
 	var refs []*git.Ref
 
 	if len(args) > 0 {
@@ -46,7 +79,17 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		cfg.CurrentRemote = ""
 	}
 
-	if len(args) > 1 {
+	if len(args) > 1 && fetchKeepGoingArg {
+		for _, name := range args[1:] {
+			ref, err := git.ResolveRef(name)
+			if err != nil {
+				fetchScanFailed(err, "Invalid ref argument: %v", name)
+				recordFetchedRef(name, false)
+				continue
+			}
+			refs = append(refs, ref)
+		}
+	} else if len(args) > 1 {
 		resolvedrefs, err := git.ResolveRefs(args[1:])
 		if err != nil {
 			Panic(err, "Invalid ref argument: %v", args[1:])
@@ -64,8 +107,8 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 	include, exclude := getIncludeExcludeArgs(cmd)
 
 	if fetchAllArg {
-		if fetchRecentArg || len(args) > 1 {
-			Exit("Cannot combine --all with ref arguments or --recent")
+		if fetchRecentArg || fetchTagsArg || len(args) > 1 {
+			Exit("Cannot combine --all with ref arguments, --recent or --tags")
 		}
 		if include != nil || exclude != nil {
 			Exit("Cannot combine --all with --include or --exclude")
@@ -81,7 +124,17 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		// Fetch refs sequentially per arg order; duplicates in later refs will be ignored
 		for _, ref := range refs {
 			Print("Fetching %v", ref.Name)
-			s := fetchRef(ref.Sha, filter)
+			var s bool
+			if fetchResumeArg {
+				s = fetchRefResumable(ref, filter)
+			} else {
+				s = fetchRef(ref.Sha, filter)
+			}
+			success = recordFetchedRef(ref.Name, s) && success
+		}
+
+		if fetchTagsArg {
+			s := fetchTags(refs, filter)
 			success = success && s
 		}
 
@@ -98,7 +151,22 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		prune(fetchconf, verify, false, false)
 	}
 
+	if fetchJSON != nil {
+		fetchJSON.finish(fetchMissingCount, fetchFailedCount)
+	}
+
+	if len(fetchFailedRefs) > 0 {
+		Error("Failed to fetch %d ref(s):", len(fetchFailedRefs))
+		for _, name := range fetchFailedRefs {
+			Error(" * %v", name)
+		}
+		success = false
+	}
+
 	if !success {
+		if fetchMissingCount > 0 && fetchFailedCount == 0 {
+			Exit("Warning: %d object(s) missing on the server", fetchMissingCount)
+		}
 		Exit("Warning: errors occurred")
 	}
 }
@@ -127,21 +195,95 @@ func fetchRefToChan(ref string, filter *filepathfilter.Filter) chan *lfs.Wrapped
 func fetchRef(ref string, filter *filepathfilter.Filter) bool {
 	pointers, err := pointersToFetchForRef(ref)
 	if err != nil {
-		Panic(err, "Could not scan for Git LFS files")
+		return fetchScanFailed(err, "Could not scan for Git LFS files")
 	}
 	return fetchPointers(pointers, filter)
 }
 
+// fetchScanFailed handles an error finding what to fetch. It is fatal unless
+// --keep-going was given, in which case it is reported and false is returned
+// so that the caller can move on to the next ref.
+func fetchScanFailed(err error, format string, args ...interface{}) bool {
+	if !fetchKeepGoingArg {
+		Panic(err, format, args...)
+	}
+	Error(format+": %v", append(args, err)...)
+	return false
+}
+
+// recordFetchedRef notes the ref called name as failed if ok is false, so
+// that --keep-going can list it at the end. It returns ok.
+func recordFetchedRef(name string, ok bool) bool {
+	if ok || !fetchKeepGoingArg {
+		return ok
+	}
+	for _, failed := range fetchFailedRefs {
+		if failed == name {
+			return ok
+		}
+	}
+	fetchFailedRefs = append(fetchFailedRefs, name)
+	return ok
+}
+
+// Fetch all binaries for a given ref, resuming from the work list of an earlier
+// unfinished fetch of the same ref if it is still valid
+func fetchRefResumable(ref *git.Ref, filter *filepathfilter.Filter) bool {
+	scanned := true
+	pointers := resumablePointers(ref.Name, ref.Sha, func() []*lfs.WrappedPointer {
+		pointers, err := pointersToFetchForRef(ref.Sha)
+		if err != nil {
+			scanned = fetchScanFailed(err, "Could not scan for Git LFS files")
+		}
+		return pointers
+	})
+	if !scanned {
+		clearFetchState(ref.Name)
+		return false
+	}
+
+	ok := fetchPointers(pointers, filter)
+	if ok {
+		clearFetchState(ref.Name)
+	}
+	return ok
+}
+
 // Fetch all previous versions of objects from since to ref (not including final state at ref)
 // So this will fetch all the '-' sides of the diff from since to ref
 func fetchPreviousVersions(ref string, since time.Time, filter *filepathfilter.Filter) bool {
 	pointers, err := lfs.ScanPreviousVersions(ref, since)
 	if err != nil {
-		Panic(err, "Could not scan for Git LFS previous versions")
+		return fetchScanFailed(err, "Could not scan for Git LFS previous versions")
 	}
 	return fetchPointers(pointers, filter)
 }
 
+// Fetch objects at all local tags, skipping any commits already fetched
+func fetchTags(alreadyFetchedRefs []*git.Ref, filter *filepathfilter.Filter) bool {
+	tags, err := git.LocalTags()
+	if err != nil {
+		return recordFetchedRef("tags", fetchScanFailed(err, "Could not scan for tags"))
+	}
+
+	ok := true
+	uniqueRefShas := make(map[string]string, len(alreadyFetchedRefs)+len(tags))
+	for _, ref := range alreadyFetchedRefs {
+		uniqueRefShas[ref.Sha] = ref.Name
+	}
+	for _, tag := range tags {
+		if prevRefName, seen := uniqueRefShas[tag.Sha]; seen {
+			tracerx.Printf("Skipping fetch for tag %v, already fetched via %v", tag.Name, prevRefName)
+			continue
+		}
+		uniqueRefShas[tag.Sha] = tag.Name
+		Print("Fetching tag %v", tag.Name)
+		k := fetchRef(tag.Sha, filter)
+		ok = recordFetchedRef(tag.Name, k) && ok
+	}
+	return ok
+}
+
 // Fetch recent objects based on config
 func fetchRecent(alreadyFetchedRefs []*git.Ref, filter *filepathfilter.Filter) bool {
 	fetchconf := cfg.FetchPruneConfig()
@@ -162,9 +304,9 @@ func fetchRecent(alreadyFetchedRefs []*git.Ref, filter *filepathfilter.Filter) b
 		refsSince := time.Now().AddDate(0, 0, -fetchconf.FetchRecentRefsDays)
 		refs, err := git.RecentBranches(refsSince, fetchconf.FetchRecentRefsIncludeRemotes, cfg.CurrentRemote)
 		if err != nil {
-			Panic(err, "Could not scan for recent refs")
+			return recordFetchedRef("recent branches", fetchScanFailed(err, "Could not scan for recent refs"))
 		}
-		for _, ref := range refs {
+		for _, ref := range excludeRefs(refs, fetchExcludeRefsArg) {
 			// Don't fetch for the same SHA twice
 			if prevRefName, ok := uniqueRefShas[ref.Sha]; ok {
 				if ref.Name != prevRefName {
@@ -174,7 +316,7 @@ func fetchRecent(alreadyFetchedRefs []*git.Ref, filter *filepathfilter.Filter) b
 				uniqueRefShas[ref.Sha] = ref.Name
 				Print("Fetching %v", ref.Name)
 				k := fetchRef(ref.Sha, filter)
-				ok = ok && k
+				ok = recordFetchedRef(ref.Name, k) && ok
 			}
 		}
 	}
@@ -190,19 +332,62 @@ func fetchRecent(alreadyFetchedRefs []*git.Ref, filter *filepathfilter.Filter) b
 			Print("Fetching changes within %v days of %v", fetchconf.FetchRecentCommitsDays, refName)
 			commitsSince := summ.CommitDate.AddDate(0, 0, -fetchconf.FetchRecentCommitsDays)
 			k := fetchPreviousVersions(commit, commitsSince, filter)
-			ok = ok && k
+			ok = recordFetchedRef(refName, k) && ok
 		}
 
 	}
 	return ok
 }
 
+// excludeRefs returns the refs whose names don't match any of patterns, which
+// are either exact names or globs as for path.Match, e.g. "ci/*"
+func excludeRefs(refs []*git.Ref, patterns []string) []*git.Ref {
+	if len(patterns) == 0 {
+		return refs
+	}
+
+	included := make([]*git.Ref, 0, len(refs))
+	for _, ref := range refs {
+		if refMatchesAny(ref.Name, patterns) {
+			tracerx.Printf("Skipping fetch for excluded ref %v", ref.Name)
+			continue
+		}
+		included = append(included, ref)
+	}
+	return included
+}
+
+func refMatchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == name {
+			return true
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func fetchAll() bool {
-	pointers := scanAll()
+	if !fetchResumeArg {
+		pointers := scanAll()
+		Print("Fetching objects...")
+		return fetchPointers(pointers, nil)
+	}
+
+	pointers := resumablePointers(fetchAllStateRef, allRefsSha(), scanAll)
 	Print("Fetching objects...")
-	return fetchPointers(pointers, nil)
+	ok := fetchPointers(pointers, nil)
+	if ok {
+		clearFetchState(fetchAllStateRef)
+	}
+	return ok
 }
 
+// fetchAllStateRef names the saved work list of `fetch --all --resume`
+const fetchAllStateRef = "--all"
+
 func scanAll() []*lfs.WrappedPointer {
 	// converts to `git rev-list --all`
 	// We only pick up objects in real commits and not the reflog
@@ -236,24 +421,66 @@ func scanAll() []*lfs.WrappedPointer {
 }
 
 func fetchPointers(pointers []*lfs.WrappedPointer, filter *filepathfilter.Filter) bool {
+	if fetchCheckArg {
+		return checkPointers(pointers, filter)
+	}
 	return fetchAndReportToChan(pointers, filter, nil)
 }
 
+// checkPointers asks the remote whether it has the objects which are missing
+// locally, without downloading them, and reports which objects are available
+// and which are missing. Objects already present locally are available without
+// asking. It returns true if all are available.
+func checkPointers(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter) bool {
+	ready, pointers, _ := readyAndMissingPointers(allpointers, filter)
+
+	if len(pointers) > 0 {
+		initFetchRemote()
+	}
+	available, missing, err := checkRemoteObjects(pointers)
+	if err != nil {
+		ExitWithError(err)
+	}
+	for _, p := range ready {
+		available.Add(p.Oid)
+	}
+
+	Print("Available:")
+	for _, p := range append(ready, pointers...) {
+		if available.Contains(p.Oid) {
+			Print(" * %v (%v)", p.Name, p.Oid)
+		}
+	}
+
+	var missingOids []string
+	for _, p := range pointers {
+		if missing.Contains(p.Oid) {
+			missingOids = append(missingOids, p.Oid)
+			missing.Remove(p.Oid)
+		}
+	}
+	reportMissing(missingOids, pointers)
+
+	return len(missingOids) == 0
+}
+
 // Fetch and report completion of each OID to a channel (optional, pass nil to skip)
 // Returns true if all completed with no errors, false if errors were written to stderr/log
 func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter, out chan<- *lfs.WrappedPointer) bool {
-	// Lazily initialize the current remote.
-	if len(cfg.CurrentRemote) == 0 {
-		// Actively find the default remote, don't just assume origin
-		defaultRemote, err := git.DefaultRemote()
-		if err != nil {
-			Exit("No default remote")
-		}
-		cfg.CurrentRemote = defaultRemote
-	}
+	initFetchRemote()
 
 	ready, pointers, totalSize := readyAndMissingPointers(allpointers, filter)
-	q := lfs.NewDownloadQueue(len(pointers), totalSize, false)
+	var options []lfs.TransferQueueOption
+	if fetchJSON != nil {
+		options = append(options, lfs.WithProgressOutput(os.Stderr))
+	}
+	q := lfs.NewDownloadQueue(len(pointers), totalSize, false, options...)
+	q.SetVerbose(transferVerboseArg)
+
+	var jsonDone <-chan struct{}
+	if fetchJSON != nil {
+		jsonDone = fetchJSON.watch(q.Watch(), pointers)
+	}
 
 	if out != nil {
 		// If we already have it, or it won't be fetched
@@ -295,12 +522,79 @@ func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfil
 	q.Wait()
 	tracerx.PerformanceSince("process queue", processQueue)
 
-	ok := true
-	for _, err := range q.Errors() {
-		ok = false
+	if fetchJSON != nil {
+		<-jsonDone
+	}
+
+	if stats := q.Stats(); stats.Succeeded+stats.Failed > 0 {
+		summary := fmt.Sprintf("Downloaded %d object(s), %s in %.1fs", stats.Succeeded,
+			humanizeBytes(stats.Bytes), stats.Duration.Seconds())
+		if stats.Failed > 0 {
+			summary += fmt.Sprintf(", %d failed", stats.Failed)
+		}
+		Print("%s", summary)
+	}
+	printRetrySummary(q)
+
+	return reportFetchErrors(q.Errors(), pointers)
+}
+
+// reportFetchErrors prints the errors from fetching pointers, listing objects
+// the server doesn't have apart from other failures. It returns whether there
+// were no errors.
+func reportFetchErrors(errs []error, pointers []*lfs.WrappedPointer) bool {
+	var missing []string
+	for _, err := range errs {
+		if fetchJSON != nil {
+			fetchJSON.addError(err)
+		}
+		if errors.IsObjectMissingError(err) {
+			if oid, ok := errors.GetContext(err, "OID").(string); ok {
+				missing = append(missing, oid)
+				continue
+			}
+		}
+		fetchFailedCount++
 		FullError(err)
 	}
-	return ok
+	reportMissing(missing, pointers)
+
+	return len(errs) == 0
+}
+
+// reportMissing lists the objects with the given OIDs as missing on the
+// server, by the names they have among pointers.
+func reportMissing(missing []string, pointers []*lfs.WrappedPointer) {
+	if len(missing) == 0 {
+		return
+	}
+	fetchMissingCount += len(missing)
+
+	names := make(map[string][]string, len(pointers))
+	for _, p := range pointers {
+		names[p.Oid] = append(names[p.Oid], p.Name)
+	}
+
+	Error("Missing on server:")
+	for _, oid := range missing {
+		if len(names[oid]) == 0 {
+			Error(" * (%v)", oid)
+		}
+		for _, name := range names[oid] {
+			Error(" * %v (%v)", name, oid)
+		}
+	}
+}
+
+// initFetchRemote lazily initializes the current remote.
+func initFetchRemote() {
+	if len(cfg.CurrentRemote) == 0 {
+		remote, err := defaultRemote()
+		if err != nil {
+			Exit("No default remote: %v", err)
+		}
+		cfg.CurrentRemote = remote
+	}
 }
 
 func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter) ([]*lfs.WrappedPointer, []*lfs.WrappedPointer, int64) {
@@ -343,5 +637,12 @@ func init() {
 		cmd.Flags().BoolVarP(&fetchRecentArg, "recent", "r", false, "Fetch recent refs & commits")
 		cmd.Flags().BoolVarP(&fetchAllArg, "all", "a", false, "Fetch all LFS files ever referenced")
 		cmd.Flags().BoolVarP(&fetchPruneArg, "prune", "p", false, "After fetching, prune old data")
+		cmd.Flags().BoolVarP(&fetchTagsArg, "tags", "t", false, "Also fetch LFS files referenced by local tags")
+		cmd.Flags().BoolVarP(&fetchResumeArg, "resume", "", false, "Resume an unfinished fetch without scanning again")
+		cmd.Flags().BoolVar(&fetchCheckArg, "check", false, "Check the objects are available without downloading them")
+		cmd.Flags().BoolVar(&fetchKeepGoingArg, "keep-going", false, "Keep fetching other refs after one fails")
+		cmd.Flags().BoolVar(&fetchJSONArg, "json", false, "Write a JSON record to stdout for each file fetched, then a summary")
+		cmd.Flags().StringSliceVar(&fetchExcludeRefsArg, "exclude-ref", nil, "Don't fetch recent refs matching this name or glob")
+		cmd.Flags().BoolVarP(&transferVerboseArg, "verbose", "v", false, "Show the progress of each file")
 	})
 }
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExcludeRefs(t *testing.T) {
+	refs := []*git.Ref{
+		{Name: "master", Type: git.RefTypeLocalBranch, Sha: "a"},
+		{Name: "ci/build-1", Type: git.RefTypeLocalBranch, Sha: "b"},
+		{Name: "ci/build-2", Type: git.RefTypeLocalBranch, Sha: "c"},
+		{Name: "feature", Type: git.RefTypeLocalBranch, Sha: "d"},
+		{Name: "origin/ci/build-3", Type: git.RefTypeRemoteBranch, Sha: "e"},
+		{Name: "origin/feature", Type: git.RefTypeRemoteBranch, Sha: "f"},
+	}
+
+	names := func(refs []*git.Ref) []string {
+		n := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			n = append(n, ref.Name)
+		}
+		return n
+	}
+
+	assert.Equal(t, names(refs), names(excludeRefs(refs, nil)))
+	assert.Equal(t, []string{"master", "ci/build-1", "ci/build-2", "origin/ci/build-3", "origin/feature"},
+		names(excludeRefs(refs, []string{"feature"})))
+	assert.Equal(t, []string{"master", "feature", "origin/ci/build-3", "origin/feature"},
+		names(excludeRefs(refs, []string{"ci/*"})))
+	assert.Equal(t, []string{"master", "ci/build-1", "ci/build-2", "feature", "origin/feature"},
+		names(excludeRefs(refs, []string{"origin/ci/*", "bogus"})))
+	assert.Equal(t, []string{"master", "ci/build-1", "feature"},
+		names(excludeRefs(refs, []string{"*/build-[23]", "*/*/build-3", "origin/feature"})))
+}
+
+func TestReportFetchErrorsGroupsMissingObjects(t *testing.T) {
+	var buf bytes.Buffer
+	oldWriter := ErrorWriter
+	ErrorWriter = &buf
+	defer func() {
+		ErrorWriter = oldWriter
+		fetchMissingCount, fetchFailedCount = 0, 0
+	}()
+
+	pointers := []*lfs.WrappedPointer{
+		{Name: "a.dat", Pointer: lfs.NewPointer("oid-a", 1, nil)},
+		{Name: "b.dat", Pointer: lfs.NewPointer("oid-b", 1, nil)},
+		{Name: "copy-of-b.dat", Pointer: lfs.NewPointer("oid-b", 1, nil)},
+		{Name: "c.dat", Pointer: lfs.NewPointer("oid-c", 1, nil)},
+	}
+	errs := []error{
+		errors.NewObjectMissingError(errors.New("[oid-a] Object does not exist"), "oid-a"),
+		errors.New("dial tcp: connection refused"),
+		errors.NewObjectMissingError(errors.New("Object not found on the server."), "oid-b"),
+	}
+
+	assert.False(t, reportFetchErrors(errs, pointers))
+	assert.Equal(t, 2, fetchMissingCount)
+	assert.Equal(t, 1, fetchFailedCount)
+	assert.Equal(t, []string{
+		"dial tcp: connection refused",
+		"Missing on server:",
+		" * a.dat (oid-a)",
+		" * b.dat (oid-b)",
+		" * copy-of-b.dat (oid-b)",
+	}, strings.Split(strings.TrimSpace(buf.String()), "\n"))
+
+	buf.Reset()
+	assert.True(t, reportFetchErrors(nil, pointers))
+	assert.Empty(t, buf.String())
+}
+
+func TestFetchJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newFetchJSONReporter(&buf)
+
+	pointers := []*lfs.WrappedPointer{
+		{Name: "a.dat", Size: 1, Pointer: lfs.NewPointer("oid-a", 1, nil)},
+		{Name: "b.dat", Size: 2, Pointer: lfs.NewPointer("oid-b", 2, nil)},
+		{Name: "copy-of-b.dat", Size: 2, Pointer: lfs.NewPointer("oid-b", 2, nil)},
+		{Name: "c.dat", Size: 3, Pointer: lfs.NewPointer("oid-c", 3, nil)},
+	}
+
+	oids := make(chan string, 3)
+	oids <- "oid-a"
+	oids <- "oid-b"
+	oids <- "unknown"
+	close(oids)
+	<-r.watch(oids, pointers)
+
+	r.addError(errors.New("[oid-c] Object does not exist"))
+	r.finish(1, 0)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 4) {
+		return
+	}
+
+	for i, name := range []string{"a.dat", "b.dat", "copy-of-b.dat"} {
+		var obj fetchJSONObject
+		if assert.Nil(t, json.Unmarshal([]byte(lines[i]), &obj), lines[i]) {
+			assert.Equal(t, "object", obj.Type)
+			assert.Equal(t, name, obj.Name)
+		}
+	}
+
+	var summary fetchJSONSummary
+	if assert.Nil(t, json.Unmarshal([]byte(lines[3]), &summary), lines[3]) {
+		assert.Equal(t, fetchJSONSummary{
+			Type:    "summary",
+			Objects: 2,
+			Bytes:   3,
+			Missing: 1,
+			Errors:  []string{"[oid-c] Object does not exist"},
+		}, summary)
+	}
+}
+
+func TestRecordFetchedRef(t *testing.T) {
+	defer func(keepGoing bool) {
+		fetchKeepGoingArg = keepGoing
+		fetchFailedRefs = nil
+	}(fetchKeepGoingArg)
+
+	fetchKeepGoingArg = false
+	assert.False(t, recordFetchedRef("master", false))
+	assert.Empty(t, fetchFailedRefs)
+
+	fetchKeepGoingArg = true
+	assert.True(t, recordFetchedRef("master", true))
+	assert.False(t, recordFetchedRef("feature", false))
+	assert.False(t, recordFetchedRef("v1.0", false))
+	assert.False(t, recordFetchedRef("feature", false))
+	assert.Equal(t, []string{"feature", "v1.0"}, fetchFailedRefs)
+}
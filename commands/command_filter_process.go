@@ -30,29 +30,24 @@ const (
 var filterSmudgeSkip bool
 
 // filterSmudge is a gateway to the `smudge()` function and serves to bail out
-// immediately if the pointer decoded from "from" has no data (i.e., is empty).
+// immediately if the data read from "from" isn't a pointer, as is the case
+// for files which were empty or smaller than lfs.clean.minsize when cleaned.
 // This function, unlike the implementation found in the legacy smudge command,
 // only combines the `io.Reader`s when necessary, since the implementation
 // found in `*git.PacketReader` blocks while waiting for the following packet.
 func filterSmudge(to io.Writer, from io.Reader, filename string) error {
 	var pbuf bytes.Buffer
-	from = io.TeeReader(from, &pbuf)
 
-	ptr, err := lfs.DecodePointer(from)
+	ptr, err := lfs.DecodePointer(io.TeeReader(from, &pbuf))
 	if err != nil {
-		// If we tried to decode a pointer out of the data given to us,
-		// and the file was _empty_, write out an empty file in
-		// response. This occurs because when the clean filter
-		// encounters an empty file, and writes out an empty file,
-		// instead of a pointer.
-		if pbuf.Len() == 0 {
-			if _, cerr := io.Copy(to, &pbuf); cerr != nil {
-				Panic(cerr, "Error writing data to stdout:")
-			}
-			return nil
+		// If the data given to us isn't a pointer, write it back out
+		// as it is, just like the legacy smudge command. The clean
+		// filter writes out files which it doesn't make pointers of
+		// verbatim, so this gives back what it was given.
+		if _, cerr := io.Copy(to, io.MultiReader(&pbuf, from)); cerr != nil {
+			Panic(cerr, "Error writing data to stdout:")
 		}
-
-		return err
+		return nil
 	}
 
 	lfs.LinkOrCopyFromReference(ptr.Oid, ptr.Size)
@@ -3,19 +3,26 @@ package commands
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/progress"
 	"github.com/git-lfs/git-lfs/tools/longpathos"
+	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fsckDryRun bool
+	fsckDryRun   bool
+	fsckObjects  bool
+	fsckPointers bool
 )
 
 func doFsck() (bool, error) {
@@ -26,9 +33,8 @@ func doFsck() (bool, error) {
 		return false, err
 	}
 
-	// The LFS scanner methods return unexported *lfs.wrappedPointer objects.
-	// All we care about is the pointer OID and file name
-	pointerIndex := make(map[string]string)
+	// Pointers by OID, where only the name, OID and size matter
+	pointerIndex := make(map[string]*lfs.WrappedPointer)
 
 	pointers, err := lfs.ScanRefs(ref.Sha, "", nil)
 	if err != nil {
@@ -36,7 +42,7 @@ func doFsck() (bool, error) {
 	}
 
 	for _, p := range pointers {
-		pointerIndex[p.Oid] = p.Name
+		pointerIndex[p.Oid] = p
 	}
 
 	// TODO(zeroshirts): do we want to look for LFS stuff in past commits?
@@ -46,19 +52,32 @@ func doFsck() (bool, error) {
 	}
 
 	for _, p := range p2 {
-		pointerIndex[p.Oid] = p.Name
+		pointerIndex[p.Oid] = p
 	}
 
 	ok := true
 
-	for oid, name := range pointerIndex {
+	// Problems are printed once the progress line is finished with
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	spinner := progress.NewSpinner()
+	checked := 0
+
+	for oid, p := range pointerIndex {
+		name := p.Name
+		checked++
+		spinner.Print(os.Stderr, fmt.Sprintf("Checking object %d/%d: %s", checked, len(pointerIndex), name))
+
 		path := lfs.LocalMediaPathReadOnly(oid)
 
 		Debug("Examining %v (%v)", name, path)
 
 		f, err := longpathos.Open(path)
 		if pErr, pOk := err.(*os.PathError); pOk {
-			Print("Object %s (%s) could not be checked: %s", name, oid, pErr.Err)
+			report("Object %s (%s) could not be checked: %s", name, oid, pErr.Err)
 			ok = false
 			continue
 		}
@@ -66,48 +85,163 @@ func doFsck() (bool, error) {
 			return false, err
 		}
 
-		oidHash := sha256.New()
-		_, err = io.Copy(oidHash, f)
+		// An object of the wrong size is corrupt, without reading it all
+		var recalculatedOid string
+		stat, err := f.Stat()
+		if err == nil && stat.Size() != p.Size {
+			tracerx.Printf("fsck: %v is %d bytes, expected %d", oid, stat.Size(), p.Size)
+		} else {
+			recalculatedOid, err = fsckOid(f)
+		}
 		f.Close()
 		if err != nil {
 			return false, err
 		}
 
-		recalculatedOid := hex.EncodeToString(oidHash.Sum(nil))
 		if recalculatedOid != oid {
 			ok = false
-			Print("Object %s (%s) is corrupt", name, oid)
+			report("Object %s (%s) is corrupt", name, oid)
 			if fsckDryRun {
 				continue
 			}
 
-			badDir := filepath.Join(config.LocalGitStorageDir, "lfs", "bad")
-			if err := longpathos.MkdirAll(badDir, 0755); err != nil {
+			badFile, err := lfs.MoveBadObject(oid, path)
+			if err != nil {
 				return false, err
 			}
+			report("  moved to %s", badFile)
+		}
+	}
 
-			badFile := filepath.Join(badDir, oid)
-			if err := longpathos.Rename(path, badFile); err != nil {
-				return false, err
+	spinner.Finish(os.Stderr, fmt.Sprintf("Checked %d object(s)", len(pointerIndex)))
+	for _, problem := range problems {
+		Print(problem)
+	}
+	return ok, nil
+}
+
+// doFsckPointers checks that each Git LFS file in the working tree matches its
+// pointer in the index, reporting files which were changed without being
+// cleaned again.
+func doFsckPointers() (bool, error) {
+	requireInRepo()
+
+	ref, err := git.CurrentRef()
+	if err != nil {
+		return false, err
+	}
+
+	pointers, err := lfs.ScanTree(ref.Sha)
+	if err != nil {
+		return false, err
+	}
+
+	staged, err := lfs.ScanIndex("HEAD")
+	if err != nil {
+		return false, err
+	}
+
+	pointerIndex := make(map[string]*lfs.WrappedPointer, len(pointers))
+	for _, p := range append(pointers, staged...) {
+		pointerIndex[p.Name] = p
+	}
+
+	names := make([]string, 0, len(pointerIndex))
+	for name := range pointerIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ok := true
+
+	for _, name := range names {
+		p := pointerIndex[name]
+		if len(p.Extensions) > 0 {
+			Debug("Skipping %v, which is cleaned by extensions", name)
+			continue
+		}
+
+		path := filepath.Join(config.LocalWorkingDir, name)
+
+		Debug("Examining %v (%v)", name, path)
+
+		f, err := longpathos.Open(path)
+		if os.IsNotExist(err) {
+			Debug("Skipping %v, which is not in the working tree", name)
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		oid, err := fsckOid(f)
+		f.Close()
+		if err != nil {
+			return false, err
+		}
+
+		if oid == p.Oid {
+			continue
+		}
+
+		// The file may have been changed and added again, so compare it
+		// with the pointer staged in the index too
+		expected := p.Oid
+		if staged, err := git.StagedContent(name); err == nil {
+			if sp, err := lfs.DecodePointer(strings.NewReader(staged)); err == nil {
+				expected = sp.Oid
 			}
-			Print("  moved to %s", badFile)
 		}
+		if oid == expected {
+			continue
+		}
+
+		// A file which was never smudged still holds the pointer itself
+		if wp, err := lfs.DecodePointerFromFile(path); err == nil && wp.Oid == expected {
+			continue
+		}
+
+		ok = false
+		Print("File %s does not match its pointer (%s)", name, expected)
 	}
 	return ok, nil
 }
 
-// TODO(zeroshirts): 'git fsck' reports status (percentage, current#/total) as
-// it checks... we should do the same, as we are rehashing potentially gigs and
-// gigs of content.
-//
+// fsckOid returns the OID of the content read from r.
+func fsckOid(r io.Reader) (string, error) {
+	oidHash := sha256.New()
+	if _, err := io.Copy(oidHash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(oidHash.Sum(nil)), nil
+}
+
 // NOTE(zeroshirts): Ideally git would have hooks for fsck such that we could
 // chain a lfs-fsck, but I don't think it does.
 func fsckCommand(cmd *cobra.Command, args []string) {
 	lfs.InstallHooks(false)
 
-	ok, err := doFsck()
-	if err != nil {
-		Panic(err, "Error checking Git LFS files")
+	// Only the objects are checked unless asked otherwise
+	if !fsckObjects && !fsckPointers {
+		fsckObjects = true
+	}
+
+	ok := true
+
+	if fsckObjects {
+		objectsOk, err := doFsck()
+		if err != nil {
+			Panic(err, "Error checking Git LFS files")
+		}
+		ok = ok && objectsOk
+	}
+
+	if fsckPointers {
+		pointersOk, err := doFsckPointers()
+		if err != nil {
+			Panic(err, "Error checking Git LFS files")
+		}
+		ok = ok && pointersOk
 	}
 
 	if ok {
@@ -118,5 +252,7 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 func init() {
 	RegisterCommand("fsck", fsckCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&fsckDryRun, "dry-run", "d", false, "List corrupt objects without deleting them.")
+		cmd.Flags().BoolVar(&fsckObjects, "objects", false, "Check the objects in the local store (the default).")
+		cmd.Flags().BoolVar(&fsckPointers, "pointers", false, "Check the working tree files match their pointers.")
 	})
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/git-lfs/git-lfs/api"
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tools/longpathos"
 	"github.com/spf13/cobra"
 )
@@ -69,8 +70,8 @@ func lockCommand(cmd *cobra.Command, args []string) {
 //
 // If the root directory, working directory, or file cannot be
 // determined/opened, an error will be returned. If the file in question is
-// actually a directory, an error will be returned. Otherwise, the cleaned path
-// will be returned.
+// actually a directory, or is outside of the repository, an error will be
+// returned. Otherwise, the cleaned path will be returned.
 //
 // For example:
 //     - Working directory: /code/foo/bar/
@@ -88,18 +89,38 @@ func lockPath(file string) (string, error) {
 		return "", err
 	}
 
-	abs := filepath.Join(wd, file)
-	path := strings.TrimPrefix(abs, repo)
+	path, err := repoRelativePath(tools.ResolveSymlinks(repo), tools.ResolveSymlinks(wd), file)
+	if err != nil {
+		return "", err
+	}
 
-	if stat, err := longpathos.Stat(abs); err != nil {
+	if stat, err := longpathos.Stat(filepath.Join(repo, path)); err != nil {
 		return "", err
 	} else {
 		if stat.IsDir() {
 			return "", fmt.Errorf("lfs: cannot lock directory: %s", file)
 		}
 
-		return path[1:], nil
+		return filepath.ToSlash(path), nil
+	}
+}
+
+// repoRelativePath converts file, which is either absolute or relative to the
+// working directory wd, into a path relative to the repository root repo. Both
+// repo and wd must be absolute, with any symlinks resolved.
+func repoRelativePath(repo, wd, file string) (string, error) {
+	var abs string
+	if filepath.IsAbs(file) {
+		abs = tools.ResolveSymlinks(file)
+	} else {
+		abs = filepath.Join(wd, file)
+	}
+
+	path, err := filepath.Rel(repo, abs)
+	if err != nil || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("lfs: %s is outside repository", file)
 	}
+	return path, nil
 }
 
 func init() {
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoRelativePath(t *testing.T) {
+	repo := filepath.FromSlash("/code/foo")
+
+	for desc, c := range map[string]struct {
+		Wd, File, Expected string
+	}{
+		"file at root":         {"/code/foo", "a.dat", "a.dat"},
+		"file in subdirectory": {"/code/foo/bar", "baz.dat", "bar/baz.dat"},
+		"file in parent":       {"/code/foo/bar", "../a.dat", "a.dat"},
+		"dot-relative file":    {"/code/foo/bar", "./baz/qux.dat", "bar/baz/qux.dat"},
+		"absolute file":        {"/code/foo/bar", "/code/foo/baz/qux.dat", "baz/qux.dat"},
+		"outside repository":   {"/code/foo/bar", "../../a.dat", ""},
+		"absolute outside":     {"/code/foo", "/code/foobar/a.dat", ""},
+		"repository parent":    {"/code/foo", "..", ""},
+	} {
+		path, err := repoRelativePath(repo, filepath.FromSlash(c.Wd), filepath.FromSlash(c.File))
+		if c.Expected == "" {
+			assert.NotNil(t, err, desc)
+			continue
+		}
+		if assert.Nil(t, err, desc) {
+			assert.Equal(t, c.Expected, filepath.ToSlash(path), desc)
+		}
+	}
+}
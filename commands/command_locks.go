@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"time"
+
 	"github.com/git-lfs/git-lfs/api"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +19,14 @@ func locksCommand(cmd *cobra.Command, args []string) {
 		Error(err.Error())
 	}
 
+	var olderThan time.Duration
+	if locksCmdFlags.OlderThan != "" {
+		olderThan, err = time.ParseDuration(locksCmdFlags.OlderThan)
+		if err != nil || olderThan < 0 {
+			Exit("Invalid duration for --older-than: %q", locksCmdFlags.OlderThan)
+		}
+	}
+
 	var locks []api.Lock
 
 	query := &api.LockSearchRequest{Filters: filters}
@@ -31,7 +41,11 @@ func locksCommand(cmd *cobra.Command, args []string) {
 			Error(resp.Err)
 		}
 
-		locks = append(locks, resp.Locks...)
+		if olderThan > 0 {
+			locks = append(locks, resp.OlderThan(olderThan)...)
+		} else {
+			locks = append(locks, resp.Locks...)
+		}
 
 		if locksCmdFlags.Limit > 0 && len(locks) > locksCmdFlags.Limit {
 			locks = locks[:locksCmdFlags.Limit]
@@ -63,6 +77,9 @@ type locksFlags struct {
 	// limit is an optional request parameter sent to the server used to
 	// limit the
 	Limit int
+	// OlderThan is an optional duration, such as "24h", used to only show
+	// locks created longer ago than that.
+	OlderThan string
 }
 
 // Filters produces a slice of api.Filter instances based on the internal state
@@ -96,5 +113,6 @@ func init() {
 		cmd.Flags().StringVarP(&locksCmdFlags.Path, "path", "p", "", "filter locks results matching a particular path")
 		cmd.Flags().StringVarP(&locksCmdFlags.Id, "id", "i", "", "filter locks results matching a particular ID")
 		cmd.Flags().IntVarP(&locksCmdFlags.Limit, "limit", "l", 0, "optional limit for number of results to return")
+		cmd.Flags().StringVar(&locksCmdFlags.OlderThan, "older-than", "", "only show locks created longer ago than this, e.g. 24h")
 	})
 }
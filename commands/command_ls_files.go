@@ -1,16 +1,34 @@
 package commands
 
 import (
+	"encoding/json"
+	"os"
+
+	"github.com/git-lfs/git-lfs/filepathfilter"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tools/longpathos"
 	"github.com/spf13/cobra"
 )
 
 var (
-	longOIDs = false
+	longOIDs    = false
+	lsFilesSize = false
+	lsFilesJSON = false
+	// lsFilesDeleted lists only files whose objects aren't in the local
+	// store, i.e. those a pull still needs to download.
+	lsFilesDeleted = false
 )
 
+// lsFilesEntry is a file listed by `git lfs ls-files --json`.
+type lsFilesEntry struct {
+	Name    string `json:"name"`
+	Oid     string `json:"oid"`
+	Size    int64  `json:"size"`
+	Present bool   `json:"present"`
+}
+
 func lsFilesCommand(cmd *cobra.Command, args []string) {
 	requireInRepo()
 
@@ -32,13 +50,51 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 		showOidLen = 64
 	}
 
-	files, err := lfs.ScanTree(ref)
+	scanned, err := lfs.ScanTree(ref)
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS tree: %s", err)
 	}
 
+	// Unlike fetch, only the patterns given on the command line apply, so
+	// that every file is listed by default
+	var includePaths, excludePaths []string
+	include, exclude := getIncludeExcludeArgs(cmd)
+	if include != nil {
+		includePaths = tools.CleanPaths(*include, ",")
+	}
+	if exclude != nil {
+		excludePaths = tools.CleanPaths(*exclude, ",")
+	}
+	filter := filepathfilter.New(includePaths, excludePaths)
+
+	files := make([]*lfs.WrappedPointer, 0, len(scanned))
+	for _, p := range scanned {
+		if !filter.Allows(p.Name) {
+			continue
+		}
+		if lsFilesDeleted && lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			continue
+		}
+		files = append(files, p)
+	}
+
+	if lsFilesJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, p := range files {
+			entry := &lsFilesEntry{Name: p.Name, Oid: p.Oid, Size: p.Size, Present: lsFilesMarker(p) == "*"}
+			if err := enc.Encode(entry); err != nil {
+				ExitWithError(err)
+			}
+		}
+		return
+	}
+
 	for _, p := range files {
-		Print("%s %s %s", p.Oid[0:showOidLen], lsFilesMarker(p), p.Name)
+		if lsFilesSize {
+			Print("%s %s %s (%s)", p.Oid[0:showOidLen], lsFilesMarker(p), p.Name, humanizeBytes(p.Size))
+		} else {
+			Print("%s %s %s", p.Oid[0:showOidLen], lsFilesMarker(p), p.Name)
+		}
 	}
 }
 
@@ -54,5 +110,11 @@ func lsFilesMarker(p *lfs.WrappedPointer) string {
 func init() {
 	RegisterCommand("ls-files", lsFilesCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&longOIDs, "long", "l", false, "")
+		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
+		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
+		cmd.Flags().BoolVarP(&lsFilesSize, "size", "s", false, "Show the size of each file")
+		cmd.Flags().BoolVar(&lsFilesJSON, "json", false, "Write a JSON object for each file")
+		cmd.Flags().BoolVarP(&lsFilesDeleted, "deleted", "d", false, "Only list files whose objects are missing locally")
+		cmd.Flags().BoolVar(&lsFilesDeleted, "not-downloaded", false, "Same as --deleted")
 	})
 }
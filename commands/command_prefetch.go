@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"github.com/git-lfs/git-lfs/filepathfilter"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prefetchRefArg string
+)
+
+// prefetchCommand downloads the Git LFS objects referenced by the given
+// pathspecs at a single ref into the local store, without touching the working
+// copy.
+func prefetchCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) == 0 {
+		Exit("Usage: git lfs prefetch [--ref=<ref>] -- <path>...")
+	}
+
+	var ref *git.Ref
+	var err error
+	if len(prefetchRefArg) > 0 {
+		ref, err = git.ResolveRef(prefetchRefArg)
+		if err != nil {
+			Panic(err, "Invalid ref argument: %v", prefetchRefArg)
+		}
+	} else {
+		ref, err = git.CurrentRef()
+		if err != nil {
+			Panic(err, "Could not prefetch")
+		}
+	}
+
+	// Pathspecs are relative to the current directory, but pointers are
+	// named relative to the root of the repo
+	var rootedpaths []string
+
+	inchan := make(chan string, 1)
+	outchan, err := lfs.ConvertCwdFilesRelativeToRepo(inchan)
+	if err != nil {
+		Panic(err, "Could not prefetch")
+	}
+	for _, arg := range args {
+		inchan <- arg
+		rootedpaths = append(rootedpaths, <-outchan)
+	}
+	close(inchan)
+
+	remote, err := defaultRemote()
+	if err != nil {
+		Exit("No default remote: %v", err)
+	}
+	cfg.CurrentRemote = remote
+
+	Print("Prefetching %v", ref.Name)
+	if !fetchRef(ref.Sha, filepathfilter.New(rootedpaths, nil)) {
+		Exit("Warning: errors occurred")
+	}
+}
+
+func init() {
+	RegisterCommand("prefetch", prefetchCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&prefetchRefArg, "ref", "r", "", "Prefetch objects at this ref instead of HEAD")
+	})
+}
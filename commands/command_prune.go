@@ -3,9 +3,12 @@ package commands
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/git-lfs/git-lfs/api"
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
@@ -22,6 +25,7 @@ var (
 	pruneVerboseArg     bool
 	pruneVerifyArg      bool
 	pruneDoNotVerifyArg bool
+	pruneOlderThanArg   string
 )
 
 func pruneCommand(cmd *cobra.Command, args []string) {
@@ -31,11 +35,26 @@ func pruneCommand(cmd *cobra.Command, args []string) {
 	}
 
 	fetchPruneConfig := cfg.FetchPruneConfig()
+	if len(pruneOlderThanArg) > 0 {
+		olderThan, err := time.ParseDuration(pruneOlderThanArg)
+		if err != nil || olderThan < 0 {
+			Exit("Invalid duration for --older-than: %q", pruneOlderThanArg)
+		}
+		// Never shorten lfs.prune.grace, which guards uncommitted content
+		if olderThan > fetchPruneConfig.PruneGracePeriod {
+			fetchPruneConfig.PruneGracePeriod = olderThan
+		}
+	}
+
 	verify := !pruneDoNotVerifyArg &&
 		(fetchPruneConfig.PruneVerifyRemoteAlways || pruneVerifyArg)
 	prune(fetchPruneConfig, verify, pruneDryRunArg, pruneVerboseArg)
 }
 
+// The number of objects to ask the remote about in each batch API request when
+// verifying
+const pruneVerifyBatchSize = 100
+
 type PruneProgressType int
 
 const (
@@ -60,9 +79,9 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 	// Add all the base funcs to the waitgroup before starting them, in case
 	// one completes really fast & hits 0 unexpectedly
 	// each main process can Add() to the wg itself if it subdivides the task
-	taskwait.Add(4) // 1..4: localObjects, current & recent refs, unpushed, worktree
+	taskwait.Add(5) // 1..5: localObjects, current & recent refs, unpushed, worktree, stashes
 	if verifyRemote {
-		taskwait.Add(1) // 5
+		taskwait.Add(1) // 6
 	}
 
 	progressChan := make(PruneProgressChan, 100)
@@ -83,6 +102,7 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 	go pruneTaskGetRetainedCurrentAndRecentRefs(fetchPruneConfig, retainChan, errorChan, &taskwait)
 	go pruneTaskGetRetainedUnpushed(fetchPruneConfig, retainChan, errorChan, &taskwait)
 	go pruneTaskGetRetainedWorktree(retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedStashes(retainChan, errorChan, &taskwait)
 	if verifyRemote {
 		reachableObjects = tools.NewStringSetWithCapacity(100)
 		go pruneTaskGetReachableObjects(&reachableObjects, errorChan, &taskwait)
@@ -114,29 +134,46 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 	var totalSize int64
 	var verboseOutput bytes.Buffer
 	var verifyc chan string
+	var verifyObjects []*api.ObjectResource
 	var verifywait sync.WaitGroup
 
 	if verifyRemote {
 		cfg.CurrentRemote = fetchPruneConfig.PruneRemoteName
-		// build queue now, no estimates or progress output
-		verifyQueue = lfs.NewDownloadCheckQueue(0, 0)
+		if remote := cfg.EnvRemote(); len(remote) > 0 {
+			if err := git.ValidateRemote(remote); err != nil {
+				Exit("Invalid remote name %q in GIT_LFS_REMOTE", remote)
+			}
+			cfg.CurrentRemote = remote
+		}
 		verifiedObjects = tools.NewStringSetWithCapacity(len(localObjects) / 2)
 
-		// this channel is filled with oids for which Check() succeeded & Transfer() was called
-		verifyc = verifyQueue.Watch()
-		verifywait.Add(1)
-		go func() {
-			for oid := range verifyc {
-				verifiedObjects.Add(oid)
-				tracerx.Printf("VERIFIED: %v", oid)
-				progressChan <- PruneProgress{PruneProgressTypeVerify, 1}
-			}
-			verifywait.Done()
-		}()
+		// The batch API can be asked about objects directly; the legacy API
+		// needs a queue to check them one by one
+		if !cfg.BatchTransfer() {
+			// build queue now, no estimates or progress output
+			verifyQueue = lfs.NewDownloadCheckQueue(0, 0)
+
+			// this channel is filled with oids for which Check() succeeded & Transfer() was called
+			verifyc = verifyQueue.Watch()
+			verifywait.Add(1)
+			go func() {
+				for oid := range verifyc {
+					verifiedObjects.Add(oid)
+					tracerx.Printf("VERIFIED: %v", oid)
+					progressChan <- PruneProgress{PruneProgressTypeVerify, 1}
+				}
+				verifywait.Done()
+			}()
+		}
 	}
 
 	for _, file := range localObjects {
 		if !retainedObjects.Contains(file.Oid) {
+			if pruneWithinGracePeriod(file.Oid, fetchPruneConfig.PruneGracePeriod) {
+				tracerx.Printf("RETAIN: %v created within grace period", file.Oid)
+				continue
+			}
+
 			prunableObjects = append(prunableObjects, file.Oid)
 			totalSize += file.Size
 			if verbose {
@@ -144,17 +181,23 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 				verboseOutput.WriteString(fmt.Sprintf(" * %v (%v)\n", file.Oid, humanizeBytes(file.Size)))
 			}
 
-			if verifyRemote {
+			if verifyQueue != nil {
 				tracerx.Printf("VERIFYING: %v", file.Oid)
 				pointer := lfs.NewPointer(file.Oid, file.Size, nil)
 				verifyQueue.Add(lfs.NewDownloadable(&lfs.WrappedPointer{Pointer: pointer}))
+			} else if verifyRemote {
+				verifyObjects = append(verifyObjects, &api.ObjectResource{Oid: file.Oid, Size: file.Size})
 			}
 		}
 	}
 
 	if verifyRemote {
-		verifyQueue.Wait()
-		verifywait.Wait()
+		if verifyQueue != nil {
+			verifyQueue.Wait()
+			verifywait.Wait()
+		} else {
+			pruneVerifyWithBatch(verifyObjects, verifiedObjects, progressChan)
+		}
 		close(progressChan) // after verify (uses spinner) but before check
 		progresswait.Wait()
 		pruneCheckVerified(prunableObjects, reachableObjects, verifiedObjects)
@@ -182,6 +225,20 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 
 }
 
+// pruneVerifyWithBatch asks the remote about objects with batch API requests,
+// adding those it has to verifiedObjects
+func pruneVerifyWithBatch(objects []*api.ObjectResource, verifiedObjects tools.StringSet, progressChan PruneProgressChan) {
+	oids, err := api.BatchCheck(cfg, objects, pruneVerifyBatchSize)
+	if err != nil {
+		Exit("Unable to verify objects on %v: %v", cfg.CurrentRemote, err)
+	}
+	for _, oid := range oids {
+		verifiedObjects.Add(oid)
+		tracerx.Printf("VERIFIED: %v", oid)
+		progressChan <- PruneProgress{PruneProgressTypeVerify, 1}
+	}
+}
+
 func pruneCheckVerified(prunableObjects []string, reachableObjects, verifiedObjects tools.StringSet) {
 	// There's no issue if an object is not reachable and missing, only if reachable & missing
 	var problems bytes.Buffer
@@ -205,6 +262,25 @@ func pruneCheckVerified(prunableObjects []string, reachableObjects, verifiedObje
 	}
 }
 
+// pruneWithinGracePeriod returns whether the media file for oid was modified
+// within the grace period, so may belong to an add which has not yet been
+// committed or pushed
+func pruneWithinGracePeriod(oid string, grace time.Duration) bool {
+	if grace <= 0 {
+		return false
+	}
+
+	mediaFile, err := lfs.LocalMediaPath(oid)
+	if err != nil {
+		return false
+	}
+	fi, err := longpathos.Stat(mediaFile)
+	if err != nil {
+		return false
+	}
+	return time.Since(fi.ModTime()) < grace
+}
+
 func pruneCheckErrors(taskErrors []error) {
 	if len(taskErrors) > 0 {
 		for _, err := range taskErrors {
@@ -263,30 +339,79 @@ func pruneTaskCollectErrors(outtaskErrors *[]error, errorChan chan error, errorw
 
 func pruneDeleteFiles(prunableObjects []string) {
 	spinner := progress.NewSpinner()
-	var problems bytes.Buffer
-	// In case we fail to delete some
-	var deletedFiles int
-	for i, oid := range prunableObjects {
-		spinner.Print(OutputWriter, fmt.Sprintf("Deleting object %d/%d", i, len(prunableObjects)))
-		mediaFile, err := lfs.LocalMediaPath(oid)
-		if err != nil {
-			problems.WriteString(fmt.Sprintf("Unable to find media path for %v: %v\n", oid, err))
-			continue
-		}
-		err = longpathos.Remove(mediaFile)
-		if err != nil {
-			problems.WriteString(fmt.Sprintf("Failed to remove file %v: %v\n", mediaFile, err))
-			continue
-		}
-		deletedFiles++
-	}
+	deletedFiles, problems := pruneDeleteObjects(prunableObjects, cfg.ConcurrentTransfers(), pruneDeleteMediaFile, func(done int) {
+		spinner.Print(OutputWriter, fmt.Sprintf("Deleting object %d/%d", done, len(prunableObjects)))
+	})
 	spinner.Finish(OutputWriter, fmt.Sprintf("Deleted %d files", deletedFiles))
-	if problems.Len() > 0 {
-		LoggedError(fmt.Errorf("Failed to delete some files"), problems.String())
+	if len(problems) > 0 {
+		LoggedError(fmt.Errorf("Failed to delete some files"), strings.Join(problems, ""))
 		Exit("Prune failed, see errors above")
 	}
 }
 
+// pruneDeleteObjects deletes objects with up to the given number of workers,
+// calling progress with the number finished so far after each one. It returns
+// how many were deleted, and a line for each that couldn't be, in oid order.
+func pruneDeleteObjects(oids []string, workers int, remove func(oid string) error, progress func(done int)) (int, []string) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	oidc := make(chan string, len(oids))
+	for _, oid := range oids {
+		oidc <- oid
+	}
+	close(oidc)
+
+	var mu sync.Mutex // guards done, deleted & problems, and calls to progress
+	var done, deleted int
+	problems := make(map[string]string)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for oid := range oidc {
+				err := remove(oid)
+
+				mu.Lock()
+				done++
+				if err != nil {
+					problems[oid] = fmt.Sprintf("%v\n", err)
+				} else {
+					deleted++
+				}
+				progress(done)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sorted := make([]string, 0, len(problems))
+	for oid := range problems {
+		sorted = append(sorted, oid)
+	}
+	sort.Strings(sorted)
+	for i, oid := range sorted {
+		sorted[i] = problems[oid]
+	}
+	return deleted, sorted
+}
+
+func pruneDeleteMediaFile(oid string) error {
+	mediaFile, err := lfs.LocalMediaPath(oid)
+	if err != nil {
+		return fmt.Errorf("Unable to find media path for %v: %v", oid, err)
+	}
+	if err := longpathos.Remove(mediaFile); err != nil {
+		return fmt.Errorf("Failed to remove file %v: %v", mediaFile, err)
+	}
+	return nil
+}
+
 // Background task, must call waitg.Done() once at end
 func pruneTaskGetLocalObjects(outLocalObjects *[]localstorage.Object, progChan PruneProgressChan, waitg *sync.WaitGroup) {
 	defer waitg.Done()
@@ -447,6 +572,23 @@ func pruneTaskGetRetainedWorktree(retainChan chan string, errorChan chan error,
 
 }
 
+// Background task, must call waitg.Done() once at end
+func pruneTaskGetRetainedStashes(retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
+	defer waitg.Done()
+
+	// Retain everything a stash entry needs to be applied again
+	stashCommits, err := git.StashCommits()
+	if err != nil {
+		errorChan <- err
+		return
+	}
+	for _, sha := range stashCommits {
+		tracerx.Printf("PRUNE: Retaining objects at stashed commit %v", sha)
+		waitg.Add(1)
+		go pruneTaskGetRetainedAtRef(sha, retainChan, errorChan, waitg)
+	}
+}
+
 // Background task, must call waitg.Done() once at end
 func pruneTaskGetReachableObjects(outObjectSet *tools.StringSet, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
@@ -479,5 +621,6 @@ func init() {
 		cmd.Flags().BoolVarP(&pruneVerboseArg, "verbose", "v", false, "Print full details of what is/would be deleted")
 		cmd.Flags().BoolVarP(&pruneVerifyArg, "verify-remote", "c", false, "Verify that remote has LFS files before deleting")
 		cmd.Flags().BoolVar(&pruneDoNotVerifyArg, "no-verify-remote", false, "Override lfs.pruneverifyremotealways and don't verify")
+		cmd.Flags().StringVar(&pruneOlderThanArg, "older-than", "", "Only delete files added to the local store longer ago than this, e.g. 24h")
 	})
 }
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneDeleteObjects(t *testing.T) {
+	oids := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		oids = append(oids, fmt.Sprintf("%064x", i))
+	}
+	unremovable := map[string]bool{oids[42]: true, oids[7]: true}
+
+	var mu sync.Mutex
+	removed := make(map[string]int)
+	remove := func(oid string) error {
+		if unremovable[oid] {
+			return fmt.Errorf("cannot remove %v", oid)
+		}
+		mu.Lock()
+		removed[oid]++
+		mu.Unlock()
+		return nil
+	}
+
+	var progress []int
+	deleted, problems := pruneDeleteObjects(oids, 8, remove, func(done int) {
+		progress = append(progress, done)
+	})
+
+	assert.Equal(t, 498, deleted)
+	assert.Len(t, removed, 498)
+	for oid, n := range removed {
+		assert.Equal(t, 1, n, oid)
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("cannot remove %v\n", oids[7]),
+		fmt.Sprintf("cannot remove %v\n", oids[42]),
+	}, problems)
+
+	if assert.Len(t, progress, 500) {
+		for i, done := range progress {
+			assert.Equal(t, i+1, done)
+		}
+	}
+}
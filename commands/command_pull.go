@@ -5,9 +5,16 @@ import (
 
 	"github.com/git-lfs/git-lfs/filepathfilter"
 	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
+var (
+	pullRequireAllArg bool
+	pullDryRunArg     bool
+)
+
 func pullCommand(cmd *cobra.Command, args []string) {
 	requireGitVersion()
 	requireInRepo()
@@ -19,16 +26,20 @@ func pullCommand(cmd *cobra.Command, args []string) {
 		}
 		cfg.CurrentRemote = args[0]
 	} else {
-		// Actively find the default remote, don't just assume origin
-		defaultRemote, err := git.DefaultRemote()
+		remote, err := defaultRemote()
 		if err != nil {
 			Panic(err, "No default remote")
 		}
-		cfg.CurrentRemote = defaultRemote
+		cfg.CurrentRemote = remote
 	}
 
 	includeArg, excludeArg := getIncludeExcludeArgs(cmd)
-	pull(filepathfilter.New(determineIncludeExcludePaths(cfg, includeArg, excludeArg)))
+	filter := filepathfilter.New(determineIncludeExcludePaths(cfg, includeArg, excludeArg))
+	if pullDryRunArg {
+		pullDryRun(filter)
+		return
+	}
+	pull(filter)
 
 }
 
@@ -40,11 +51,83 @@ func pull(filter *filepathfilter.Filter) {
 
 	c := fetchRefToChan(ref.Sha, filter)
 	checkoutFromFetchChan(filter, c)
+
+	if pullRequireAllArg || cfg.Git.Bool("lfs.pull.requireall", false) {
+		requireAllPulled(ref.Sha, filter)
+	}
+}
+
+// pullDryRun lists each file at the current ref allowed by the filter, and
+// whether pull would download its object or only check it out, without
+// contacting the remote or changing the working copy or local store.
+func pullDryRun(filter *filepathfilter.Filter) {
+	ref, err := git.CurrentRef()
+	if err != nil {
+		Panic(err, "Could not pull")
+	}
+
+	pointers, err := pointersToFetchForRef(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	var files, downloads int
+	var size int64
+	seen := tools.NewStringSet()
+	for _, p := range pointers {
+		if !filter.Allows(p.Name) {
+			continue
+		}
+		files++
+
+		if lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			Print("checkout %s", p.Name)
+			continue
+		}
+
+		Print("download %s (%s)", p.Name, humanizeBytes(p.Size))
+		if !seen.Contains(p.Oid) {
+			seen.Add(p.Oid)
+			downloads++
+			size += p.Size
+		}
+	}
+
+	Print("%d file(s), %d object(s) to download (%s)", files, downloads, humanizeBytes(size))
+}
+
+// requireAllPulled exits with an error listing every file at ref, allowed by
+// the filter, whose object could not be downloaded.
+func requireAllPulled(ref string, filter *filepathfilter.Filter) {
+	pointers, err := pointersToFetchForRef(ref)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	var missing []*lfs.WrappedPointer
+	for _, p := range pointers {
+		if filter.Allows(p.Name) && !lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			missing = append(missing, p)
+		}
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("Could not pull all Git LFS objects, %d missing:", len(missing))
+	for _, p := range missing {
+		msg += fmt.Sprintf("\n  %s (%s)", p.Name, p.Oid)
+	}
+	Exit("%s", msg)
 }
 
 func init() {
 	RegisterCommand("pull", pullCommand, func(cmd *cobra.Command) {
 		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
 		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
+		cmd.Flags().BoolVarP(&pullRequireAllArg, "require-all", "", false, "Fail if any object could not be downloaded")
+		cmd.Flags().BoolVarP(&pullDryRunArg, "dry-run", "d", false, "Don't download or check out anything, just report")
+		cmd.Flags().BoolVarP(&transferVerboseArg, "verbose", "v", false, "Show the progress of each file")
 	})
 }
@@ -109,6 +109,12 @@ func refsByNames(refnames []string) ([]*git.Ref, error) {
 // pushCommand calculates the git objects to send by looking comparing the range
 // of commits between the local and remote git servers.
 func pushCommand(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		if remote := cfg.EnvRemote(); len(remote) > 0 {
+			args = []string{remote}
+		}
+	}
+
 	if len(args) == 0 {
 		Print("Specify a remote and a remote branch name (`git lfs push origin master`)")
 		os.Exit(1)
@@ -171,5 +177,6 @@ func init() {
 		cmd.Flags().BoolVarP(&useStdin, "stdin", "s", false, "Take refs on stdin (for pre-push hook)")
 		cmd.Flags().BoolVarP(&pushObjectIDs, "object-id", "o", false, "Push LFS object ID(s)")
 		cmd.Flags().BoolVarP(&pushAll, "all", "a", false, "Push all objects for the current ref to the remote.")
+		cmd.Flags().BoolVarP(&transferVerboseArg, "verbose", "v", false, "Show the progress of each file")
 	})
 }
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/git-lfs/git-lfs/api"
 	"github.com/spf13/cobra"
@@ -16,6 +17,8 @@ var (
 	errLockAmbiguous = errors.New("lfs: multiple locks found; ambiguous")
 
 	unlockCmdFlags unlockFlags
+
+	unlockUsage = "Usage: git lfs unlock (--id my-lock-id | --all-mine | <path>...)"
 )
 
 // unlockFlags holds the flags given to the `git lfs unlock` command
@@ -26,40 +29,113 @@ type unlockFlags struct {
 	// with "--force", signifying the user's intent to break another
 	// individual's lock(s).
 	Force bool
+	// AllMine specifies whether or not to unlock every active lock held by
+	// the current committer.
+	AllMine bool
+}
+
+// unlockTarget is a lock to be removed by the `git lfs unlock` command.
+type unlockTarget struct {
+	// Name is how the lock is shown to the user, i.e. the path as given on
+	// the command line, or the path of the lock.
+	Name string
+	// Id is the ID of the lock, if it could be resolved.
+	Id string
+	// Err is why the ID of the lock couldn't be resolved, if it couldn't.
+	Err error
 }
 
 func unlockCommand(cmd *cobra.Command, args []string) {
 	setLockRemoteFor(cfg)
 
-	var id string
-	if len(args) != 0 {
-		path, err := lockPath(args[0])
+	var targets []*unlockTarget
+	switch {
+	case unlockCmdFlags.Id != "" && len(args) == 0 && !unlockCmdFlags.AllMine:
+		targets = append(targets, &unlockTarget{Name: unlockCmdFlags.Id, Id: unlockCmdFlags.Id})
+	case unlockCmdFlags.AllMine && len(args) == 0 && unlockCmdFlags.Id == "":
+		mine, err := locksHeldBy(api.CurrentCommitter())
 		if err != nil {
 			Error(err.Error())
+			Exit("Error communicating with LFS API.")
+		}
+		if len(mine) == 0 {
+			Print("No locks to unlock.")
+			return
+		}
+		for _, lock := range mine {
+			targets = append(targets, &unlockTarget{Name: lock.Path, Id: lock.Id})
+		}
+	case len(args) > 0 && unlockCmdFlags.Id == "" && !unlockCmdFlags.AllMine:
+		resolve := lockIdFromPath
+		if len(args) > 1 {
+			// List the locks once, rather than searching for each path
+			resolve = new(lockCache).IdForPath
+		}
+		for _, arg := range args {
+			targets = append(targets, unlockTargetForPath(arg, resolve))
 		}
+	default:
+		Exit(unlockUsage)
+	}
 
-		if id, err = lockIdFromPath(path); err != nil {
-			Error(err.Error())
+	failed := unlockTargets(targets)
+	if len(targets) > 1 {
+		Print("\n%d of %d lock(s) unlocked", len(targets)-failed, len(targets))
+	}
+	if failed > 0 {
+		Exit("Server unable to unlock %d lock(s).", failed)
+	}
+}
+
+// unlockTargetForPath resolves the lock on the file at the given path, as
+// given on the command line, using resolve to find the ID of the lock on the
+// path relative to the repository root.
+func unlockTargetForPath(file string, resolve func(path string) (string, error)) *unlockTarget {
+	t := &unlockTarget{Name: file}
+
+	path, err := lockPath(file)
+	if err != nil {
+		t.Err = err
+		return t
+	}
+
+	t.Id, t.Err = resolve(path)
+	return t
+}
+
+// unlockTargets removes each of the given locks, printing whether or not each
+// was unlocked. A failure to unlock one doesn't stop the rest from being
+// unlocked. It returns the number of locks which couldn't be unlocked.
+func unlockTargets(targets []*unlockTarget) int {
+	var failed int
+	for _, t := range targets {
+		err := t.Err
+		if err == nil {
+			err = unlockById(t.Id)
+		}
+
+		if err != nil {
+			Error("'%s' was not unlocked: %v", t.Name, err)
+			failed++
+			continue
 		}
-	} else if unlockCmdFlags.Id != "" {
-		id = unlockCmdFlags.Id
-	} else {
-		Error("Usage: git lfs unlock (--id my-lock-id | <path>)")
+		Print("'%s' was unlocked (%s)", t.Name, t.Id)
 	}
+	return failed
+}
 
+// unlockById makes a call to the LFS API to remove the lock with the given ID.
+func unlockById(id string) error {
 	s, resp := API.Locks.Unlock(id, unlockCmdFlags.Force)
 
 	if _, err := API.Do(s); err != nil {
-		Error(err.Error())
-		Exit("Error communicating with LFS API.")
+		return fmt.Errorf("Error communicating with LFS API: %v", err)
 	}
 
 	if len(resp.Err) > 0 {
-		Error(resp.Err)
-		Exit("Server unable to unlock lock.")
+		return errors.New(resp.Err)
 	}
-
-	Print("'%s' was unlocked (%s)", args[0], resp.Lock.Id)
+	return nil
 }
 
 // lockIdFromPath makes a call to the LFS API and resolves the ID for the locked
@@ -92,6 +168,82 @@ func lockIdFromPath(path string) (string, error) {
 	}
 }
 
+// locksHeldBy makes calls to the LFS API to find all active locks created by
+// the given committer.
+func locksHeldBy(committer api.Committer) ([]api.Lock, error) {
+	all, err := searchAllLocks(&api.LockSearchRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var locks []api.Lock
+	for _, lock := range all {
+		if lock.Active() && lock.Committer == committer {
+			locks = append(locks, lock)
+		}
+	}
+	return locks, nil
+}
+
+// searchAllLocks makes calls to the LFS API to find all locks matching the
+// given query, following the pages of results.
+func searchAllLocks(query *api.LockSearchRequest) ([]api.Lock, error) {
+	var locks []api.Lock
+	for {
+		s, resp := API.Locks.Search(query)
+		if _, err := API.Do(s); err != nil {
+			return nil, err
+		}
+
+		if resp.Err != "" {
+			return nil, errors.New(resp.Err)
+		}
+
+		locks = append(locks, resp.Locks...)
+
+		if resp.NextCursor == "" {
+			return locks, nil
+		}
+		query.Cursor = resp.NextCursor
+	}
+}
+
+// lockCache holds the locks on the current remote, listed by a single search
+// the first time one is looked up, so that commands resolving many paths
+// don't make an API call for each of them.
+type lockCache struct {
+	locks  []api.Lock
+	loaded bool
+}
+
+// IdForPath resolves the ID of the lock at the given path in the same way as
+// lockIdFromPath, but from the cached list of locks.
+func (c *lockCache) IdForPath(path string) (string, error) {
+	if !c.loaded {
+		locks, err := searchAllLocks(&api.LockSearchRequest{})
+		if err != nil {
+			return "", err
+		}
+		c.locks, c.loaded = locks, true
+	}
+
+	var matches []api.Lock
+	for _, lock := range c.locks {
+		if lock.Path == path {
+			matches = append(matches, lock)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", errNoMatchingLocks
+	case 1:
+		return matches[0].Id, nil
+	default:
+		return "", errLockAmbiguous
+	}
+}
+
 func init() {
 	if !isCommandEnabled(cfg, "locks") {
 		return
@@ -101,5 +253,6 @@ func init() {
 		cmd.Flags().StringVarP(&lockRemote, "remote", "r", cfg.CurrentRemote, lockRemoteHelp)
 		cmd.Flags().StringVarP(&unlockCmdFlags.Id, "id", "i", "", "unlock a lock by its ID")
 		cmd.Flags().BoolVarP(&unlockCmdFlags.Force, "force", "f", false, "forcibly break another user's lock(s)")
+		cmd.Flags().BoolVar(&unlockCmdFlags.AllMine, "all-mine", false, "unlock all of your own locks")
 	})
 }
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLockLifecycle answers lock searches from Locks, and unlocks any lock in
+// Locks by its ID, except those in Failing.
+type fakeLockLifecycle struct {
+	Locks   []api.Lock
+	Failing map[string]bool
+
+	// Unlocked are the IDs of locks successfully unlocked, in order
+	Unlocked []string
+	// Searches counts the lock searches made
+	Searches int
+
+	schema *api.RequestSchema
+}
+
+var _ api.Lifecycle = new(fakeLockLifecycle)
+
+func (l *fakeLockLifecycle) Build(schema *api.RequestSchema) (*http.Request, error) {
+	l.schema = schema
+	return http.NewRequest(schema.Method, schema.Path, nil)
+}
+
+func (l *fakeLockLifecycle) Execute(req *http.Request, into interface{}) (api.Response, error) {
+	switch resp := into.(type) {
+	case *api.LockList:
+		l.Searches++
+		path := l.schema.Query["path"]
+		for _, lock := range l.Locks {
+			if path == "" || lock.Path == path {
+				resp.Locks = append(resp.Locks, lock)
+			}
+		}
+	case *api.UnlockResponse:
+		id := strings.TrimSuffix(strings.TrimPrefix(l.schema.Path, "/locks/"), "/unlock")
+		if l.Failing[id] {
+			resp.Err = "unable to unlock " + id
+			break
+		}
+		for i, lock := range l.Locks {
+			if lock.Id == id {
+				resp.Lock = &l.Locks[i]
+				l.Unlocked = append(l.Unlocked, id)
+			}
+		}
+		if resp.Lock == nil {
+			resp.Err = "unable to find lock"
+		}
+	}
+	return nil, nil
+}
+
+func (l *fakeLockLifecycle) Cleanup(resp api.Response) error {
+	return nil
+}
+
+func withFakeLockAPI(lifecycle *fakeLockLifecycle, fn func(out, errs *bytes.Buffer)) {
+	oldAPI, oldOut, oldErr := API, OutputWriter, ErrorWriter
+	defer func() {
+		API, OutputWriter, ErrorWriter = oldAPI, oldOut, oldErr
+	}()
+
+	var out, errs bytes.Buffer
+	API = api.NewClient(lifecycle)
+	OutputWriter, ErrorWriter = &out, &errs
+
+	fn(&out, &errs)
+}
+
+func TestUnlockTargetsContinuesPastFailures(t *testing.T) {
+	lifecycle := &fakeLockLifecycle{
+		Locks: []api.Lock{
+			{Id: "lock-a", Path: "a.dat"},
+			{Id: "lock-b", Path: "b.dat"},
+			{Id: "lock-c", Path: "c.dat"},
+		},
+		Failing: map[string]bool{"lock-b": true},
+	}
+
+	withFakeLockAPI(lifecycle, func(out, errs *bytes.Buffer) {
+		var targets []*unlockTarget
+		for _, path := range []string{"a.dat", "b.dat", "missing.dat", "c.dat"} {
+			id, err := lockIdFromPath(path)
+			targets = append(targets, &unlockTarget{Name: path, Id: id, Err: err})
+		}
+
+		assert.Equal(t, 2, unlockTargets(targets))
+		assert.Equal(t, []string{"lock-a", "lock-c"}, lifecycle.Unlocked)
+		assert.Equal(t, "'a.dat' was unlocked (lock-a)\n'c.dat' was unlocked (lock-c)\n", out.String())
+		assert.Equal(t, "'b.dat' was not unlocked: unable to unlock lock-b\n"+
+			"'missing.dat' was not unlocked: lfs: no matching locks found\n", errs.String())
+	})
+}
+
+func TestLocksHeldBy(t *testing.T) {
+	me := api.Committer{Name: "Jane Doe", Email: "jane@example.com"}
+	other := api.Committer{Name: "John Doe", Email: "john@example.com"}
+
+	lifecycle := &fakeLockLifecycle{
+		Locks: []api.Lock{
+			{Id: "lock-a", Path: "a.dat", Committer: me},
+			{Id: "lock-b", Path: "b.dat", Committer: other},
+			{Id: "lock-c", Path: "c.dat", Committer: me},
+		},
+	}
+
+	withFakeLockAPI(lifecycle, func(out, errs *bytes.Buffer) {
+		locks, err := locksHeldBy(me)
+		assert.Nil(t, err)
+		if assert.Len(t, locks, 2) {
+			assert.Equal(t, "lock-a", locks[0].Id)
+			assert.Equal(t, "lock-c", locks[1].Id)
+		}
+	})
+}
+
+func TestLockIdFromPath(t *testing.T) {
+	lifecycle := &fakeLockLifecycle{
+		Locks: []api.Lock{
+			{Id: "lock-a", Path: "a.dat"},
+			{Id: "lock-b1", Path: "b.dat"},
+			{Id: "lock-b2", Path: "b.dat"},
+		},
+	}
+
+	withFakeLockAPI(lifecycle, func(out, errs *bytes.Buffer) {
+		id, err := lockIdFromPath("a.dat")
+		assert.Nil(t, err)
+		assert.Equal(t, "lock-a", id)
+
+		id, err = lockIdFromPath("b.dat")
+		assert.Equal(t, errLockAmbiguous, err)
+		assert.Empty(t, id)
+
+		id, err = lockIdFromPath("c.dat")
+		assert.Equal(t, errNoMatchingLocks, err)
+		assert.Empty(t, id)
+	})
+}
+
+func TestLockCacheSearchesOnce(t *testing.T) {
+	lifecycle := &fakeLockLifecycle{
+		Locks: []api.Lock{
+			{Id: "lock-a", Path: "a.dat"},
+			{Id: "lock-b", Path: "b.dat"},
+			{Id: "lock-c1", Path: "c.dat"},
+			{Id: "lock-c2", Path: "c.dat"},
+		},
+	}
+
+	withFakeLockAPI(lifecycle, func(out, errs *bytes.Buffer) {
+		cache := new(lockCache)
+
+		for _, path := range []string{"a.dat", "b.dat", "a.dat"} {
+			id, err := cache.IdForPath(path)
+			assert.Nil(t, err)
+			assert.Equal(t, "lock-"+strings.TrimSuffix(path, ".dat"), id)
+		}
+
+		_, err := cache.IdForPath("c.dat")
+		assert.Equal(t, errLockAmbiguous, err)
+		_, err = cache.IdForPath("d.dat")
+		assert.Equal(t, errNoMatchingLocks, err)
+
+		assert.Equal(t, 1, lifecycle.Searches)
+	})
+}
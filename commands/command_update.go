@@ -9,8 +9,9 @@ import (
 )
 
 var (
-	updateForce  = false
-	updateManual = false
+	updateForce          = false
+	updateManual         = false
+	updateMigrateStorage = false
 )
 
 // updateCommand is used for updating parts of Git LFS that reside under
@@ -41,6 +42,14 @@ func updateCommand(cmd *cobra.Command, args []string) {
 		Exit("You cannot use --force and --manual options together")
 	}
 
+	if updateMigrateStorage {
+		if moved, err := lfs.MigrateLocalObjects(); err != nil {
+			Exit("Could not move objects to the current storage layout: %v", err)
+		} else {
+			Print("Moved %d object(s) to the current storage layout.", moved)
+		}
+	}
+
 	if updateManual {
 		Print(lfs.GetHookInstallSteps())
 	} else {
@@ -58,5 +67,6 @@ func init() {
 	RegisterCommand("update", updateCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&updateForce, "force", "f", false, "Overwrite existing hooks.")
 		cmd.Flags().BoolVarP(&updateManual, "manual", "m", false, "Print instructions for manual install.")
+		cmd.Flags().BoolVarP(&updateMigrateStorage, "migrate-storage", "", false, "Move local objects to the configured storage layout.")
 	})
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tools/longpathos"
 	"github.com/git-lfs/git-lfs/transfer"
+	"github.com/rubyist/tracerx"
 )
 
 // Populate man pages
@@ -38,6 +39,10 @@ var (
 
 	includeArg string
 	excludeArg string
+
+	// transferVerboseArg is shared by the commands which transfer objects,
+	// and shows the progress of each file as well as the overall progress.
+	transferVerboseArg bool
 )
 
 // TransferManifest builds a transfer.Manifest from the commands package global
@@ -167,6 +172,21 @@ func requireInRepo() {
 	}
 }
 
+// defaultRemote returns the remote to use when none was given on the command
+// line: the one named by GIT_LFS_REMOTE if set, otherwise the default remote
+// for the current branch.
+func defaultRemote() (string, error) {
+	if remote := cfg.EnvRemote(); len(remote) > 0 {
+		if err := git.ValidateRemote(remote); err != nil {
+			return "", errors.Wrapf(err, "Invalid remote name %q in GIT_LFS_REMOTE", remote)
+		}
+		return remote, nil
+	}
+
+	// Actively find the default remote, don't just assume origin
+	return git.DefaultRemote()
+}
+
 func handlePanic(err error) string {
 	if err == nil {
 		return ""
@@ -255,6 +275,76 @@ func determineIncludeExcludePaths(config *config.Configuration, includeArg, excl
 	return
 }
 
+// printRetrySummary tells the user how many of the objects transferred by q
+// had to be retried, which is otherwise hidden behind the progress meter. It
+// should be called once q.Wait() has returned, and prints nothing if there
+// were no retries.
+func printRetrySummary(q *lfs.TransferQueue) {
+	summary := q.RetrySummary()
+	if len(summary) == 0 {
+		return
+	}
+
+	var attempts int
+	for oid, count := range summary {
+		tracerx.Printf("%s: retried %d time(s)", oid, count)
+		attempts += count
+	}
+	Print("%d object(s) required retries (%d retry attempt(s))", len(summary), attempts)
+}
+
+// checkRemoteObjects asks the current remote whether it has each of the given
+// objects, without downloading them. It returns the OIDs of those it has, and
+// of those it doesn't, or an error if it couldn't say for one of them.
+func checkRemoteObjects(pointers []*lfs.WrappedPointer) (present, missing tools.StringSet, err error) {
+	present = tools.NewStringSetWithCapacity(len(pointers))
+	missing = tools.NewStringSet()
+	if len(pointers) == 0 {
+		return present, missing, nil
+	}
+
+	var totalSize int64
+	for _, p := range pointers {
+		totalSize += p.Size
+	}
+
+	q := lfs.NewDownloadCheckQueue(len(pointers), totalSize)
+	watch := q.Watch()
+	done := make(chan struct{})
+	go func() {
+		for oid := range watch {
+			present.Add(oid)
+		}
+		close(done)
+	}()
+
+	for _, p := range pointers {
+		tracerx.Printf("checking %v [%v]", p.Name, p.Oid)
+		q.Add(lfs.NewDownloadable(p))
+	}
+	q.Wait()
+	<-done
+
+	// Objects the remote doesn't have come back as errors too, but any other
+	// error means the remote couldn't say whether it has the object.
+	for _, qerr := range q.Errors() {
+		if !errors.IsObjectMissingError(qerr) {
+			return nil, nil, qerr
+		}
+		tracerx.Printf("check: %v", qerr)
+		if oid, ok := errors.GetContext(qerr, "OID").(string); ok {
+			missing.Add(oid)
+		}
+	}
+
+	for _, p := range pointers {
+		if !present.Contains(p.Oid) && !missing.Contains(p.Oid) {
+			return nil, nil, errors.Errorf("Git LFS: remote did not say whether it has %v", p.Oid)
+		}
+	}
+	return present, missing, nil
+}
+
 // isCommandEnabled returns whether the environment variable GITLFS<CMD>ENABLED
 // is "truthy" according to config.Os.Bool (see
 // github.com/git-lfs/git-lfs/config#Configuration.Env.Os), returning false
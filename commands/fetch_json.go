@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/lfs"
+)
+
+// fetchJSONReporter writes the newline-delimited JSON records of
+// `git lfs fetch --json`: one for each file whose object is downloaded, then a
+// summary of the whole fetch.
+type fetchJSONReporter struct {
+	mu      sync.Mutex // guards enc and summary
+	enc     *json.Encoder
+	summary *fetchJSONSummary
+}
+
+type fetchJSONObject struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+type fetchJSONSummary struct {
+	Type    string   `json:"type"`
+	Objects int      `json:"objects"`
+	Bytes   int64    `json:"bytes"`
+	Missing int      `json:"missing"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors"`
+}
+
+func newFetchJSONReporter(w io.Writer) *fetchJSONReporter {
+	return &fetchJSONReporter{
+		enc:     json.NewEncoder(w),
+		summary: &fetchJSONSummary{Type: "summary", Errors: make([]string, 0)},
+	}
+}
+
+// watch writes a record for each of pointers whose object is reported on oids,
+// as from TransferQueue.Watch(). The returned channel is closed once oids is.
+func (r *fetchJSONReporter) watch(oids <-chan string, pointers []*lfs.WrappedPointer) <-chan struct{} {
+	oidToPointers := make(map[string][]*lfs.WrappedPointer, len(pointers))
+	for _, p := range pointers {
+		oidToPointers[p.Oid] = append(oidToPointers[p.Oid], p)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for oid := range oids {
+			plist, ok := oidToPointers[oid]
+			if !ok {
+				continue
+			}
+
+			r.mu.Lock()
+			r.summary.Objects++
+			r.summary.Bytes += plist[0].Size
+			for _, p := range plist {
+				r.enc.Encode(&fetchJSONObject{Type: "object", Name: p.Name, Oid: p.Oid, Size: p.Size})
+			}
+			r.mu.Unlock()
+		}
+	}()
+	return done
+}
+
+func (r *fetchJSONReporter) addError(err error) {
+	r.mu.Lock()
+	r.summary.Errors = append(r.summary.Errors, err.Error())
+	r.mu.Unlock()
+}
+
+// finish writes the summary record, with the given numbers of objects which
+// are missing on the server or failed to download for other reasons.
+func (r *fetchJSONReporter) finish(missing, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.summary.Missing = missing
+	r.summary.Failed = failed
+	r.enc.Encode(r.summary)
+}
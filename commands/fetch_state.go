@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/rubyist/tracerx"
+)
+
+// fetchState is the work list of a `git lfs fetch --resume`, persisted until
+// the fetch succeeds so that a later run can skip scanning again. It is only
+// valid for the commit it was computed at: Sha records that commit, and
+// OidHash the set of objects found, so that a list which is out of date or
+// has been damaged is discarded rather than silently skipping objects.
+type fetchState struct {
+	Ref      string               `json:"ref"`
+	Sha      string               `json:"sha"`
+	OidHash  string               `json:"oid_hash"`
+	Pointers []*fetchStatePointer `json:"pointers"`
+}
+
+type fetchStatePointer struct {
+	Name string `json:"name"`
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// resumablePointers returns the pointers to fetch for ref, which is at sha.
+// If a valid work list was saved for ref by an earlier unfinished fetch, it is
+// used instead of calling scan. Otherwise scan is called, and its result saved
+// until clearFetchState is called.
+func resumablePointers(ref, sha string, scan func() []*lfs.WrappedPointer) []*lfs.WrappedPointer {
+	if state, err := loadFetchState(ref); err == nil {
+		if state.Sha == sha && state.OidHash == oidSetHash(state.pointers()) {
+			Print("Resuming fetch of %v", ref)
+			return state.pointers()
+		}
+
+		Print("Discarding stale fetch state for %v", ref)
+		tracerx.Printf("fetch: state for %v was at %v, now at %v", ref, state.Sha, sha)
+		clearFetchState(ref)
+	} else if !os.IsNotExist(err) {
+		tracerx.Printf("fetch: ignoring unreadable state for %v: %v", ref, err)
+	}
+
+	pointers := scan()
+
+	state := &fetchState{
+		Ref:      ref,
+		Sha:      sha,
+		OidHash:  oidSetHash(pointers),
+		Pointers: make([]*fetchStatePointer, 0, len(pointers)),
+	}
+	for _, p := range pointers {
+		state.Pointers = append(state.Pointers, &fetchStatePointer{Name: p.Name, Oid: p.Oid, Size: p.Size})
+	}
+	if err := saveFetchState(state); err != nil {
+		Error("Could not save fetch state for %v: %v", ref, err)
+	}
+
+	return pointers
+}
+
+// allRefsSha identifies the state of every local branch and tag, standing in
+// for the commit of a single ref when resuming `fetch --all`.
+func allRefsSha() string {
+	refs, err := git.LocalRefs()
+	if err != nil {
+		Panic(err, "Could not list refs")
+	}
+
+	lines := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		lines = append(lines, ref.Name+" "+ref.Sha)
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		fmt.Fprintln(h, line)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// oidSetHash hashes the distinct OIDs of pointers, regardless of order.
+func oidSetHash(pointers []*lfs.WrappedPointer) string {
+	seen := make(map[string]bool, len(pointers))
+	oids := make([]string, 0, len(pointers))
+	for _, p := range pointers {
+		if !seen[p.Oid] {
+			seen[p.Oid] = true
+			oids = append(oids, p.Oid)
+		}
+	}
+	sort.Strings(oids)
+
+	h := sha256.New()
+	for _, oid := range oids {
+		fmt.Fprintln(h, oid)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *fetchState) pointers() []*lfs.WrappedPointer {
+	pointers := make([]*lfs.WrappedPointer, 0, len(s.Pointers))
+	for _, p := range s.Pointers {
+		pointers = append(pointers, &lfs.WrappedPointer{
+			Name:    p.Name,
+			Size:    p.Size,
+			Pointer: lfs.NewPointer(p.Oid, p.Size, nil),
+		})
+	}
+	return pointers
+}
+
+func fetchStatePath(ref string) string {
+	sum := sha256.Sum256([]byte(ref))
+	return filepath.Join(config.LocalGitStorageDir, "lfs", "fetch-state", hex.EncodeToString(sum[:])+".json")
+}
+
+func loadFetchState(ref string) (*fetchState, error) {
+	by, err := ioutil.ReadFile(fetchStatePath(ref))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &fetchState{}
+	if err := json.Unmarshal(by, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func saveFetchState(state *fetchState) error {
+	by, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	path := fetchStatePath(state.Ref)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, by, 0644)
+}
+
+// clearFetchState removes any saved work list for ref, once the fetch it
+// describes has completed.
+func clearFetchState(ref string) {
+	if err := os.Remove(fetchStatePath(ref)); err != nil && !os.IsNotExist(err) {
+		tracerx.Printf("fetch: could not remove state for %v: %v", ref, err)
+	}
+}
@@ -78,6 +78,7 @@ func (c *uploadContext) prepareUpload(unfiltered []*lfs.WrappedPointer) (*lfs.Tr
 	// build the TransferQueue, automatically skipping any missing objects that
 	// the server already has.
 	uploadQueue := lfs.NewUploadQueue(numObjects, totalSize, c.DryRun)
+	uploadQueue.SetVerbose(transferVerboseArg)
 	for _, p := range missingLocalObjects {
 		if c.HasUploaded(p.Oid) {
 			// if the server already has this object, call Skip() on
@@ -156,6 +157,7 @@ func upload(c *uploadContext, unfiltered []*lfs.WrappedPointer) {
 	}
 
 	q.Wait()
+	printRetrySummary(q)
 
 	for _, err := range q.Errors() {
 		FullError(err)
@@ -164,4 +166,38 @@ func upload(c *uploadContext, unfiltered []*lfs.WrappedPointer) {
 	if len(q.Errors()) > 0 {
 		os.Exit(2)
 	}
+
+	if cfg.VerifyUploads() {
+		missing, err := verifyUploaded(pointers)
+		if err != nil {
+			Error("Could not verify uploaded objects: %v", err)
+			os.Exit(2)
+		}
+		if len(missing) > 0 {
+			Error("The server is missing %d uploaded object(s):", len(missing))
+			for _, p := range missing {
+				Error(" * %s (%s)", p.Name, p.Oid)
+			}
+			os.Exit(2)
+		}
+	}
+}
+
+// verifyUploaded asks the server whether it has each of the given objects,
+// which have just been uploaded, without downloading them. It returns those
+// the server doesn't have, or an error if it couldn't say whether it has any
+// of them.
+func verifyUploaded(uploaded []*lfs.WrappedPointer) ([]*lfs.WrappedPointer, error) {
+	_, notFound, err := checkRemoteObjects(uploaded)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []*lfs.WrappedPointer
+	for _, p := range uploaded {
+		if notFound.Contains(p.Oid) {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
 }
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyUploadedReportsMissingObjects(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	// the batch endpoint has every object except "dropped"
+	defer useVerifyServer(map[string]int{"dropped": 404})()
+
+	uploaded := []*lfs.WrappedPointer{
+		{Name: "a.dat", Pointer: lfs.NewPointer("stored", 1, nil)},
+		{Name: "b.dat", Pointer: lfs.NewPointer("dropped", 1, nil)},
+	}
+
+	missing, err := verifyUploaded(uploaded)
+	require.Nil(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "b.dat", missing[0].Name)
+}
+
+func TestVerifyUploadedFailsOnOtherErrors(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	defer useVerifyServer(map[string]int{"broken": 500})()
+
+	uploaded := []*lfs.WrappedPointer{
+		{Name: "a.dat", Pointer: lfs.NewPointer("stored", 1, nil)},
+		{Name: "b.dat", Pointer: lfs.NewPointer("broken", 1, nil)},
+	}
+
+	missing, err := verifyUploaded(uploaded)
+	assert.NotNil(t, err)
+	assert.Empty(t, missing)
+}
+
+// useVerifyServer points config.Config at a batch endpoint which has every
+// object, except that those in codes fail with the given status. Returns a
+// func which restores the config and stops the server.
+func useVerifyServer(codes map[string]int) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Objects []*api.ObjectResource `json:"objects"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		objs := make([]*api.ObjectResource, 0, len(req.Objects))
+		for _, o := range req.Objects {
+			obj := &api.ObjectResource{Oid: o.Oid, Size: o.Size}
+			if code, ok := codes[o.Oid]; ok {
+				obj.Error = &api.ObjectError{Code: code, Message: http.StatusText(code)}
+			} else {
+				obj.Actions = map[string]*api.LinkRelation{
+					"download": {Href: "http://example.com/" + o.Oid},
+				}
+			}
+			objs = append(objs, obj)
+		}
+
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": objs})
+	}))
+
+	oldConfig := config.Config
+	config.Config = config.NewFrom(config.Values{
+		Git: map[string]string{"lfs.url": server.URL},
+	})
+
+	return func() {
+		config.Config = oldConfig
+		server.Close()
+	}
+}
+
+func TestVerifyUploadedWithNothingUploaded(t *testing.T) {
+	missing, err := verifyUploaded(nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, missing)
+}
@@ -5,11 +5,14 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ThomsonReutersEikon/go-ntlm/ntlm"
 	"github.com/bgentry/go-netrc/netrc"
@@ -18,7 +21,17 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
+const (
+	defaultConcurrentTransfers = 3
+	maxConcurrentTransfers     = 64
+
+	defaultShardingDepth = 2
+	maxShardingDepth     = 3
+)
+
 var (
+	durationType = reflect.TypeOf(time.Duration(0))
+
 	Config                 = New()
 	ShowConfigWarnings     = false
 	defaultRemote          = "origin"
@@ -44,6 +57,9 @@ type FetchPruneConfig struct {
 	PruneVerifyRemoteAlways bool `git:"lfs.pruneverifyremotealways"`
 	// Name of remote to check for unpushed and verify checks
 	PruneRemoteName string `git:"lfs.pruneremotetocheck"`
+	// Objects whose media file was modified more recently than this are never
+	// pruned (default 0 = no grace period)
+	PruneGracePeriod time.Duration `git:"lfs.prune.grace"`
 }
 
 type Configuration struct {
@@ -65,13 +81,19 @@ type Configuration struct {
 	IsDebuggingHttp bool
 	IsLoggingStats  bool
 
+	// Dial, if set, is used by HTTP clients to open network connections in
+	// place of the default dialer, which honors lfs.dialtimeout and
+	// lfs.keepalive.
+	Dial func(network, addr string) (net.Conn, error)
+
 	loading        sync.Mutex // guards initialization of gitConfig and remotes
 	remotes        []string
 	extensions     map[string]Extension
 	manualEndpoint *Endpoint
 	parsedNetrc    netrcfinder
 	urlAliasesMap  map[string]string
-	urlAliasMu     sync.Mutex
+	urlRewritesMap map[string]string
+	urlAliasMu     sync.Mutex // guards urlAliasesMap and urlRewritesMap
 }
 
 func New() *Configuration {
@@ -115,11 +137,16 @@ func NewFrom(v Values) *Configuration {
 // according to the following rules:
 //
 // Values are marshaled according to the given key and environment, as follows:
+//
 //	type T struct {
 //		Field string `git:"key"`
 //		Other string `os:"key"`
 //	}
 //
+// Fields may be of type string, int, bool, float64, or time.Duration. Durations
+// are parsed by time.ParseDuration, e.g. "30s". An invalid float64 or
+// time.Duration value is traced and the field left alone.
+//
 // If an unknown environment is given, an error will be returned. If there is no
 // method supporting conversion into a field's type, an error will be returned.
 // If no value is associated with the given key and environment, the field will
@@ -150,18 +177,36 @@ func (c *Configuration) Unmarshal(v interface{}) error {
 		}
 
 		var val interface{}
-		switch sfield.Type.Kind() {
-		case reflect.String:
+		switch {
+		case sfield.Type.Kind() == reflect.String:
 			var ok bool
 
 			val, ok = env.Get(key)
 			if !ok {
 				val = field.String()
 			}
-		case reflect.Int:
+		case sfield.Type.Kind() == reflect.Int:
 			val = env.Int(key, int(field.Int()))
-		case reflect.Bool:
+		case sfield.Type.Kind() == reflect.Bool:
 			val = env.Bool(key, field.Bool())
+		case sfield.Type == durationType:
+			val = time.Duration(field.Int())
+			if s, ok := env.Get(key); ok {
+				if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+					val = d
+				} else {
+					tracerx.Printf("config: ignoring invalid duration %q for %q", s, key)
+				}
+			}
+		case sfield.Type.Kind() == reflect.Float64:
+			val = field.Float()
+			if s, ok := env.Get(key); ok {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+					val = f
+				} else {
+					tracerx.Printf("config: ignoring invalid number %q for %q", s, key)
+				}
+			}
 		default:
 			return fmt.Errorf(
 				"lfs/config: unsupported target type for field %q: %v",
@@ -169,7 +214,7 @@ func (c *Configuration) Unmarshal(v interface{}) error {
 		}
 
 		if val != nil {
-			into.Field(i).Set(reflect.ValueOf(val))
+			into.Field(i).Set(reflect.ValueOf(val).Convert(sfield.Type))
 		}
 	}
 
@@ -249,33 +294,181 @@ func (c *Configuration) Endpoint(operation string) Endpoint {
 	return c.RemoteEndpoint(defaultRemote, operation)
 }
 
+// ConcurrentTransfers returns the number of objects to transfer at once, from
+// lfs.concurrenttransfers. It defaults to defaultConcurrentTransfers if unset
+// or less than 1, and is capped at maxConcurrentTransfers so that a typo can't
+// start an unreasonable number of workers.
 func (c *Configuration) ConcurrentTransfers() int {
 	if c.NtlmAccess("download") {
 		return 1
 	}
 
-	uploads := 3
+	n := c.Git.Int("lfs.concurrenttransfers", defaultConcurrentTransfers)
+	if n < 1 {
+		tracerx.Printf("config: invalid lfs.concurrenttransfers %d, using %d", n, defaultConcurrentTransfers)
+		return defaultConcurrentTransfers
+	}
+	if n > maxConcurrentTransfers {
+		tracerx.Printf("config: lfs.concurrenttransfers %d is over the limit, using %d", n, maxConcurrentTransfers)
+		return maxConcurrentTransfers
+	}
 
-	if v, ok := c.Git.Get("lfs.concurrenttransfers"); ok {
-		n, err := strconv.Atoi(v)
-		if err == nil && n > 0 {
-			uploads = n
-		}
+	return n
+}
+
+// RemoteConcurrentTransfers returns the number of objects to transfer at once
+// with the given remote, from lfs.<remote>.concurrenttransfers. It falls back
+// to ConcurrentTransfers if that is unset or less than 1, and is capped at
+// maxConcurrentTransfers in the same way.
+func (c *Configuration) RemoteConcurrentTransfers(remote string) int {
+	if len(remote) == 0 || c.NtlmAccess("download") {
+		return c.ConcurrentTransfers()
 	}
 
-	return uploads
+	key := "lfs." + remote + ".concurrenttransfers"
+	if _, ok := c.Git.Get(key); !ok {
+		return c.ConcurrentTransfers()
+	}
+
+	n := c.Git.Int(key, 0)
+	if n < 1 {
+		fallback := c.ConcurrentTransfers()
+		tracerx.Printf("config: invalid %s %d, using %d", key, n, fallback)
+		return fallback
+	}
+	if n > maxConcurrentTransfers {
+		tracerx.Printf("config: %s %d is over the limit, using %d", key, n, maxConcurrentTransfers)
+		return maxConcurrentTransfers
+	}
+
+	return n
+}
+
+// CheckoutWorkers returns how many files to check out at once, from
+// lfs.checkout.workers. It defaults to the number of CPUs if unset or less
+// than 1.
+func (c *Configuration) CheckoutWorkers() int {
+	if n := c.Git.Int("lfs.checkout.workers", 0); n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
+
+// StorageShardingDepth returns how many levels of two-character directories
+// local objects are stored under, from lfs.storage.shardingdepth. It defaults
+// to defaultShardingDepth if unset or outside of 1 to maxShardingDepth.
+func (c *Configuration) StorageShardingDepth() int {
+	n := c.Git.Int("lfs.storage.shardingdepth", defaultShardingDepth)
+	if n < 1 || n > maxShardingDepth {
+		tracerx.Printf("config: invalid lfs.storage.shardingdepth %d, using %d", n, defaultShardingDepth)
+		return defaultShardingDepth
+	}
+	return n
+}
+
+// ApiMaxConnsPerHost returns the maximum number of concurrent batch and legacy
+// API requests made to any one host, from lfs.api.maxconnsperhost. Zero means
+// there is no limit.
+func (c *Configuration) ApiMaxConnsPerHost() int {
+	if n := c.Git.Int("lfs.api.maxconnsperhost", 0); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// BoolWithEnvOverride returns the boolean value of the environment variable
+// envKey if it is set to a non-empty value, so that it takes precedence over
+// the git config. Otherwise it returns the value of gitKey, or def.
+func (c *Configuration) BoolWithEnvOverride(envKey, gitKey string, def bool) bool {
+	if v, ok := c.Os.Get(envKey); ok && len(strings.TrimSpace(v)) > 0 {
+		return c.Os.Bool(envKey, def)
+	}
+	return c.Git.Bool(gitKey, def)
 }
 
 // BasicTransfersOnly returns whether to only allow "basic" HTTP transfers.
-// Default is false, including if the lfs.basictransfersonly is invalid
+// Default is false, including if the lfs.basictransfersonly is invalid. It is
+// overridden by GIT_LFS_BASIC_TRANSFERS_ONLY.
 func (c *Configuration) BasicTransfersOnly() bool {
-	return c.Git.Bool("lfs.basictransfersonly", false)
+	return c.BoolWithEnvOverride("GIT_LFS_BASIC_TRANSFERS_ONLY", "lfs.basictransfersonly", false)
 }
 
 // TusTransfersAllowed returns whether to only use "tus.io" HTTP transfers.
-// Default is false, including if the lfs.tustransfers is invalid
+// Default is false, including if the lfs.tustransfers is invalid. It is
+// overridden by GIT_LFS_TUS_TRANSFERS.
 func (c *Configuration) TusTransfersAllowed() bool {
-	return c.Git.Bool("lfs.tustransfers", false)
+	return c.BoolWithEnvOverride("GIT_LFS_TUS_TRANSFERS", "lfs.tustransfers", false)
+}
+
+// CleanMinSize returns the size, in bytes, below which the clean filter keeps
+// files in Git as they are rather than converting them to pointers, from
+// lfs.clean.minsize. It is 0, meaning every file is converted, if unset or
+// invalid.
+func (c *Configuration) CleanMinSize() int64 {
+	return c.byteSize("lfs.clean.minsize")
+}
+
+// TusMinSize returns the size, in bytes, below which objects are uploaded with
+// the basic adapter even if tus is allowed, from lfs.tus.minsize. Resumable
+// uploads only pay off for large objects. It is 0, meaning tus is offered for
+// every object, if unset or invalid.
+func (c *Configuration) TusMinSize() int64 {
+	return c.byteSize("lfs.tus.minsize")
+}
+
+// ProgressRefreshInterval returns how often progress meters should redraw,
+// from lfs.progress.refreshinterval in milliseconds. Default is 100ms,
+// including if the value is invalid or not positive.
+func (c *Configuration) ProgressRefreshInterval() time.Duration {
+	ms := c.Git.Int("lfs.progress.refreshinterval", 100)
+	if ms < 1 {
+		ms = 100
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// SkipEmptyObjects returns whether zero-byte objects are satisfied locally
+// instead of being sent through the API, from lfs.transfer.skipemptyobjects.
+// Downloads skip them by default, since their content is always known, but
+// uploads don't, so that clients which do ask the server can find them.
+func (c *Configuration) SkipEmptyObjects(upload bool) bool {
+	return c.Git.Bool("lfs.transfer.skipemptyobjects", !upload)
+}
+
+// TransferLargeObjectSize returns the size, in bytes, from which objects are
+// sent to the batch API on their own, from lfs.transfer.largeobjectsize. It is
+// 0, meaning objects are never split out, if unset or invalid.
+func (c *Configuration) TransferLargeObjectSize() int64 {
+	return c.byteSize("lfs.transfer.largeobjectsize")
+}
+
+// byteSize returns the number of bytes given by the git config key, or 0 if it
+// is unset or isn't a number of bytes.
+func (c *Configuration) byteSize(key string) int64 {
+	s, ok := c.Git.Get(key)
+	if !ok {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		tracerx.Printf("config: invalid %s %q, ignoring", key, s)
+		return 0
+	}
+	return n
+}
+
+// BatchMaxObjects returns the most objects to send in one batch API request,
+// from lfs.batch.maxobjects, for servers which limit how many they accept.
+// Larger batches are split into several requests. It defaults to 0, meaning
+// no limit, if unset or less than 1.
+func (c *Configuration) BatchMaxObjects() int {
+	n := c.Git.Int("lfs.batch.maxobjects", 0)
+	if n < 0 {
+		tracerx.Printf("config: invalid lfs.batch.maxobjects %d, ignoring", n)
+		return 0
+	}
+	return n
 }
 
 func (c *Configuration) BatchTransfer() bool {
@@ -354,13 +547,23 @@ func (c *Configuration) SetEndpointAccess(e Endpoint, authType string) {
 }
 
 func (c *Configuration) FetchIncludePaths() []string {
-	patterns, _ := c.Git.Get("lfs.fetchinclude")
-	return tools.CleanPaths(patterns, ",")
+	return c.gitPatterns("lfs.fetchinclude")
 }
 
 func (c *Configuration) FetchExcludePaths() []string {
-	patterns, _ := c.Git.Get("lfs.fetchexclude")
-	return tools.CleanPaths(patterns, ",")
+	return c.gitPatterns("lfs.fetchexclude")
+}
+
+// gitPatterns returns the paths listed in every value of the given git config
+// key, which may be separated by commas or newlines.
+func (c *Configuration) gitPatterns(key string) []string {
+	var patterns []string
+	for _, val := range c.Git.GetAll(key) {
+		for _, line := range strings.Split(val, "\n") {
+			patterns = append(patterns, tools.CleanPaths(line, ",")...)
+		}
+	}
+	return patterns
 }
 
 func (c *Configuration) RemoteEndpoint(remote, operation string) Endpoint {
@@ -456,6 +659,52 @@ func (c *Configuration) ReplaceUrlAlias(rawurl string) string {
 	return rawurl
 }
 
+func (c *Configuration) urlRewrites() map[string]string {
+	c.urlAliasMu.Lock()
+	defer c.urlAliasMu.Unlock()
+
+	if c.urlRewritesMap == nil {
+		c.urlRewritesMap = make(map[string]string)
+		prefix := "lfs.urlrewrite."
+		suffix := ".to"
+		for gitkey, gitval := range c.Git.All() {
+			if strings.HasPrefix(gitkey, prefix) && strings.HasSuffix(gitkey, suffix) && len(gitkey) > len(prefix)+len(suffix) {
+				c.urlRewritesMap[gitkey[len(prefix):len(gitkey)-len(suffix)]] = gitval
+			}
+		}
+	}
+
+	return c.urlRewritesMap
+}
+
+// RewriteActionUrl returns an action url returned by the API, with a prefix
+// replaced by a `lfs.urlrewrite.<from>.to` git config setting. This works like
+// `url.*.insteadof`, but only for the urls objects are transferred from. Git
+// lowercases config keys, so prefixes match case-insensitively. If multiple
+// prefixes match, use the longest one.
+func (c *Configuration) RewriteActionUrl(rawurl string) string {
+	var longestprefix string
+	lowerurl := strings.ToLower(rawurl)
+	rewrites := c.urlRewrites()
+	for prefix := range rewrites {
+		if !strings.HasPrefix(lowerurl, prefix) {
+			continue
+		}
+
+		if len(longestprefix) < len(prefix) {
+			longestprefix = prefix
+		}
+	}
+
+	if len(longestprefix) > 0 {
+		rewritten := rewrites[longestprefix] + rawurl[len(longestprefix):]
+		tracerx.Printf("rewriting action url %q to %q", rawurl, rewritten)
+		return rewritten
+	}
+
+	return rawurl
+}
+
 func (c *Configuration) FetchPruneConfig() FetchPruneConfig {
 	f := &FetchPruneConfig{
 		FetchRecentRefsDays:           7,
@@ -467,9 +716,43 @@ func (c *Configuration) FetchPruneConfig() FetchPruneConfig {
 	if err := c.Unmarshal(f); err != nil {
 		panic(err.Error())
 	}
+
+	if f.PruneGracePeriod < 0 {
+		tracerx.Printf("config: ignoring negative lfs.prune.grace %v", f.PruneGracePeriod)
+		f.PruneGracePeriod = 0
+	}
 	return *f
 }
 
+// EnvRemote returns the remote named by GIT_LFS_REMOTE, or an empty string if
+// it is not set. Commands use it in place of the default remote when no remote
+// is given on the command line.
+func (c *Configuration) EnvRemote() string {
+	remote, _ := c.Os.Get("GIT_LFS_REMOTE")
+	return strings.TrimSpace(remote)
+}
+
+// SmudgeVerify returns whether to check the OID of objects read from the local
+// store during smudge, from lfs.smudge.verify. Default is false.
+func (c *Configuration) SmudgeVerify() bool {
+	return c.Git.Bool("lfs.smudge.verify", false)
+}
+
+// UserAgentSuffix returns the text to append to the User-Agent of every
+// request, from lfs.useragentsuffix, so that proxies can identify where
+// requests come from. It is empty by default.
+func (c *Configuration) UserAgentSuffix() string {
+	suffix, _ := c.Git.Get("lfs.useragentsuffix")
+	return strings.TrimSpace(suffix)
+}
+
+// VerifyUploads returns whether to ask the server for each object after
+// uploading it, to make sure it was stored, from lfs.verifyupload. Default is
+// false.
+func (c *Configuration) VerifyUploads() bool {
+	return c.Git.Bool("lfs.verifyupload", false)
+}
+
 func (c *Configuration) SkipDownloadErrors() bool {
 	return c.Os.Bool("GIT_LFS_SKIP_DOWNLOAD_ERRORS", false) || c.Git.Bool("lfs.skipdownloaderrors", false)
 }
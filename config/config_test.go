@@ -1,6 +1,8 @@
 package config
 
 import (
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -267,6 +269,59 @@ func TestBareGitEndpointAddsLfsSuffix(t *testing.T) {
 	assert.Equal(t, "", endpoint.SshPort)
 }
 
+func TestUserAgentSuffix(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.useragentsuffix": " acme-proxy/1.0 ",
+		},
+	})
+
+	assert.Equal(t, "acme-proxy/1.0", cfg.UserAgentSuffix())
+	assert.Equal(t, "", NewFrom(Values{}).UserAgentSuffix())
+}
+
+func TestCheckoutWorkers(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.checkout.workers": "4",
+		},
+	})
+
+	assert.Equal(t, 4, cfg.CheckoutWorkers())
+}
+
+func TestCheckoutWorkersDefaultsToCPUs(t *testing.T) {
+	for _, value := range []string{"", "0", "-1", "elephant"} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.checkout.workers": value,
+			},
+		})
+
+		assert.Equal(t, runtime.NumCPU(), cfg.CheckoutWorkers(), "lfs.checkout.workers=%q", value)
+	}
+}
+
+func TestStorageShardingDepth(t *testing.T) {
+	for value, expected := range map[string]int{
+		"":         2,
+		"1":        1,
+		"2":        2,
+		"3":        3,
+		"0":        2,
+		"4":        2,
+		"elephant": 2,
+	} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.storage.shardingdepth": value,
+			},
+		})
+
+		assert.Equal(t, expected, cfg.StorageShardingDepth(), "lfs.storage.shardingdepth=%q", value)
+	}
+}
+
 func TestConcurrentTransfersSetValue(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string]string{
@@ -318,6 +373,53 @@ func TestConcurrentTransfersNegativeValue(t *testing.T) {
 	assert.Equal(t, 3, n)
 }
 
+func TestConcurrentTransfersMaxValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.concurrenttransfers": "64",
+		},
+	})
+
+	n := cfg.ConcurrentTransfers()
+	assert.Equal(t, 64, n)
+}
+
+func TestConcurrentTransfersOverLimit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.concurrenttransfers": "1000",
+		},
+	})
+
+	n := cfg.ConcurrentTransfers()
+	assert.Equal(t, 64, n)
+}
+
+func TestRemoteConcurrentTransfers(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.concurrenttransfers":         "5",
+			"lfs.mirror.concurrenttransfers":  "20",
+			"lfs.public.concurrenttransfers":  "2",
+			"lfs.broken.concurrenttransfers":  "elephant",
+			"lfs.toomany.concurrenttransfers": "1000",
+		},
+	})
+
+	assert.Equal(t, 20, cfg.RemoteConcurrentTransfers("mirror"))
+	assert.Equal(t, 2, cfg.RemoteConcurrentTransfers("public"))
+	assert.Equal(t, 5, cfg.RemoteConcurrentTransfers("origin"))
+	assert.Equal(t, 5, cfg.RemoteConcurrentTransfers(""))
+	assert.Equal(t, 5, cfg.RemoteConcurrentTransfers("broken"))
+	assert.Equal(t, 64, cfg.RemoteConcurrentTransfers("toomany"))
+}
+
+func TestRemoteConcurrentTransfersDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.Equal(t, 3, cfg.RemoteConcurrentTransfers("origin"))
+}
+
 func TestBasicTransfersOnlySetValue(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string]string{
@@ -347,6 +449,55 @@ func TestBasicTransfersOnlyInvalidValue(t *testing.T) {
 	assert.Equal(t, false, b)
 }
 
+func TestBasicTransfersOnlyEnvOnly(t *testing.T) {
+	cfg := NewFrom(Values{
+		Os: map[string]string{
+			"GIT_LFS_BASIC_TRANSFERS_ONLY": "1",
+		},
+	})
+
+	assert.True(t, cfg.BasicTransfersOnly())
+}
+
+func TestBasicTransfersOnlyEnvAndGitAgree(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.basictransfersonly": "true",
+		},
+		Os: map[string]string{
+			"GIT_LFS_BASIC_TRANSFERS_ONLY": "true",
+		},
+	})
+
+	assert.True(t, cfg.BasicTransfersOnly())
+}
+
+func TestBasicTransfersOnlyEnvOverridesGit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.basictransfersonly": "true",
+		},
+		Os: map[string]string{
+			"GIT_LFS_BASIC_TRANSFERS_ONLY": "false",
+		},
+	})
+
+	assert.False(t, cfg.BasicTransfersOnly())
+}
+
+func TestBasicTransfersOnlyEmptyEnvKeepsGit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.basictransfersonly": "true",
+		},
+		Os: map[string]string{
+			"GIT_LFS_BASIC_TRANSFERS_ONLY": "",
+		},
+	})
+
+	assert.True(t, cfg.BasicTransfersOnly())
+}
+
 func TestTusTransfersAllowedSetValue(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string]string{
@@ -376,6 +527,129 @@ func TestTusTransfersAllowedInvalidValue(t *testing.T) {
 	assert.Equal(t, false, b)
 }
 
+func TestTusTransfersAllowedEnvOverridesGit(t *testing.T) {
+	for env, expected := range map[string]bool{"1": true, "0": false} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.tustransfers": "true",
+			},
+			Os: map[string]string{
+				"GIT_LFS_TUS_TRANSFERS": env,
+			},
+		})
+
+		assert.Equal(t, expected, cfg.TusTransfersAllowed(), env)
+	}
+}
+
+func TestCleanMinSize(t *testing.T) {
+	for v, expected := range map[string]int64{"": 0, "1024": 1024, "-1": 0, "1k": 0} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.clean.minsize": v,
+			},
+		})
+
+		assert.Equal(t, expected, cfg.CleanMinSize(), v)
+	}
+}
+
+func TestTusMinSizeSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.tus.minsize": "10485760",
+		},
+	})
+
+	assert.Equal(t, int64(10485760), cfg.TusMinSize())
+}
+
+func TestTusMinSizeDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.Equal(t, int64(0), cfg.TusMinSize())
+}
+
+func TestTusMinSizeInvalidValue(t *testing.T) {
+	for _, v := range []string{"-1", "10MB"} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.tus.minsize": v,
+			},
+		})
+
+		assert.Equal(t, int64(0), cfg.TusMinSize(), v)
+	}
+}
+
+func TestProgressRefreshIntervalSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.progress.refreshinterval": "250",
+		},
+	})
+
+	assert.Equal(t, 250*time.Millisecond, cfg.ProgressRefreshInterval())
+}
+
+func TestProgressRefreshIntervalDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.Equal(t, 100*time.Millisecond, cfg.ProgressRefreshInterval())
+}
+
+func TestProgressRefreshIntervalInvalidValue(t *testing.T) {
+	for _, v := range []string{"0", "-5", "elephant"} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.progress.refreshinterval": v,
+			},
+		})
+
+		assert.Equal(t, 100*time.Millisecond, cfg.ProgressRefreshInterval(), v)
+	}
+}
+
+func TestTransferLargeObjectSizeSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.transfer.largeobjectsize": "1073741824",
+		},
+	})
+
+	assert.Equal(t, int64(1073741824), cfg.TransferLargeObjectSize())
+}
+
+func TestTransferLargeObjectSizeDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.Equal(t, int64(0), cfg.TransferLargeObjectSize())
+}
+
+func TestTransferLargeObjectSizeInvalidValue(t *testing.T) {
+	for _, v := range []string{"-5", "1GB", "elephant"} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.transfer.largeobjectsize": v,
+			},
+		})
+
+		assert.Equal(t, int64(0), cfg.TransferLargeObjectSize(), v)
+	}
+}
+
+func TestBatchMaxObjects(t *testing.T) {
+	for v, expected := range map[string]int{"": 0, "100": 100, "0": 0, "-1": 0, "elephant": 0} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.batch.maxobjects": v,
+			},
+		})
+
+		assert.Equal(t, expected, cfg.BatchMaxObjects(), v)
+	}
+}
+
 func TestBatch(t *testing.T) {
 	tests := map[string]bool{
 		"":         true,
@@ -524,6 +798,7 @@ func TestFetchPruneConfigDefault(t *testing.T) {
 	assert.Equal(t, 3, fp.PruneOffsetDays)
 	assert.Equal(t, "origin", fp.PruneRemoteName)
 	assert.False(t, fp.PruneVerifyRemoteAlways)
+	assert.Equal(t, time.Duration(0), fp.PruneGracePeriod)
 
 }
 func TestFetchPruneConfigCustom(t *testing.T) {
@@ -535,10 +810,13 @@ func TestFetchPruneConfigCustom(t *testing.T) {
 			"lfs.pruneoffsetdays":         "30",
 			"lfs.pruneverifyremotealways": "true",
 			"lfs.pruneremotetocheck":      "upstream",
+			"lfs.prune.grace":             "1h30m",
 		},
 	})
 	fp := cfg.FetchPruneConfig()
 
+	assert.Equal(t, 90*time.Minute, fp.PruneGracePeriod)
+
 	assert.Equal(t, 12, fp.FetchRecentRefsDays)
 	assert.Equal(t, 9, fp.FetchRecentCommitsDays)
 	assert.False(t, fp.FetchRecentRefsIncludeRemotes)
@@ -547,6 +825,70 @@ func TestFetchPruneConfigCustom(t *testing.T) {
 	assert.True(t, fp.PruneVerifyRemoteAlways)
 }
 
+func TestFetchPruneConfigInvalidGracePeriod(t *testing.T) {
+	for _, v := range []string{"1", "-1h", "elephant"} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.prune.grace": v,
+			},
+		})
+
+		assert.Equal(t, time.Duration(0), cfg.FetchPruneConfig().PruneGracePeriod, v)
+	}
+}
+
+func TestApiMaxConnsPerHost(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.api.maxconnsperhost": "4",
+		},
+	})
+
+	assert.Equal(t, 4, cfg.ApiMaxConnsPerHost())
+}
+
+func TestApiMaxConnsPerHostDefaultsToUnlimited(t *testing.T) {
+	for _, v := range []string{"", "0", "-2", "elephant"} {
+		cfg := NewFrom(Values{
+			Git: map[string]string{
+				"lfs.api.maxconnsperhost": v,
+			},
+		})
+
+		assert.Equal(t, 0, cfg.ApiMaxConnsPerHost(), v)
+	}
+}
+
+func TestRewriteActionUrl(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.urlrewrite.http://internal.example.com/.to":         "https://cdn.example.com/",
+			"lfs.urlrewrite.http://internal.example.com/private/.to": "https://private.example.com/lfs/",
+		},
+	})
+
+	assert.Equal(t, "https://cdn.example.com/objects/abc", cfg.RewriteActionUrl("http://internal.example.com/objects/abc"))
+	assert.Equal(t, "https://cdn.example.com/Objects/ABC", cfg.RewriteActionUrl("http://INTERNAL.example.com/Objects/ABC"))
+	assert.Equal(t, "https://private.example.com/lfs/abc", cfg.RewriteActionUrl("http://internal.example.com/private/abc"))
+	assert.Equal(t, "https://other.example.com/objects/abc", cfg.RewriteActionUrl("https://other.example.com/objects/abc"))
+}
+
+func TestEnvRemote(t *testing.T) {
+	cfg := NewFrom(Values{
+		Os: map[string]string{
+			"GIT_LFS_REMOTE": " upstream ",
+		},
+	})
+
+	assert.Equal(t, "upstream", cfg.EnvRemote())
+}
+
+func TestEnvRemoteUnset(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.Equal(t, "", cfg.EnvRemote())
+}
+
 func TestFetchIncludeExcludesAreCleaned(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string]string{
@@ -559,6 +901,48 @@ func TestFetchIncludeExcludesAreCleaned(t *testing.T) {
 	assert.Equal(t, []string{"/other/path/to/clean"}, cfg.FetchExcludePaths())
 }
 
+func TestFetchIncludeExcludesAcceptCommasAndNewlines(t *testing.T) {
+	commas := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.fetchinclude": "a/b, c/d",
+			"lfs.fetchexclude": "e/f,g/h",
+		},
+	})
+	newlines := NewFrom(Values{
+		Git: map[string]string{
+			"lfs.fetchinclude": "a/b\nc/d\n",
+			"lfs.fetchexclude": "e/f\n g/h",
+		},
+	})
+
+	assert.Equal(t, []string{"a/b", "c/d"}, commas.FetchIncludePaths())
+	assert.Equal(t, []string{"e/f", "g/h"}, commas.FetchExcludePaths())
+	assert.Equal(t, commas.FetchIncludePaths(), newlines.FetchIncludePaths())
+	assert.Equal(t, commas.FetchExcludePaths(), newlines.FetchExcludePaths())
+}
+
+func TestFetchIncludeMergesRepeatedKeys(t *testing.T) {
+	gf, _, _ := ReadGitConfig(NewGitConfig(strings.Join([]string{
+		"lfs.fetchinclude=a/b",
+		"lfs.fetchinclude=c/d,e/f",
+	}, "\n"), false))
+	cfg := NewFrom(Values{})
+	cfg.Git = EnvironmentOf(gf)
+
+	assert.Equal(t, []string{"a/b", "c/d", "e/f"}, cfg.FetchIncludePaths())
+}
+
+func TestFetchIncludeLaterConfigOverrides(t *testing.T) {
+	gf, _, _ := ReadGitConfig(
+		NewGitConfig("lfs.fetchinclude=a/b\nlfs.fetchinclude=c/d", true),
+		NewGitConfig("lfs.fetchinclude=e/f", false),
+	)
+	cfg := NewFrom(Values{})
+	cfg.Git = EnvironmentOf(gf)
+
+	assert.Equal(t, []string{"e/f"}, cfg.FetchIncludePaths())
+}
+
 func TestUnmarshalMultipleTypes(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string]string{
@@ -668,14 +1052,54 @@ func TestUnmarshalIgnoresUnknownEnvironments(t *testing.T) {
 
 func TestUnmarshalErrsOnUnsupportedTypes(t *testing.T) {
 	v := &struct {
-		Unsupported time.Duration `git:"duration"`
+		Unsupported []string `git:"list"`
 	}{}
 
 	cfg := NewFrom(Values{
-		Git: map[string]string{"duration": "foo"},
+		Git: map[string]string{"list": "foo"},
 	})
 
 	err := cfg.Unmarshal(v)
 
-	assert.Equal(t, "lfs/config: unsupported target type for field \"Unsupported\": time.Duration", err.Error())
+	assert.Equal(t, "lfs/config: unsupported target type for field \"Unsupported\": []string", err.Error())
+}
+
+func TestUnmarshalDurationsAndFloats(t *testing.T) {
+	v := &struct {
+		Timeout time.Duration `git:"timeout"`
+		Ratio   float64       `os:"RATIO"`
+	}{}
+
+	cfg := NewFrom(Values{
+		Git: map[string]string{"timeout": "1m30s"},
+		Os:  map[string]string{"RATIO": "0.75"},
+	})
+
+	assert.Nil(t, cfg.Unmarshal(v))
+	assert.Equal(t, 90*time.Second, v.Timeout)
+	assert.Equal(t, 0.75, v.Ratio)
+}
+
+func TestUnmarshalLeavesDurationsAndFloatsWhenInvalid(t *testing.T) {
+	v := &struct {
+		Timeout time.Duration `git:"timeout"`
+		Ratio   float64       `git:"ratio"`
+		Other   int           `git:"other"`
+	}{
+		Timeout: 30 * time.Second,
+		Ratio:   0.5,
+	}
+
+	cfg := NewFrom(Values{
+		Git: map[string]string{
+			"timeout": "30",
+			"ratio":   "half",
+			"other":   "1",
+		},
+	})
+
+	assert.Nil(t, cfg.Unmarshal(v))
+	assert.Equal(t, 30*time.Second, v.Timeout)
+	assert.Equal(t, 0.5, v.Ratio)
+	assert.Equal(t, 1, v.Other)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"sort"
+	"strings"
 )
 
 // An Extension describes how to manipulate files during smudge and clean.
@@ -14,26 +15,52 @@ type Extension struct {
 	Priority int
 }
 
-// SortExtensions sorts a map of extensions in ascending order by Priority
-func SortExtensions(m map[string]Extension) ([]Extension, error) {
-	pMap := make(map[int]Extension)
-	priorities := make([]int, 0, len(m))
-	for n, ext := range m {
-		p := ext.Priority
-		if _, exist := pMap[p]; exist {
-			err := fmt.Errorf("duplicate priority %d on %s", p, n)
-			return nil, err
+// ValidateExtensions returns an error naming the first extension, in order of
+// name, which has no clean or smudge command, or shares its priority with
+// another extension.
+func ValidateExtensions(m map[string]Extension) error {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	byPriority := make(map[int]string, len(m))
+	for _, n := range names {
+		ext := m[n]
+		if len(strings.TrimSpace(ext.Clean)) == 0 {
+			return fmt.Errorf("extension %s has no clean command", n)
 		}
-		pMap[p] = ext
-		priorities = append(priorities, p)
+		if len(strings.TrimSpace(ext.Smudge)) == 0 {
+			return fmt.Errorf("extension %s has no smudge command", n)
+		}
+		if other, exist := byPriority[ext.Priority]; exist {
+			return fmt.Errorf("duplicate priority %d on %s and %s", ext.Priority, other, n)
+		}
+		byPriority[ext.Priority] = n
 	}
 
-	sort.Ints(priorities)
+	return nil
+}
 
-	result := make([]Extension, len(priorities))
-	for i, p := range priorities {
-		result[i] = pMap[p]
+// SortExtensions sorts a map of extensions in ascending order by Priority,
+// after checking them with ValidateExtensions.
+func SortExtensions(m map[string]Extension) ([]Extension, error) {
+	if err := ValidateExtensions(m); err != nil {
+		return nil, err
+	}
+
+	result := make([]Extension, 0, len(m))
+	for _, ext := range m {
+		result = append(result, ext)
 	}
+	sort.Sort(byExtensionPriority(result))
 
 	return result, nil
 }
+
+type byExtensionPriority []Extension
+
+func (e byExtensionPriority) Len() int           { return len(e) }
+func (e byExtensionPriority) Less(i, j int) bool { return e[i].Priority < e[j].Priority }
+func (e byExtensionPriority) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -64,3 +65,55 @@ func TestSortExtensionsDuplicatePriority(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Empty(t, sorted)
 }
+
+func TestSortExtensionsDuplicatePriorityNamesBoth(t *testing.T) {
+	m := map[string]Extension{
+		"foo": Extension{"foo", "foo-clean %f", "foo-smudge %f", 1},
+		"bar": Extension{"bar", "bar-clean %f", "bar-smudge %f", 1},
+		"baz": Extension{"baz", "baz-clean %f", "baz-smudge %f", 0},
+	}
+
+	_, err := SortExtensions(m)
+
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "duplicate priority 1 on bar and foo", err.Error())
+	}
+}
+
+func TestSortExtensionsMissingCommands(t *testing.T) {
+	for desc, c := range map[string]struct {
+		Ext Extension
+		Err string
+	}{
+		"no clean":  {Extension{"foo", "", "foo-smudge %f", 1}, "extension foo has no clean command"},
+		"no smudge": {Extension{"foo", "foo-clean %f", " ", 1}, "extension foo has no smudge command"},
+	} {
+		m := map[string]Extension{
+			"foo": c.Ext,
+			"bar": Extension{"bar", "bar-clean %f", "bar-smudge %f", 0},
+		}
+
+		sorted, err := SortExtensions(m)
+
+		assert.Empty(t, sorted, desc)
+		if assert.NotNil(t, err, desc) {
+			assert.Equal(t, c.Err, err.Error(), desc)
+		}
+	}
+}
+
+func TestSortedExtensionsValidatesConfig(t *testing.T) {
+	gf, extensions, _ := ReadGitConfig(NewGitConfig(strings.Join([]string{
+		"lfs.extension.foo.clean=foo-clean %f",
+		"lfs.extension.foo.priority=0",
+	}, "\n"), false))
+	cfg := NewFrom(Values{})
+	cfg.Git = EnvironmentOf(gf)
+	cfg.extensions = extensions
+
+	_, err := cfg.SortedExtensions()
+
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "extension foo has no smudge command", err.Error())
+	}
+}
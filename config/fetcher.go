@@ -8,6 +8,10 @@ type Fetcher interface {
 	// determining if the key exists.
 	Get(key string) (val string, ok bool)
 
+	// GetAll returns every value associated with a given key, in the order
+	// they were read, or nil if the key does not exist.
+	GetAll(key string) []string
+
 	// All returns a copy of all the key/value pairs for the current environment.
 	All() map[string]string
 
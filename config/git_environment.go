@@ -21,6 +21,14 @@ func (g *gitEnvironment) Get(key string) (val string, ok bool) {
 	return g.git.Get(key)
 }
 
+// GetAll is shorthand for calling the loadGitConfig, and then returning
+// `g.git.GetAll(key)`.
+func (g *gitEnvironment) GetAll(key string) []string {
+	g.loadGitConfig()
+
+	return g.git.GetAll(key)
+}
+
 // Get is shorthand for calling the loadGitConfig, and then returning
 // `g.git.Bool(key, def)`.
 func (g *gitEnvironment) Bool(key string, def bool) (val bool) {
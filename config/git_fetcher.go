@@ -15,6 +15,9 @@ import (
 type GitFetcher struct {
 	vmu  sync.RWMutex
 	vals map[string]string
+	// multi holds every value of each key, since git allows a key to be
+	// repeated. vals holds the last of them.
+	multi map[string][]string
 }
 
 type GitConfig struct {
@@ -31,12 +34,17 @@ func NewGitConfig(gitconfiglines string, onlysafe bool) *GitConfig {
 
 func ReadGitConfig(configs ...*GitConfig) (gf *GitFetcher, extensions map[string]Extension, uniqRemotes map[string]bool) {
 	vals := make(map[string]string)
+	multi := make(map[string][]string)
 
 	extensions = make(map[string]Extension)
 	uniqRemotes = make(map[string]bool)
 
 	for _, gc := range configs {
 		uniqKeys := make(map[string]string)
+		// seenKeys tracks the keys already stored from this config, so
+		// that values repeated within it are merged, while a later
+		// config still overrides an earlier one.
+		seenKeys := make(map[string]bool)
 
 		for _, line := range gc.Lines {
 			pieces := strings.SplitN(line, "=", 2)
@@ -103,10 +111,15 @@ func ReadGitConfig(configs ...*GitConfig) (gf *GitFetcher, extensions map[string
 			}
 
 			vals[key] = val
+			if !seenKeys[key] {
+				seenKeys[key] = true
+				multi[key] = nil
+			}
+			multi[key] = append(multi[key], val)
 		}
 	}
 
-	gf = &GitFetcher{vals: vals}
+	gf = &GitFetcher{vals: vals, multi: multi}
 
 	return
 }
@@ -127,6 +140,22 @@ func (g *GitFetcher) Get(key string) (val string, ok bool) {
 	return
 }
 
+// GetAll implements the Fetcher interface, and returns every value associated
+// with a given key, in the order they appear in the config that set it. If the
+// key is absent, nil is returned.
+//
+// GetAll is safe to call across multiple goroutines.
+func (g *GitFetcher) GetAll(key string) []string {
+	g.vmu.RLock()
+	defer g.vmu.RUnlock()
+
+	vals := g.multi[strings.ToLower(key)]
+	if len(vals) == 0 {
+		return nil
+	}
+	return append([]string(nil), vals...)
+}
+
 func (g *GitFetcher) All() map[string]string {
 	newmap := make(map[string]string)
 
@@ -144,12 +173,14 @@ func (g *GitFetcher) set(key, value string) {
 	g.vmu.Lock()
 	defer g.vmu.Unlock()
 	g.vals[strings.ToLower(key)] = value
+	g.multi[strings.ToLower(key)] = []string{value}
 }
 
 func (g *GitFetcher) del(key string) {
 	g.vmu.Lock()
 	defer g.vmu.Unlock()
 	delete(g.vals, strings.ToLower(key))
+	delete(g.multi, strings.ToLower(key))
 }
 
 func getGitConfigs() (sources []*GitConfig) {
@@ -202,6 +233,7 @@ func keyIsUnsafe(key string) bool {
 }
 
 var safeKeys = []string{
+	"lfs.clean.minsize",
 	"lfs.fetchexclude",
 	"lfs.fetchinclude",
 	"lfs.gitprotocol",
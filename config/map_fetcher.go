@@ -14,6 +14,15 @@ func (m mapFetcher) Get(key string) (val string, ok bool) {
 	return
 }
 
+// GetAll implements the func `Fetcher.GetAll`. A map holds at most one value
+// per key.
+func (m mapFetcher) GetAll(key string) []string {
+	if val, ok := m[key]; ok {
+		return []string{val}
+	}
+	return nil
+}
+
 func (m mapFetcher) All() map[string]string {
 	newmap := make(map[string]string)
 	for key, value := range m {
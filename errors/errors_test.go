@@ -73,3 +73,19 @@ func TestContextOnWrappedErrors(t *testing.T) {
 		t.Errorf("expected to delete from error context")
 	}
 }
+
+func TestObjectMissingErrorRecordsOid(t *testing.T) {
+	err := NewObjectMissingError(errors.New("Go error"), "oid")
+
+	if !IsObjectMissingError(err) {
+		t.Error("expected error to be an object missing error")
+	}
+
+	if IsObjectMissingError(errors.New("Go error")) {
+		t.Error("go error should not be an object missing error")
+	}
+
+	if v := GetContext(err, "OID"); v != "oid" {
+		t.Errorf("expected OID context of %q, got %v", "oid", v)
+	}
+}
@@ -140,6 +140,20 @@ func IsRetriableError(err error) bool {
 	return false
 }
 
+// IsObjectMissingError indicates the server does not have an object the client
+// asked to download. The object's OID is in the "OID" context key.
+func IsObjectMissingError(err error) bool {
+	if e, ok := err.(interface {
+		ObjectMissingError() bool
+	}); ok {
+		return e.ObjectMissingError()
+	}
+	if parent := parentOf(err); parent != nil {
+		return IsObjectMissingError(parent)
+	}
+	return false
+}
+
 type errorWithCause interface {
 	Cause() error
 	StackTrace() errors.StackTrace
@@ -331,6 +345,22 @@ func NewRetriableError(err error) error {
 	return retriableError{newWrappedError(err, "")}
 }
 
+// Definitions for IsObjectMissingError()
+
+type objectMissingError struct {
+	*wrappedError
+}
+
+func (e objectMissingError) ObjectMissingError() bool {
+	return true
+}
+
+func NewObjectMissingError(err error, oid string) error {
+	e := objectMissingError{newWrappedError(err, "")}
+	SetContext(e, "OID", oid)
+	return e
+}
+
 func parentOf(err error) error {
 	if c, ok := err.(errorWithCause); ok {
 		return c.Cause()
@@ -12,8 +12,9 @@ type Pattern interface {
 }
 
 type Filter struct {
-	include []Pattern
-	exclude []Pattern
+	include  []Pattern
+	exclude  []Pattern
+	caseFold bool
 }
 
 func NewFromPatterns(include, exclude []Pattern) *Filter {
@@ -24,6 +25,14 @@ func New(include, exclude []string) *Filter {
 	return NewFromPatterns(convertToPatterns(include), convertToPatterns(exclude))
 }
 
+// NewCaseInsensitive returns a Filter like New, but which ignores case when
+// matching, as the filesystems on macOS and Windows usually do.
+func NewCaseInsensitive(include, exclude []string) *Filter {
+	f := New(toLower(include), toLower(exclude))
+	f.caseFold = true
+	return f
+}
+
 func (f *Filter) Allows(filename string) bool {
 	if f == nil {
 		return true
@@ -33,33 +42,110 @@ func (f *Filter) Allows(filename string) bool {
 		return true
 	}
 
-	cleanedName := filepath.Clean(filename)
+	cleanedName := f.clean(filename)
 
 	if len(f.include) > 0 {
-		matched := false
-		for _, inc := range f.include {
-			matched = inc.Match(cleanedName)
-			if matched {
-				break
-			}
+		if included, _ := matchInOrder(f.include, cleanedName); !included {
+			return false
 		}
-		if !matched {
+	}
+
+	if len(f.exclude) > 0 {
+		if excluded, _ := matchInOrder(f.exclude, cleanedName); excluded {
 			return false
 		}
 	}
 
+	return true
+}
+
+// Explain returns the same decision as Allows, along with the pattern that
+// made it and the reason it applies. The pattern is blank if no pattern
+// matched filename.
+func (f *Filter) Explain(filename string) (allowed bool, matchedPattern string, reason string) {
+	if f == nil || len(f.include)+len(f.exclude) == 0 {
+		return true, "", "no include or exclude patterns"
+	}
+
+	cleanedName := f.clean(filename)
+
+	var includedBy Pattern
+	if len(f.include) > 0 {
+		included, p := matchInOrder(f.include, cleanedName)
+		if !included {
+			if p != nil {
+				return false, patternString(p), "removed by include pattern"
+			}
+			return false, "", "not matched by any include pattern"
+		}
+		includedBy = p
+	}
+
 	if len(f.exclude) > 0 {
-		for _, ex := range f.exclude {
-			if ex.Match(cleanedName) {
-				return false
+		excluded, p := matchInOrder(f.exclude, cleanedName)
+		if excluded {
+			if p != nil {
+				return false, patternString(p), "matched exclude pattern"
 			}
+			return false, "", "not kept by any exclude pattern"
+		}
+		if p != nil {
+			return true, patternString(p), "kept by exclude pattern"
 		}
 	}
 
-	return true
+	if includedBy != nil {
+		return true, patternString(includedBy), "matched include pattern"
+	}
+	if len(f.include) > 0 {
+		return true, "", "not removed by any include pattern"
+	}
+	return true, "", "not matched by any exclude pattern"
+}
+
+func (f *Filter) clean(filename string) string {
+	cleanedName := filepath.Clean(filename)
+	if f.caseFold {
+		return strings.ToLower(cleanedName)
+	}
+	return cleanedName
+}
+
+// matchInOrder reports whether name is matched by patterns, applied in order
+// like a gitignore file: the last pattern to match decides, and a negated
+// pattern un-matches a name an earlier pattern matched. A list which starts
+// with a negated pattern matches everything it doesn't negate. The deciding
+// pattern is returned too, or nil if none matched.
+func matchInOrder(patterns []Pattern, name string) (bool, Pattern) {
+	_, matched := patterns[0].(*negatedPattern)
+	var decidedBy Pattern
+	for _, p := range patterns {
+		if neg, ok := p.(*negatedPattern); ok {
+			if matched && neg.Match(name) {
+				matched = false
+				decidedBy = p
+			}
+		} else if !matched && p.Match(name) {
+			matched = true
+			decidedBy = p
+		}
+	}
+	return matched, decidedBy
+}
+
+// patternString returns the pattern p was created from, if it is known.
+func patternString(p Pattern) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%v", p)
 }
 
 func NewPattern(rawpattern string) Pattern {
+	if strings.HasPrefix(rawpattern, "!") {
+		return &negatedPattern{NewPattern(rawpattern[1:])}
+	}
+
 	cleanpattern := filepath.Clean(rawpattern)
 
 	// Special case local dir, matches all (inc subpaths)
@@ -103,10 +189,22 @@ func convertToPatterns(rawpatterns []string) []Pattern {
 	return patterns
 }
 
+func toLower(rawpatterns []string) []string {
+	lowered := make([]string, len(rawpatterns))
+	for i, raw := range rawpatterns {
+		lowered[i] = strings.ToLower(raw)
+	}
+	return lowered
+}
+
 type basicPattern struct {
 	rawPattern string
 }
 
+func (p *basicPattern) String() string {
+	return p.rawPattern
+}
+
 // Match is a revised version of filepath.Match which makes it behave more
 // like gitignore
 func (p *basicPattern) Match(name string) bool {
@@ -120,6 +218,10 @@ type pathlessWildcardPattern struct {
 	wildcardRE *regexp.Regexp
 }
 
+func (p *pathlessWildcardPattern) String() string {
+	return p.rawPattern
+}
+
 // Match is a revised version of filepath.Match which makes it behave more
 // like gitignore
 func (p *pathlessWildcardPattern) Match(name string) bool {
@@ -133,6 +235,10 @@ type doubleWildcardPattern struct {
 	wildcardRE *regexp.Regexp
 }
 
+func (p *doubleWildcardPattern) String() string {
+	return p.rawPattern
+}
+
 // Match is a revised version of filepath.Match which makes it behave more
 // like gitignore
 func (p *doubleWildcardPattern) Match(name string) bool {
@@ -141,6 +247,16 @@ func (p *doubleWildcardPattern) Match(name string) bool {
 	return matched || p.wildcardRE.MatchString(name)
 }
 
+// negatedPattern matches the same names as the Pattern it wraps. Filters treat
+// it as removing the names it matches rather than adding them.
+type negatedPattern struct {
+	Pattern
+}
+
+func (p *negatedPattern) String() string {
+	return "!" + patternString(p.Pattern)
+}
+
 type noOpMatcher struct {
 }
 
@@ -148,6 +264,10 @@ func (n noOpMatcher) Match(name string) bool {
 	return true
 }
 
+func (n noOpMatcher) String() string {
+	return "."
+}
+
 var localDirSet = map[string]struct{}{
 	".":   struct{}{},
 	"./":  struct{}{},
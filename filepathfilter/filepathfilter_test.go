@@ -45,6 +45,43 @@ func TestPatternMatch(t *testing.T) {
 	assert.True(t, patternMatch(".\\", "path.txt"))
 }
 
+func TestPatternMatchCaseInsensitive(t *testing.T) {
+	assert.True(t, patternMatchCaseInsensitive("filename.txt", "FileName.TXT"))
+	assert.True(t, patternMatchCaseInsensitive("*.TXT", "filename.txt"))
+	assert.False(t, patternMatchCaseInsensitive("*.TX", "filename.txt"))
+	assert.True(t, patternMatchCaseInsensitive("F*.txt", "filename.Txt"))
+	assert.False(t, patternMatchCaseInsensitive("G*.txt", "filename.txt"))
+	assert.True(t, patternMatchCaseInsensitive("FILE*", "filename.txt"))
+	assert.False(t, patternMatchCaseInsensitive("FILE", "filename.txt"))
+
+	// With no path separators, should match in subfolders
+	assert.True(t, patternMatchCaseInsensitive("*.txt", "Sub/FileName.TXT"))
+	assert.False(t, patternMatchCaseInsensitive("*.tx", "Sub/FileName.TXT"))
+	assert.True(t, patternMatchCaseInsensitive("f*.txt", "SUB/FILENAME.TXT"))
+	assert.False(t, patternMatchCaseInsensitive("g*.txt", "SUB/FILENAME.TXT"))
+	// Needs wildcard for exact filename
+	assert.True(t, patternMatchCaseInsensitive("**/FileName.txt", "sub/Sub/SUB/filename.TXT"))
+
+	// Path specific
+	assert.True(t, patternMatchCaseInsensitive("SUB", "sub/filename.txt"))
+	assert.False(t, patternMatchCaseInsensitive("SUB", "subfilename.txt"))
+
+	// Absolute
+	assert.True(t, patternMatchCaseInsensitive("*.DAT", "/Path/To/Sub/.git/test.dat"))
+	assert.True(t, patternMatchCaseInsensitive("**/.GIT", "/path/to/sub/.git"))
+
+	// Match anything
+	assert.True(t, patternMatchCaseInsensitive(".", "Path.txt"))
+
+	// Case sensitive by default
+	assert.False(t, patternMatch("*.JPG", "photo.jpg"))
+	assert.True(t, patternMatchCaseInsensitive("*.JPG", "photo.jpg"))
+}
+
+func patternMatchCaseInsensitive(pattern, filename string) bool {
+	return NewCaseInsensitive([]string{pattern}, nil).Allows(filename)
+}
+
 func patternMatch(pattern, filename string) bool {
 	return NewPattern(pattern).Match(filepath.Clean(filename))
 }
@@ -105,11 +142,26 @@ func TestFilterAllows(t *testing.T) {
 		filterTest{true, []string{"test/*"}, []string{"test/notfile*"}},
 		filterTest{false, []string{"test/*"}, []string{"test/file*"}},
 		filterTest{false, []string{"another/*", "test/*"}, []string{"test/notfilename.dat", "test/filename.dat"}},
+
+		// Negation, applied in order
+		filterTest{false, []string{"test", "!*.dat"}, nil},
+		filterTest{true, []string{"test", "!*.dat", "test/file*"}, nil},
+		filterTest{false, []string{"!*.dat"}, nil},
+		filterTest{true, []string{"!*.bin"}, nil},
+		filterTest{true, nil, []string{"test", "!test/filename.dat"}},
+		filterTest{false, nil, []string{"test", "!test/filename.dat", "*.dat"}},
+		filterTest{false, nil, []string{"!test/filename.dat", "test"}},
+		filterTest{false, nil, []string{"!*.bin"}},
+		filterTest{true, nil, []string{"!*.dat"}},
+		filterTest{false, []string{"test", "!test/notfilename.dat"}, []string{"*.dat"}},
+		filterTest{true, []string{"test", "!test/notfilename.dat"}, []string{"*.dat", "!test/file*"}},
 	}
 
 	for _, c := range cases {
 		result := New(c.includes, c.excludes).Allows("test/filename.dat")
 		assert.Equal(t, c.expectedResult, result, "includes: %v excludes: %v", c.includes, c.excludes)
+		explained, _, _ := New(c.includes, c.excludes).Explain("test/filename.dat")
+		assert.Equal(t, result, explained, "explain includes: %v excludes: %v", c.includes, c.excludes)
 		if runtime.GOOS == "windows" {
 			// also test with \ path separators, tolerate mixed separators
 			for i, inc := range c.includes {
@@ -122,3 +174,40 @@ func TestFilterAllows(t *testing.T) {
 		}
 	}
 }
+
+type explainTest struct {
+	includes []string
+	excludes []string
+	allowed  bool
+	pattern  string
+	reason   string
+}
+
+func TestFilterExplain(t *testing.T) {
+	cases := []explainTest{
+		explainTest{nil, nil, true, "", "no include or exclude patterns"},
+		// Inclusion
+		explainTest{[]string{"*.bin", "test"}, nil, true, "test", "matched include pattern"},
+		explainTest{[]string{"*.bin", "other"}, nil, false, "", "not matched by any include pattern"},
+		explainTest{[]string{"test", "!*.dat"}, nil, false, "!*.dat", "removed by include pattern"},
+		explainTest{[]string{"!*.bin"}, nil, true, "", "not removed by any include pattern"},
+		// Exclusion
+		explainTest{nil, []string{"other", "test/file*"}, false, "test/file*", "matched exclude pattern"},
+		explainTest{nil, []string{"other", "*.bin"}, true, "", "not matched by any exclude pattern"},
+		explainTest{nil, []string{"test", "!*.dat"}, true, "!*.dat", "kept by exclude pattern"},
+		explainTest{nil, []string{"!*.bin"}, false, "", "not kept by any exclude pattern"},
+		// Both
+		explainTest{[]string{"test"}, []string{"*.bin"}, true, "test", "matched include pattern"},
+		explainTest{[]string{"test"}, []string{"*.dat"}, false, "*.dat", "matched exclude pattern"},
+		explainTest{[]string{"other"}, []string{"*.dat"}, false, "", "not matched by any include pattern"},
+	}
+
+	for _, c := range cases {
+		f := New(c.includes, c.excludes)
+		allowed, pattern, reason := f.Explain("test/filename.dat")
+		assert.Equal(t, c.allowed, allowed, "includes: %v excludes: %v", c.includes, c.excludes)
+		assert.Equal(t, f.Allows("test/filename.dat"), allowed, "includes: %v excludes: %v", c.includes, c.excludes)
+		assert.Equal(t, filepath.FromSlash(c.pattern), pattern, "includes: %v excludes: %v", c.includes, c.excludes)
+		assert.Equal(t, c.reason, reason, "includes: %v excludes: %v", c.includes, c.excludes)
+	}
+}
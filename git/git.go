@@ -228,6 +228,54 @@ func LocalRefs() ([]*Ref, error) {
 	return refs, cmd.Wait()
 }
 
+// LocalTags returns all of the local tags in the current repository which
+// point to a commit. The Sha of each Ref is that of the commit, so annotated
+// tags are resolved through the tag object.
+func LocalTags() ([]*Ref, error) {
+	cmd := subprocess.ExecCommand("git", "for-each-ref",
+		`--format=%(refname) %(objecttype) %(objectname) %(*objecttype) %(*objectname)`,
+		"refs/tags")
+
+	outp, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git for-each-ref: %v", err)
+	}
+
+	var refs []*Ref
+
+	if err := cmd.Start(); err != nil {
+		return refs, err
+	}
+
+	// Output is like this, the last 2 fields are only present for annotated tags:
+	// refs/tags/v1 commit f03686b324b29ff480591745dbfbbfa5e5ac1bd5
+	// refs/tags/v2 tag 7d1cc6b5e5cb3b0d8a6d0ba0e5b8ea0ec17ee4f2 commit ad3b29b773e46ad6870fdf08796c33d97190fe93
+	scanner := bufio.NewScanner(outp)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		parts := strings.Fields(line)
+		if len(parts) != 3 && len(parts) != 5 {
+			tracerx.Printf("Invalid line from git for-each-ref: %q", line)
+			continue
+		}
+
+		objtype, sha := parts[1], parts[2]
+		if len(parts) == 5 {
+			objtype, sha = parts[3], parts[4]
+		}
+
+		if objtype != "commit" {
+			tracerx.Printf("Skipping tag %q, which does not point to a commit", parts[0])
+			continue
+		}
+
+		_, name := ParseRefToTypeAndName(parts[0])
+		refs = append(refs, &Ref{name, RefTypeLocalTag, sha})
+	}
+
+	return refs, cmd.Wait()
+}
+
 // ValidateRemote checks that a named remote is valid for use
 // Mainly to check user-supplied remotes & fail more nicely
 func ValidateRemote(remote string) error {
@@ -605,6 +653,12 @@ func GitAndRootDirs() (string, string, error) {
 	return absGitDir, absRootDir, nil
 }
 
+// StagedContent returns the content of the file at path, relative to the root
+// of the repository, as it is staged in the index.
+func StagedContent(path string) (string, error) {
+	return subprocess.SimpleExec("git", "cat-file", "blob", ":"+path)
+}
+
 func RootDir() (string, error) {
 	cmd := subprocess.ExecCommand("git", "rev-parse", "--show-toplevel")
 	out, err := cmd.Output()
@@ -633,6 +687,30 @@ func GitDir() (string, error) {
 	return "", nil
 }
 
+// StashCommits returns the commits of every entry in the stash, followed by
+// their parents, which hold the index and untracked files of each entry and the
+// commit it was made on. It returns no commits if nothing has been stashed.
+func StashCommits() ([]string, error) {
+	if _, err := subprocess.SimpleExec("git", "rev-parse", "--verify", "--quiet", "refs/stash"); err != nil {
+		return nil, nil
+	}
+
+	outp, err := subprocess.SimpleExec("git", "log", "--walk-reflogs", "--format=%H %P", "refs/stash")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list stash entries: %v", err)
+	}
+
+	var commits []string
+	seen := make(map[string]bool)
+	for _, sha := range strings.Fields(outp) {
+		if !seen[sha] {
+			seen[sha] = true
+			commits = append(commits, sha)
+		}
+	}
+	return commits, nil
+}
+
 // GetAllWorkTreeHEADs returns the refs that all worktrees are using as HEADs
 // This returns all worktrees plus the master working copy, and works even if
 // working dir is actually in a worktree right now
@@ -466,3 +466,45 @@ func TestLocalRefs(t *testing.T) {
 		t.Errorf("Unexpected local refs: %v", actual)
 	}
 }
+
+func TestLocalTags(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	outputs := repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+			},
+			Tags: []string{"v1"},
+		},
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 30},
+			},
+		},
+	})
+
+	test.RunGitCommand(t, true, "tag", "lightweight")
+	test.RunGitCommand(t, true, "tag", "-a", "-m", "tree", "tree", "HEAD^{tree}")
+
+	refs, err := LocalTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make(map[string]string)
+	for _, r := range refs {
+		assert.Equal(t, RefTypeLocalTag, r.Type)
+		actual[r.Name] = r.Sha
+	}
+
+	assert.Equal(t, map[string]string{
+		"v1":          outputs[0].Sha,
+		"lightweight": outputs[1].Sha,
+	}, actual)
+}
@@ -10,6 +10,10 @@ type pktlineReader struct {
 	pl *pktline
 
 	buf []byte
+	// eof is whether the flush packet ending the data has been read, after
+	// which nothing more is read, since the following packets belong to
+	// something else.
+	eof bool
 }
 
 var _ io.Reader = new(pktlineReader)
@@ -30,6 +34,10 @@ func (r *pktlineReader) Read(p []byte) (int, error) {
 	// have either, a) overfilled the given buffer "p", or we have started
 	// to internally buffer in "r.buf".
 	for len(r.buf) == 0 {
+		if r.eof {
+			return n, io.EOF
+		}
+
 		chunk, err := r.pl.readPacket()
 		if err != nil {
 			return n, err
@@ -39,6 +47,7 @@ func (r *pktlineReader) Read(p []byte) (int, error) {
 			// If we got an empty chunk, then we know that we have
 			// reached the end of processing for this particular
 			// packet, so let's terminate.
+			r.eof = true
 
 			return n, io.EOF
 		}
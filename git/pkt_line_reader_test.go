@@ -141,3 +141,20 @@ func TestPktlineReaderReadsManyPacketsInMultipleCallsWithEvenBuffering(t *testin
 	assert.Equal(t, 0, n3)
 	assert.Equal(t, io.EOF, e3)
 }
+
+func TestPktlineReaderStopsAtFlushPacket(t *testing.T) {
+	var buf bytes.Buffer
+
+	writePacket(t, &buf, []byte("first"))
+	writePacket(t, &buf, []byte("second"))
+
+	pr := &pktlineReader{pl: newPktline(&buf, nil)}
+
+	data, err := ioutil.ReadAll(pr)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("first"), data)
+
+	n, err := pr.Read(make([]byte, 10))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+}
@@ -6,7 +6,6 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/tls"
-	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -14,6 +13,7 @@ import (
 	"net/http/httputil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -57,9 +57,15 @@ func LogTransfer(cfg *config.Configuration, key string, res *http.Response) {
 type HttpClient struct {
 	Config *config.Configuration
 	*http.Client
+
+	// MaxRedirects is the redirect at which a request is stopped, from
+	// lfs.transfer.maxredirects.
+	MaxRedirects int
 }
 
 func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
+	setTraceId(c.Config, req)
+	setUserAgent(c.Config, req)
 	traceHttpRequest(c.Config, req)
 
 	crc := countingRequest(c.Config, req)
@@ -106,7 +112,9 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 	return res, err
 }
 
-// NewHttpClient returns a new HttpClient for the given host (which may be "host:port")
+// NewHttpClient returns a new HttpClient for the given host (which may be "host:port").
+// Clients are shared per host, except when the configuration has its own Dial,
+// which a shared client would ignore.
 func NewHttpClient(c *config.Configuration, host string) *HttpClient {
 	httpClientsMutex.Lock()
 	defer httpClientsMutex.Unlock()
@@ -114,22 +122,42 @@ func NewHttpClient(c *config.Configuration, host string) *HttpClient {
 	if httpClients == nil {
 		httpClients = make(map[string]*HttpClient)
 	}
-	if client, ok := httpClients[host]; ok {
+	if client, ok := httpClients[host]; ok && c.Dial == nil {
 		return client
 	}
 
 	dialtime := c.Git.Int("lfs.dialtimeout", 30)
 	keepalivetime := c.Git.Int("lfs.keepalive", 1800) // 30 minutes
 	tlstime := c.Git.Int("lfs.tlstimeout", 30)
+	idletime := c.Git.Int("lfs.transfer.idletimeout", 0)
+
+	// Keep enough idle connections to a host for every transfer to reuse
+	// one, but leave an explicit total limit alone.
+	maxidle := c.Git.Int("lfs.transfer.maxidleconns", 0)
+	maxidleperhost := c.ConcurrentTransfers()
+	if maxidle > maxidleperhost {
+		maxidleperhost = maxidle
+	} else if maxidle > 0 && maxidle < maxidleperhost {
+		tracerx.Printf("http: lfs.transfer.maxidleconns=%d is below lfs.concurrenttransfers=%d, some connections will not be reused", maxidle, maxidleperhost)
+	}
 
-	tr := &http.Transport{
-		Proxy: ProxyFromGitConfigOrEnvironment(c),
-		Dial: (&net.Dialer{
+	dial := c.Dial
+	if dial == nil {
+		dial = (&net.Dialer{
 			Timeout:   time.Duration(dialtime) * time.Second,
 			KeepAlive: time.Duration(keepalivetime) * time.Second,
-		}).Dial,
+		}).Dial
+	}
+
+	tr := &http.Transport{
+		Proxy:               ProxyFromGitConfigOrEnvironment(c),
+		Dial:                dial,
 		TLSHandshakeTimeout: time.Duration(tlstime) * time.Second,
-		MaxIdleConnsPerHost: c.ConcurrentTransfers(),
+		MaxIdleConns:        maxidle,
+		MaxIdleConnsPerHost: maxidleperhost,
+	}
+	if idletime > 0 {
+		tr.IdleConnTimeout = time.Duration(idletime) * time.Second
 	}
 
 	tr.TLSClientConfig = &tls.Config{}
@@ -140,17 +168,43 @@ func NewHttpClient(c *config.Configuration, host string) *HttpClient {
 	}
 
 	client := &HttpClient{
-		Config: c,
-		Client: &http.Client{Transport: tr, CheckRedirect: CheckRedirect},
+		Config:       c,
+		MaxRedirects: maxRedirects(c),
+	}
+	client.Client = &http.Client{Transport: tr, CheckRedirect: client.CheckRedirect}
+	if c.Dial == nil {
+		httpClients[host] = client
 	}
-	httpClients[host] = client
 
 	return client
 }
 
+const defaultMaxRedirects = 3
+
+// maxRedirects returns lfs.transfer.maxredirects, or defaultMaxRedirects if it
+// is unset or invalid.
+func maxRedirects(c *config.Configuration) int {
+	if n := c.Git.Int("lfs.transfer.maxredirects", defaultMaxRedirects); n > 0 {
+		return n
+	}
+	return defaultMaxRedirects
+}
+
+// CheckRedirect prepares req to follow a redirect, stopping at the
+// c.MaxRedirects'th.
+func (c *HttpClient) CheckRedirect(req *http.Request, via []*http.Request) error {
+	return checkRedirect(req, via, c.MaxRedirects)
+}
+
+// CheckRedirect prepares req to follow a redirect, stopping at the
+// defaultMaxRedirects'th.
 func CheckRedirect(req *http.Request, via []*http.Request) error {
-	if len(via) >= 3 {
-		return errors.New("stopped after 3 redirects")
+	return checkRedirect(req, via, defaultMaxRedirects)
+}
+
+func checkRedirect(req *http.Request, via []*http.Request, max int) error {
+	if len(via) >= max {
+		return fmt.Errorf("stopped after %d redirects", max)
 	}
 
 	oldest := via[0]
@@ -235,6 +289,19 @@ func isTraceableContent(h http.Header) bool {
 	return false
 }
 
+// traceBodyOutput is where traced bodies are echoed. It is a variable so that
+// tests can capture it.
+var traceBodyOutput io.Writer = os.Stderr
+
+// traceBodyLimit returns the number of bytes of each body to trace, from
+// GIT_LFS_HTTP_TRACE_MAX_BYTES. Zero means there is no limit.
+func traceBodyLimit(cfg *config.Configuration) int {
+	if n := cfg.Os.Int("GIT_LFS_HTTP_TRACE_MAX_BYTES", 0); n > 0 {
+		return n
+	}
+	return 0
+}
+
 func countingRequest(cfg *config.Configuration, req *http.Request) *CountingReadCloser {
 	return &CountingReadCloser{
 		request:         req,
@@ -242,6 +309,7 @@ func countingRequest(cfg *config.Configuration, req *http.Request) *CountingRead
 		ReadCloser:      req.Body,
 		isTraceableType: isTraceableContent(req.Header),
 		useGitTrace:     false,
+		traceLimit:      traceBodyLimit(cfg),
 	}
 }
 
@@ -252,6 +320,7 @@ func countingResponse(cfg *config.Configuration, res *http.Response) *CountingRe
 		ReadCloser:      res.Body,
 		isTraceableType: isTraceableContent(res.Header),
 		useGitTrace:     true,
+		traceLimit:      traceBodyLimit(cfg),
 	}
 }
 
@@ -262,9 +331,30 @@ type CountingReadCloser struct {
 	cfg             *config.Configuration
 	isTraceableType bool
 	useGitTrace     bool
+	// traceLimit is the number of bytes of the body to trace, or zero to
+	// trace all of it
+	traceLimit int
 	io.ReadCloser
 }
 
+// traceChunk returns the part of b, the last n bytes read, to trace. Once
+// traceLimit bytes have been traced, the rest of the body is replaced by a
+// marker.
+func (c *CountingReadCloser) traceChunk(b []byte) string {
+	if c.traceLimit <= 0 {
+		return string(b)
+	}
+
+	remaining := c.traceLimit - (c.Count - len(b))
+	if remaining <= 0 {
+		return ""
+	}
+	if len(b) <= remaining {
+		return string(b)
+	}
+	return string(b[0:remaining]) + "... (truncated)"
+}
+
 func (c *CountingReadCloser) Read(b []byte) (int, error) {
 	n, err := c.ReadCloser.Read(b)
 	if err != nil && err != io.EOF {
@@ -274,13 +364,14 @@ func (c *CountingReadCloser) Read(b []byte) (int, error) {
 	c.Count += n
 
 	if n > 0 && c.isTraceableType {
-		chunk := string(b[0:n])
-		if c.useGitTrace {
-			tracerx.Printf("HTTP: %s", chunk)
-		}
+		if chunk := c.traceChunk(b[0:n]); len(chunk) > 0 {
+			if c.useGitTrace {
+				tracerx.Printf("HTTP: %s", chunk)
+			}
 
-		if c.cfg.IsTracingHttp {
-			fmt.Fprint(os.Stderr, chunk)
+			if c.cfg.IsTracingHttp {
+				fmt.Fprint(traceBodyOutput, chunk)
+			}
 		}
 	}
 
@@ -313,24 +404,63 @@ func LogHttpStats(cfg *config.Configuration) {
 		return
 	}
 
-	fmt.Fprintf(file, "concurrent=%d batch=%v time=%d version=%s\n", cfg.ConcurrentTransfers(), cfg.BatchTransfer(), time.Now().Unix(), config.Version)
+	writeHttpStats(file, cfg)
+
+	fmt.Fprintf(os.Stderr, "HTTP Stats logged to file %s\n", file.Name())
+}
+
+// writeHttpStats writes a line for each logged transfer, followed by a line of
+// totals for each host.
+func writeHttpStats(w io.Writer, cfg *config.Configuration) {
+	fmt.Fprintf(w, "concurrent=%d batch=%v time=%d version=%s\n", cfg.ConcurrentTransfers(), cfg.BatchTransfer(), time.Now().Unix(), config.Version)
 
+	hosts := make(map[string]*hostStats)
 	for key, responses := range httpTransferBuckets {
 		for _, response := range responses {
 			stats := httpTransfers[response]
-			fmt.Fprintf(file, "key=%s reqheader=%d reqbody=%d resheader=%d resbody=%d restime=%d status=%d url=%s\n",
+			restime := stats.responseStats.Stop.Sub(stats.responseStats.Start)
+			fmt.Fprintf(w, "key=%s reqheader=%d reqbody=%d resheader=%d resbody=%d restime=%d status=%d url=%s\n",
 				key,
 				stats.requestStats.HeaderSize,
 				stats.requestStats.BodySize,
 				stats.responseStats.HeaderSize,
 				stats.responseStats.BodySize,
-				stats.responseStats.Stop.Sub(stats.responseStats.Start).Nanoseconds(),
+				restime.Nanoseconds(),
 				response.StatusCode,
 				response.Request.URL)
+
+			host := response.Request.URL.Host
+			h, ok := hosts[host]
+			if !ok {
+				h = &hostStats{}
+				hosts[host] = h
+			}
+			h.Transfers++
+			h.ReqBytes += stats.requestStats.HeaderSize + stats.requestStats.BodySize
+			h.ResBytes += stats.responseStats.HeaderSize + stats.responseStats.BodySize
+			h.Time += restime
 		}
 	}
 
-	fmt.Fprintf(os.Stderr, "HTTP Stats logged to file %s\n", file.Name())
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	for _, host := range names {
+		h := hosts[host]
+		fmt.Fprintf(w, "host=%s transfers=%d reqbytes=%d resbytes=%d meantime=%d\n",
+			host, h.Transfers, h.ReqBytes, h.ResBytes, (h.Time / time.Duration(h.Transfers)).Nanoseconds())
+	}
+}
+
+// hostStats totals the transfers logged to one host.
+type hostStats struct {
+	Transfers int
+	ReqBytes  int
+	ResBytes  int
+	Time      time.Duration
 }
 
 func statsLogFile() (*os.File, error) {
@@ -0,0 +1,279 @@
+package httputil
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func transportFor(t *testing.T, host string, gitconf map[string]string) *http.Transport {
+	cfg := config.NewFrom(config.Values{Git: gitconf})
+	client := NewHttpClient(cfg, host)
+
+	tr, ok := client.Client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("unexpected transport type %T", client.Client.Transport)
+	}
+	return tr
+}
+
+func TestHttpClientIdleConnsDefault(t *testing.T) {
+	tr := transportFor(t, "idle-default.example.com", nil)
+
+	assert.Equal(t, 0, tr.MaxIdleConns)
+	assert.Equal(t, 3, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Duration(0), tr.IdleConnTimeout)
+}
+
+func TestHttpClientIdleConnsFollowConcurrentTransfers(t *testing.T) {
+	tr := transportFor(t, "idle-concurrent.example.com", map[string]string{
+		"lfs.concurrenttransfers": "8",
+	})
+
+	assert.Equal(t, 0, tr.MaxIdleConns)
+	assert.Equal(t, 8, tr.MaxIdleConnsPerHost)
+}
+
+func TestHttpClientMaxIdleConns(t *testing.T) {
+	tr := transportFor(t, "idle-max.example.com", map[string]string{
+		"lfs.concurrenttransfers":   "4",
+		"lfs.transfer.maxidleconns": "10",
+		"lfs.transfer.idletimeout":  "90",
+	})
+
+	assert.Equal(t, 10, tr.MaxIdleConns)
+	assert.Equal(t, 10, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 90*time.Second, tr.IdleConnTimeout)
+}
+
+func TestHttpClientMaxIdleConnsBelowConcurrentTransfers(t *testing.T) {
+	tr := transportFor(t, "idle-low.example.com", map[string]string{
+		"lfs.concurrenttransfers":   "4",
+		"lfs.transfer.maxidleconns": "2",
+	})
+
+	assert.Equal(t, 2, tr.MaxIdleConns)
+	assert.Equal(t, 4, tr.MaxIdleConnsPerHost)
+}
+
+// redirectServer redirects /<n> to /<n-1>, and answers /0.
+func redirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/%d", n-1), http.StatusFound)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+}
+
+func TestHttpClientMaxRedirects(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := NewHttpClient(config.NewFrom(config.Values{
+		Git: map[string]string{"lfs.transfer.maxredirects": "5"},
+	}), u.Host)
+	assert.Equal(t, 5, client.MaxRedirects)
+
+	res, err := client.Get(server.URL + "/4")
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.Equal(t, 200, res.StatusCode)
+	}
+
+	_, err = client.Get(server.URL + "/5")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "stopped after 5 redirects")
+	}
+}
+
+func TestHttpClientMaxRedirectsDefault(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := NewHttpClient(config.NewFrom(config.Values{
+		Git: map[string]string{"lfs.transfer.maxredirects": "-1"},
+	}), u.Host)
+	assert.Equal(t, 3, client.MaxRedirects)
+
+	res, err := client.Get(server.URL + "/2")
+	if assert.Nil(t, err) {
+		res.Body.Close()
+	}
+
+	_, err = client.Get(server.URL + "/3")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "stopped after 3 redirects")
+	}
+}
+
+func TestCountingReadCloserTruncatesTracedBody(t *testing.T) {
+	oldOutput := traceBodyOutput
+	defer func() { traceBodyOutput = oldOutput }()
+	var traced bytes.Buffer
+	traceBodyOutput = &traced
+
+	cfg := config.NewFrom(config.Values{
+		Os: map[string]string{"GIT_LFS_HTTP_TRACE_MAX_BYTES": "10"},
+	})
+	cfg.IsTracingHttp = true
+
+	body := strings.Repeat("0123456789", 1000)
+	res := &http.Response{
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   ioutil.NopCloser(iotest.OneByteReader(strings.NewReader(body))),
+	}
+
+	c := countingResponse(cfg, res)
+	by, err := ioutil.ReadAll(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, body, string(by))
+	assert.Equal(t, len(body), c.Count)
+	assert.Equal(t, "0123456789", traced.String())
+
+	// A read straddling the limit is cut short
+	traced.Reset()
+	res.Body = ioutil.NopCloser(strings.NewReader(body))
+	c = countingResponse(cfg, res)
+	by, err = ioutil.ReadAll(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, body, string(by))
+	assert.Equal(t, len(body), c.Count)
+	assert.Equal(t, "0123456789... (truncated)", traced.String())
+}
+
+func TestCountingReadCloserTracesWholeBodyByDefault(t *testing.T) {
+	oldOutput := traceBodyOutput
+	defer func() { traceBodyOutput = oldOutput }()
+	var traced bytes.Buffer
+	traceBodyOutput = &traced
+
+	cfg := config.NewFrom(config.Values{})
+	cfg.IsTracingHttp = true
+
+	body := strings.Repeat("0123456789", 1000)
+	c := countingResponse(cfg, &http.Response{
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   ioutil.NopCloser(strings.NewReader(body)),
+	})
+	_, err := ioutil.ReadAll(c)
+
+	assert.Nil(t, err)
+	assert.Equal(t, body, traced.String())
+}
+
+func TestWriteHttpStatsTotalsPerHost(t *testing.T) {
+	oldTransfers, oldBuckets := httpTransfers, httpTransferBuckets
+	defer func() {
+		httpTransfers, httpTransferBuckets = oldTransfers, oldBuckets
+	}()
+	httpTransfers = make(map[*http.Response]*httpTransfer)
+	httpTransferBuckets = make(map[string][]*http.Response)
+
+	start := time.Now()
+	logResponse := func(key, rawurl string, size int, elapsed time.Duration) {
+		req, err := http.NewRequest("GET", rawurl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := &http.Response{StatusCode: 200, Request: req}
+		httpTransfers[res] = &httpTransfer{
+			requestStats:  &httpTransferStats{HeaderSize: 10, BodySize: size},
+			responseStats: &httpTransferStats{HeaderSize: 20, BodySize: 2 * size, Start: start, Stop: start.Add(elapsed)},
+		}
+		httpTransferBuckets[key] = append(httpTransferBuckets[key], res)
+	}
+
+	logResponse("lfs.batch", "https://a.example.com/objects/batch", 100, 2*time.Millisecond)
+	logResponse("lfs.data.download", "https://a.example.com/objects/1", 5, 4*time.Millisecond)
+	logResponse("lfs.data.download", "https://b.example.com/objects/2", 50, 6*time.Millisecond)
+
+	var buf bytes.Buffer
+	writeHttpStats(&buf, config.NewFrom(config.Values{}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 6) {
+		assert.Equal(t, "host=a.example.com transfers=2 reqbytes=125 resbytes=250 meantime=3000000", lines[4])
+		assert.Equal(t, "host=b.example.com transfers=1 reqbytes=60 resbytes=120 meantime=6000000", lines[5])
+	}
+}
+
+func TestNewHttpClientUsesConfiguredDial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	// a client already shared for the host must not stand in for the Dial
+	httpClientsMutex.Lock()
+	httpClients = nil
+	httpClientsMutex.Unlock()
+	NewHttpClient(config.NewFrom(config.Values{}), "dial.example.com")
+
+	var dialed []string
+	cfg := config.NewFrom(config.Values{})
+	cfg.Dial = func(network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return net.Dial(network, srv.Listener.Addr().String())
+	}
+
+	client := NewHttpClient(cfg, "dial.example.com")
+	res, err := client.Get("http://dial.example.com/")
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.Equal(t, 200, res.StatusCode)
+	}
+	assert.Equal(t, []string{"dial.example.com:80"}, dialed)
+}
+
+func TestHttpClientAppendsUserAgentSuffix(t *testing.T) {
+	var agents []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+	})
+
+	for _, cfg := range []*config.Configuration{
+		config.NewFrom(config.Values{}),
+		config.NewFrom(config.Values{
+			Git: map[string]string{"lfs.useragentsuffix": "acme-proxy/1.0"},
+		}),
+	} {
+		// clients are kept per host, so use a new server for each config
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		req, err := NewHttpRequest("GET", server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := NewHttpClient(cfg, req.Host).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	if assert.Len(t, agents, 2) {
+		assert.Equal(t, UserAgent, agents[0])
+		assert.Equal(t, UserAgent+" acme-proxy/1.0", agents[1])
+	}
+}
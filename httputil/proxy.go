@@ -40,13 +40,15 @@ func ProxyFromGitConfigOrEnvironment(c *config.Configuration) func(req *http.Req
 	}
 
 	return func(req *http.Request) (*url.URL, error) {
-		var proxy string
-		if req.URL.Scheme == "https" {
-			proxy = https_proxy
-		}
+		proxy, ok := proxyFromGitConfigForURL(c, req.URL)
+		if !ok {
+			if req.URL.Scheme == "https" {
+				proxy = https_proxy
+			}
 
-		if len(proxy) == 0 {
-			proxy = http_proxy
+			if len(proxy) == 0 {
+				proxy = http_proxy
+			}
 		}
 
 		if len(proxy) == 0 {
@@ -73,6 +75,45 @@ func ProxyFromGitConfigOrEnvironment(c *config.Configuration) func(req *http.Req
 	}
 }
 
+// proxyFromGitConfigForURL returns the value of the most specific
+// http.<url>.proxy setting matching u, the way Git matches per-URL config: the
+// scheme, host and port must be equal, and the path must match on whole
+// segments. An empty value disables the proxy for matching URLs.
+func proxyFromGitConfigForURL(c *config.Configuration, u *url.URL) (string, bool) {
+	hosts := []string{u.Host}
+	if !hasPort(u.Host) {
+		hosts = append(hosts, u.Host+":"+portMap[u.Scheme])
+	} else if port, ok := portMap[u.Scheme]; ok && strings.HasSuffix(u.Host, ":"+port) {
+		hosts = append(hosts, strings.TrimSuffix(u.Host, ":"+port))
+	}
+
+	path := strings.Trim(u.Path, "/")
+	for {
+		for _, host := range hosts {
+			prefix := fmt.Sprintf("%s://%s", u.Scheme, host)
+			if len(path) > 0 {
+				prefix += "/" + path
+			}
+
+			for _, key := range []string{prefix + "/", prefix} {
+				if proxy, ok := c.Git.Get(fmt.Sprintf("http.%s.proxy", key)); ok {
+					return proxy, true
+				}
+			}
+		}
+
+		if len(path) == 0 {
+			return "", false
+		}
+
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+	}
+}
+
 // canonicalAddr returns url.Host but always with a ":port" suffix
 // Copied from "net/http".ProxyFromEnvironment in the go std lib.
 func canonicalAddr(url *url.URL) string {
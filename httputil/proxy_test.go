@@ -102,3 +102,38 @@ func TestProxyNoProxy(t *testing.T) {
 	assert.Nil(t, proxyUrl)
 	assert.Nil(t, err)
 }
+
+func TestProxyFromGitConfigForURL(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"http.proxy":                                 "http://global-proxy:8080",
+			"http.https://some-host.com.proxy":           "http://host-proxy:8080",
+			"http.https://some-host.com/foo/.proxy":      "http://path-proxy:8080",
+			"http.https://other-host.com:8443/bar.proxy": "",
+		},
+	})
+
+	for rawurl, expected := range map[string]string{
+		"https://some-host.com/foo/bar":          "path-proxy:8080",
+		"https://some-host.com:443/foo/bar":      "path-proxy:8080",
+		"https://some-host.com/foobar":           "host-proxy:8080",
+		"https://some-host.com/":                 "host-proxy:8080",
+		"http://some-host.com/foo/bar":           "global-proxy:8080",
+		"https://another-host.com/foo/bar":       "global-proxy:8080",
+		"https://other-host.com:8443/bar/baz":    "",
+		"https://other-host.com:8443/barbaz/baz": "global-proxy:8080",
+	} {
+		req, err := http.NewRequest("GET", rawurl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proxyURL, err := ProxyFromGitConfigOrEnvironment(cfg)(req)
+		assert.Nil(t, err, rawurl)
+		if len(expected) == 0 {
+			assert.Nil(t, proxyURL, rawurl)
+		} else if assert.NotNil(t, proxyURL, rawurl) {
+			assert.Equal(t, expected, proxyURL.Host, rawurl)
+		}
+	}
+}
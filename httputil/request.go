@@ -151,7 +151,7 @@ func DoHttpRequestWithRedirects(cfg *config.Configuration, req *http.Request, vi
 		redirectedReq.Body = realBody
 		redirectedReq.ContentLength = req.ContentLength
 
-		if err = CheckRedirect(redirectedReq, via); err != nil {
+		if err = NewHttpClient(cfg, req.Host).CheckRedirect(redirectedReq, via); err != nil {
 			return res, errors.Wrapf(err, err.Error())
 		}
 
@@ -177,6 +177,19 @@ func NewHttpRequest(method, rawurl string, header map[string]string) (*http.Requ
 	return req, nil
 }
 
+// UserAgentFor returns the User-Agent sent with requests made using cfg: the
+// UserAgent, followed by lfs.useragentsuffix if it is set.
+func UserAgentFor(cfg *config.Configuration) string {
+	if suffix := cfg.UserAgentSuffix(); len(suffix) > 0 {
+		return UserAgent + " " + suffix
+	}
+	return UserAgent
+}
+
+func setUserAgent(cfg *config.Configuration, req *http.Request) {
+	req.Header.Set("User-Agent", UserAgentFor(cfg))
+}
+
 func SetAuthType(cfg *config.Configuration, req *http.Request, res *http.Response) {
 	authType := GetAuthType(res)
 	operation := auth.GetOperationForRequest(req)
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/git-lfs/git-lfs/auth"
 	"github.com/git-lfs/git-lfs/config"
@@ -20,6 +21,10 @@ var (
 		"Authorization": true,
 	}
 
+	// Response headers which commonly report whether a CDN served the
+	// response from its cache, e.g. "X-Cache: HIT" or "CF-Cache-Status: MISS"
+	defaultCacheStatusHeaders = []string{"X-Cache", "CF-Cache-Status"}
+
 	defaultErrors = map[int]string{
 		400: "Client error: %s",
 		401: "Authorization error: %s\nCheck that you have proper access to the repository",
@@ -50,6 +55,50 @@ func DecodeResponse(res *http.Response, obj interface{}) error {
 	return nil
 }
 
+// CacheStatus returns whether a cache in front of the server answered res from
+// its contents, as "hit" or "miss", or the status reported by the cache if it
+// is neither. The headers consulted are taken from lfs.transfer.cacheheaders,
+// a comma separated list, and default to X-Cache and CF-Cache-Status. Returns
+// an empty string if no header is present.
+func CacheStatus(cfg *config.Configuration, res *http.Response) string {
+	if res == nil {
+		return ""
+	}
+
+	headers := defaultCacheStatusHeaders
+	if v, ok := cfg.Git.Get("lfs.transfer.cacheheaders"); ok {
+		headers = nil
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); len(h) > 0 {
+				headers = append(headers, h)
+			}
+		}
+	}
+
+	for _, h := range headers {
+		if v := res.Header.Get(h); len(v) > 0 {
+			return parseCacheStatus(v)
+		}
+	}
+	return ""
+}
+
+// parseCacheStatus normalises a cache status header value. Layered caches may
+// report one status per layer, e.g. "MISS, HIT", in which case the last is the
+// one nearest the client. Others add detail, e.g. "Hit from cloudfront".
+func parseCacheStatus(v string) string {
+	parts := strings.Split(v, ",")
+	status := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+
+	switch {
+	case strings.Contains(status, "hit"):
+		return "hit"
+	case strings.Contains(status, "miss"):
+		return "miss"
+	}
+	return status
+}
+
 // GetDefaultError returns the default text for standard error codes (blank if none)
 func GetDefaultError(code int) string {
 	if s, ok := defaultErrors[code]; ok {
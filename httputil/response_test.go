@@ -0,0 +1,62 @@
+package httputil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheStatusFromCommonHeaders(t *testing.T) {
+	cfg := config.NewFrom(config.Values{})
+
+	for header, expected := range map[string]map[string]string{
+		"X-Cache": {
+			"HIT":                  "hit",
+			"MISS":                 "miss",
+			"Hit from cloudfront":  "hit",
+			"Miss from cloudfront": "miss",
+			"MISS, HIT":            "hit",
+			"HIT, MISS":            "miss",
+		},
+		"CF-Cache-Status": {
+			"HIT":     "hit",
+			"MISS":    "miss",
+			"EXPIRED": "expired",
+			"DYNAMIC": "dynamic",
+		},
+	} {
+		for value, status := range expected {
+			res := &http.Response{Header: make(http.Header)}
+			res.Header.Set(header, value)
+
+			assert.Equal(t, status, CacheStatus(cfg, res), "%s: %s", header, value)
+		}
+	}
+}
+
+func TestCacheStatusWithoutHeaders(t *testing.T) {
+	cfg := config.NewFrom(config.Values{})
+
+	assert.Equal(t, "", CacheStatus(cfg, &http.Response{Header: make(http.Header)}))
+	assert.Equal(t, "", CacheStatus(cfg, nil))
+}
+
+func TestCacheStatusFromConfiguredHeaders(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.transfer.cacheheaders": "X-Edge-Cache, X-Other-Cache",
+		},
+	})
+
+	res := &http.Response{Header: make(http.Header)}
+	res.Header.Set("X-Cache", "HIT")
+	assert.Equal(t, "", CacheStatus(cfg, res))
+
+	res.Header.Set("X-Other-Cache", "MISS")
+	assert.Equal(t, "miss", CacheStatus(cfg, res))
+
+	res.Header.Set("X-Edge-Cache", "HIT")
+	assert.Equal(t, "hit", CacheStatus(cfg, res))
+}
@@ -0,0 +1,50 @@
+package httputil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/rubyist/tracerx"
+)
+
+// TraceIdHeader is the header carrying the trace ID on every API and transfer
+// request, so that servers can correlate the requests made by one run.
+const TraceIdHeader = "X-GitLFS-Trace-Id"
+
+var (
+	traceId     string
+	traceIdOnce sync.Once
+)
+
+// TraceId returns the ID shared by all requests made by this process. It is
+// taken from GIT_LFS_TRACE_ID if set, otherwise it is generated at random the
+// first time it's needed.
+func TraceId(cfg *config.Configuration) string {
+	traceIdOnce.Do(func() {
+		if id, _ := cfg.Os.Get("GIT_LFS_TRACE_ID"); len(strings.TrimSpace(id)) > 0 {
+			traceId = strings.TrimSpace(id)
+		} else {
+			traceId = newTraceId()
+		}
+		tracerx.Printf("trace id: %s", traceId)
+	})
+
+	return traceId
+}
+
+func setTraceId(cfg *config.Configuration, req *http.Request) {
+	req.Header.Set(TraceIdHeader, TraceId(cfg))
+}
+
+func newTraceId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		tracerx.Printf("trace id: unable to generate: %v", err)
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
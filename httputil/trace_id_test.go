@@ -0,0 +1,59 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetTraceId() {
+	traceId = ""
+	traceIdOnce = sync.Once{}
+}
+
+func TestTraceIdHeaderIsConsistentAcrossRequests(t *testing.T) {
+	resetTraceId()
+	defer resetTraceId()
+
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(TraceIdHeader))
+	}))
+	defer server.Close()
+
+	cfg := config.NewFrom(config.Values{})
+	for i := 0; i < 3; i++ {
+		req, err := NewHttpRequest("GET", server.URL, nil)
+		require.Nil(t, err)
+
+		res, err := NewHttpClient(cfg, req.Host).Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+	}
+
+	require.Len(t, ids, 3)
+	assert.Len(t, ids[0], 32)
+	assert.Equal(t, ids[0], ids[1])
+	assert.Equal(t, ids[0], ids[2])
+}
+
+func TestTraceIdFromEnvironment(t *testing.T) {
+	resetTraceId()
+	defer resetTraceId()
+
+	cfg := config.NewFrom(config.Values{
+		Os: map[string]string{
+			"GIT_LFS_TRACE_ID": "build-1234",
+		},
+	})
+
+	assert.Equal(t, "build-1234", TraceId(cfg))
+
+	// the ID is fixed for the life of the process
+	assert.Equal(t, "build-1234", TraceId(config.NewFrom(config.Values{})))
+}
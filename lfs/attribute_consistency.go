@@ -0,0 +1,157 @@
+package lfs
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// AttributeConflict describes a file whose content at a ref is not stored the
+// way the .gitattributes files which apply to it say it should be.
+type AttributeConflict struct {
+	// Name of the file, relative to the root of the repository
+	Name string
+	// Tracked is true if the file is tracked by Git LFS but stored as a plain
+	// Git blob, and false if it is stored as a Git LFS pointer but is not
+	// tracked by Git LFS
+	Tracked bool
+}
+
+func (c *AttributeConflict) String() string {
+	if c.Tracked {
+		return fmt.Sprintf("%s: tracked by Git LFS, but stored as a Git blob", c.Name)
+	}
+	return fmt.Sprintf("%s: stored as a Git LFS pointer, but not tracked by Git LFS", c.Name)
+}
+
+// ScanAttributeConflicts compares every file in the tree at ref with the
+// effective "filter" attribute for its path, taking all .gitattributes files
+// in the tree at ref into account, and returns the files where they disagree.
+func ScanAttributeConflicts(ref string) ([]*AttributeConflict, error) {
+	files, err := lsTreeFiles(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	pointers, err := ScanTree(ref)
+	if err != nil {
+		return nil, err
+	}
+	isPointer := make(map[string]bool, len(pointers))
+	for _, p := range pointers {
+		isPointer[p.Name] = true
+	}
+
+	filters, err := checkAttrFilter(ref, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []*AttributeConflict
+	for _, name := range files {
+		tracked := filters[name] == "lfs"
+		if tracked != isPointer[name] {
+			conflicts = append(conflicts, &AttributeConflict{Name: name, Tracked: tracked})
+		}
+	}
+	return conflicts, nil
+}
+
+// lsTreeFiles returns the names of all regular files in the tree at ref,
+// ignoring symlinks and submodules which are never stored by Git LFS.
+func lsTreeFiles(ref string) ([]string, error) {
+	cmd, err := startCommand("git", "ls-tree", "-r", "-z", "--full-tree", ref)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin.Close()
+
+	var files []string
+	scanner := bufio.NewScanner(cmd.Stdout)
+	scanner.Split(scanNullLines)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		attrs := strings.SplitN(parts[0], " ", 3)
+		if len(attrs) < 3 || attrs[1] != "blob" || attrs[0] == symlinkMode {
+			continue
+		}
+		files = append(files, parts[1])
+	}
+
+	stderr, _ := ioutil.ReadAll(cmd.Stderr)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("Error in git ls-tree: %v %v", err, string(stderr))
+	}
+	return files, nil
+}
+
+// checkAttrFilter returns the value of the "filter" attribute for each of the
+// given files, according to the .gitattributes files in the tree at ref. They
+// are read from a temporary index holding that tree, so that the real index is
+// left alone.
+func checkAttrFilter(ref string, files []string) (map[string]string, error) {
+	tmp, err := TempFile("index")
+	if err != nil {
+		return nil, err
+	}
+	// git won't read an empty index, only a missing one
+	tmp.Close()
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	env := append(os.Environ(), "GIT_INDEX_FILE="+tmp.Name())
+
+	readTree := exec.Command("git", "read-tree", ref)
+	readTree.Env = env
+	if out, err := readTree.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("Error in git read-tree: %v %v", err, string(out))
+	}
+
+	cmd, err := startCommandEnv(env, "git", "check-attr", "--cached", "--stdin", "-z", "filter")
+	if err != nil {
+		return nil, err
+	}
+
+	// Feed the paths concurrently, git may block writing results before it
+	// has read all of its input
+	var feedwait sync.WaitGroup
+	feedwait.Add(1)
+	go func() {
+		defer feedwait.Done()
+		for _, f := range files {
+			fmt.Fprintf(cmd.Stdin, "%s\000", f)
+		}
+		cmd.Stdin.Close()
+	}()
+
+	// Output is <path> NUL <attribute> NUL <value> NUL
+	filters := make(map[string]string, len(files))
+	scanner := bufio.NewScanner(cmd.Stdout)
+	scanner.Split(scanNullLines)
+	for {
+		var fields [3]string
+		i := 0
+		for ; i < len(fields) && scanner.Scan(); i++ {
+			fields[i] = scanner.Text()
+		}
+		if i < len(fields) {
+			break
+		}
+		filters[fields[0]] = fields[2]
+	}
+	feedwait.Wait()
+
+	stderr, _ := ioutil.ReadAll(cmd.Stderr)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("Error in git check-attr: %v %v", err, string(stderr))
+	}
+	return filters, nil
+}
@@ -1,6 +1,9 @@
 package lfs
 
-import "sync/atomic"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 // Batcher provides a way to process a set of items in groups of n. Items can
 // be added to the batcher from multiple goroutines and pulled off in groups
@@ -11,37 +14,45 @@ import "sync/atomic"
 // When an Exit() or Flush() occurs, the group may be smaller than the batch
 // size.
 type Batcher struct {
-	exited     uint32
-	batchSize  int
-	input      chan interface{}
+	batchSize  int32
 	batchReady chan []interface{}
-	flush      chan interface{}
+
+	// mu guards exited, input and flush, which are replaced when the
+	// batcher is reset after Exit()
+	mu     sync.Mutex
+	exited bool
+	input  chan interface{}
+	flush  chan interface{}
 }
 
 // NewBatcher creates a Batcher with the batchSize.
 func NewBatcher(batchSize int) *Batcher {
 	b := &Batcher{
-		batchSize:  batchSize,
+		batchSize:  int32(batchSize),
 		input:      make(chan interface{}),
 		batchReady: make(chan []interface{}),
 		flush:      make(chan interface{}),
 	}
 
-	go b.acceptInput()
+	go b.acceptInput(b.input, b.flush)
 	return b
 }
 
 // Add adds one or more items to the batcher. Add is safe to call from multiple
 // goroutines.
 func (b *Batcher) Add(ts ...interface{}) {
-	if atomic.CompareAndSwapUint32(&b.exited, 1, 0) {
+	b.mu.Lock()
+	if b.exited {
+		b.exited = false
 		b.input = make(chan interface{})
 		b.flush = make(chan interface{})
-		go b.acceptInput()
+		go b.acceptInput(b.input, b.flush)
 	}
+	input := b.input
+	b.mu.Unlock()
 
 	for _, t := range ts {
-		b.input <- t
+		input <- t
 	}
 }
 
@@ -54,13 +65,34 @@ func (b *Batcher) Next() []interface{} {
 // Flush causes the current batch to halt accumulation and return
 // immediately, even if it is smaller than the given batch size.
 func (b *Batcher) Flush() {
-	b.flush <- struct{}{}
+	b.mu.Lock()
+	flush := b.flush
+	b.mu.Unlock()
+
+	flush <- struct{}{}
+}
+
+// SetBatchSize changes the size of the batches returned by Next(). It takes
+// effect immediately, so the batch being filled may be returned as soon as it
+// reaches the new size. Sizes of less than one are ignored.
+func (b *Batcher) SetBatchSize(batchSize int) {
+	if batchSize > 0 {
+		atomic.StoreInt32(&b.batchSize, int32(batchSize))
+	}
+}
+
+// BatchSize returns the current size of the batches returned by Next().
+func (b *Batcher) BatchSize() int {
+	return int(atomic.LoadInt32(&b.batchSize))
 }
 
 // Exit stops all batching and allows Next() to return. Calling Add() after
 // calling Exit() will reset the batcher.
 func (b *Batcher) Exit() {
-	atomic.StoreUint32(&b.exited, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.exited = true
 	close(b.input)
 	close(b.flush)
 }
@@ -70,22 +102,25 @@ func (b *Batcher) Exit() {
 // order, and then dispensed. If, while filling a batch, it is flushed part-way
 // through, the batch will be dispensed with its current contents, and all
 // subsequent Add()s will be placed in the next batch.
-func (b *Batcher) acceptInput() {
+//
+// It reads from the given channels rather than the Batcher's, which Add()
+// replaces when it resets the batcher after Exit().
+func (b *Batcher) acceptInput(input, flush <-chan interface{}) {
 	var exit bool
 
 	for {
-		batch := make([]interface{}, 0, b.batchSize)
+		batch := make([]interface{}, 0, b.BatchSize())
 	Acc:
-		for len(batch) < b.batchSize {
+		for len(batch) < b.BatchSize() {
 			select {
-			case t, ok := <-b.input:
+			case t, ok := <-input:
 				if !ok {
 					exit = true // input channel was closed by Exit()
 					break Acc
 				}
 
 				batch = append(batch, t)
-			case <-b.flush:
+			case <-flush:
 				break Acc
 			}
 		}
@@ -24,6 +24,17 @@ func TestBatcherReturnsIncompleteBatchesWhenExiting(t *testing.T) {
 	}, t)
 }
 
+func TestBatcherUsesChangedBatchSize(t *testing.T) {
+	b := NewBatcher(2)
+	b.SetBatchSize(3)
+	b.SetBatchSize(0)
+
+	go b.Add("a", "b", "c", "d")
+
+	assert.Len(t, b.Next(), 3)
+	assert.Equal(t, 3, b.BatchSize())
+}
+
 func TestBatcherFlushesPartialBatches(t *testing.T) {
 	first, second := "first", "second"
 
@@ -7,8 +7,9 @@ import (
 )
 
 type Downloadable struct {
-	pointer *WrappedPointer
-	object  *api.ObjectResource
+	pointer  *WrappedPointer
+	object   *api.ObjectResource
+	priority int
 }
 
 func (d *Downloadable) Object() *api.ObjectResource {
@@ -36,6 +37,17 @@ func (d *Downloadable) SetObject(o *api.ObjectResource) {
 	d.object = o
 }
 
+// Priority implements Prioritizable.
+func (d *Downloadable) Priority() int {
+	return d.priority
+}
+
+// SetPriority makes this download start before others in the same batch with
+// a lower priority. The default is zero.
+func (d *Downloadable) SetPriority(priority int) {
+	d.priority = priority
+}
+
 // TODO remove this legacy method & only support batch
 func (d *Downloadable) LegacyCheck() (*api.ObjectResource, error) {
 	return api.DownloadCheck(config.Config, d.pointer.Oid)
@@ -46,12 +58,12 @@ func NewDownloadable(p *WrappedPointer) *Downloadable {
 }
 
 // NewDownloadCheckQueue builds a checking queue, checks that objects are there but doesn't download
-func NewDownloadCheckQueue(files int, size int64) *TransferQueue {
+func NewDownloadCheckQueue(files int, size int64, options ...TransferQueueOption) *TransferQueue {
 	// Always dry run
-	return newTransferQueue(files, size, true, transfer.Download)
+	return newTransferQueue(files, size, true, transfer.Download, options...)
 }
 
 // NewDownloadQueue builds a DownloadQueue, allowing concurrent downloads.
-func NewDownloadQueue(files int, size int64, dryRun bool) *TransferQueue {
-	return newTransferQueue(files, size, dryRun, transfer.Download)
+func NewDownloadQueue(files int, size int64, dryRun bool, options ...TransferQueueOption) *TransferQueue {
+	return newTransferQueue(files, size, dryRun, transfer.Download, options...)
 }
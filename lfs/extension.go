@@ -40,6 +40,10 @@ type extCommand struct {
 	result *pipeExtResult
 }
 
+// pipeExtensions runs the content from request.reader through each extension
+// in turn, in the order given. The extensions are started together and
+// connected by pipes, so every stage works concurrently on the stream, each on
+// the output of the one before.
 func pipeExtensions(request *pipeRequest) (response pipeResponse, err error) {
 	var extcmds []*extCommand
 	for _, e := range request.extensions {
@@ -0,0 +1,118 @@
+package lfs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTempRepo changes into a new, empty repository and points the local
+// storage at it, as test.Repo.Pushd does for tests outside this package. The
+// returned func changes back and removes the repository.
+func useTempRepo(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "lfsRepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := exec.Command("git", "init", dir).CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Unable to create git repo at %v: %v %v", dir, err, string(out))
+	}
+	if err := os.Chdir(dir); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	localstorage.ResolveDirs()
+
+	return func() {
+		os.Chdir(oldwd)
+		localstorage.ResolveDirs()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPipeExtensionsRunsStagesConcurrently(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("extension scripts need a POSIX shell")
+	}
+	defer useTempRepo(t)()
+
+	dir, err := ioutil.TempDir("", "lfs-extensions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var exts []config.Extension
+	for i, name := range []string{"foo", "bar"} {
+		script := filepath.Join(dir, name)
+		body := "#!/bin/sh\ntouch \"$0.started\"\nexec cat\n"
+		if err := ioutil.WriteFile(script, []byte(body), 0755); err != nil {
+			t.Fatal(err)
+		}
+		exts = append(exts, config.Extension{
+			Name:     name,
+			Clean:    script + " %f",
+			Smudge:   script + " %f",
+			Priority: i,
+		})
+	}
+
+	input, inputWriter := io.Pipe()
+	overlapped := make(chan bool, 1)
+	go func() {
+		fmt.Fprint(inputWriter, "partial ")
+
+		// Both stages start while the first is still reading its input,
+		// so the second isn't waiting for the first to finish.
+		started := false
+		deadline := time.Now().Add(5 * time.Second)
+		for !started && time.Now().Before(deadline) {
+			_, fooErr := os.Stat(filepath.Join(dir, "foo.started"))
+			_, barErr := os.Stat(filepath.Join(dir, "bar.started"))
+			if started = fooErr == nil && barErr == nil; !started {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		overlapped <- started
+
+		fmt.Fprint(inputWriter, "content")
+		inputWriter.Close()
+	}()
+
+	response, err := pipeExtensions(&pipeRequest{"clean", input, "file.dat", exts})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.Remove(response.file.Name())
+
+	assert.True(t, <-overlapped, "stages did not run at the same time")
+
+	by, err := ioutil.ReadFile(response.file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, "partial content", string(by))
+
+	// Each stage passes its input through unchanged
+	if assert.Len(t, response.results, 2) {
+		assert.Equal(t, "foo", response.results[0].name)
+		assert.Equal(t, "bar", response.results[1].name)
+		assert.Equal(t, response.results[0].oidIn, response.results[0].oidOut)
+		assert.Equal(t, response.results[0].oidOut, response.results[1].oidIn)
+		assert.Equal(t, response.results[1].oidIn, response.results[1].oidOut)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/localstorage"
 	"github.com/git-lfs/git-lfs/tools"
+	"github.com/git-lfs/git-lfs/tools/longpathos"
 	"github.com/git-lfs/git-lfs/transfer"
 	"github.com/rubyist/tracerx"
 )
@@ -24,6 +25,9 @@ var (
 	LargeSizeThreshold = 5 * 1024 * 1024
 )
 
+// EmptyObjectOid is the OID of the zero-byte object.
+const EmptyObjectOid = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
 // LocalMediaDir returns the root of lfs objects
 func LocalMediaDir() string {
 	if localstorage.Objects() != nil {
@@ -55,6 +59,21 @@ func LocalMediaPathReadOnly(oid string) string {
 	return localstorage.Objects().ObjectPath(oid)
 }
 
+// MoveBadObject moves the corrupt object at path out of the local store, into
+// .git/lfs/bad, and returns its new path.
+func MoveBadObject(oid, path string) (string, error) {
+	badDir := filepath.Join(config.LocalGitStorageDir, "lfs", "bad")
+	if err := longpathos.MkdirAll(badDir, 0755); err != nil {
+		return "", err
+	}
+
+	badFile := filepath.Join(badDir, oid)
+	if err := longpathos.Rename(path, badFile); err != nil {
+		return "", err
+	}
+	return badFile, nil
+}
+
 func LocalReferencePath(sha string) string {
 	if config.LocalReferenceDir == "" {
 		return ""
@@ -67,6 +86,30 @@ func ObjectExistsOfSize(oid string, size int64) bool {
 	return tools.FileExistsOfSize(path, size)
 }
 
+// IsEmptyObject returns whether oid and size describe the zero-byte object,
+// which never needs transferring since its content is known.
+func IsEmptyObject(oid string, size int64) bool {
+	return size == 0 && oid == EmptyObjectOid
+}
+
+// WriteEmptyObject creates the zero-byte object in local storage, if it isn't
+// there already.
+func WriteEmptyObject() error {
+	path, err := LocalMediaPath(EmptyObjectOid)
+	if err != nil {
+		return err
+	}
+	if tools.FileExistsOfSize(path, 0) {
+		return nil
+	}
+
+	file, err := longpathos.Create(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
 func Environ(cfg *config.Configuration, manifest *transfer.Manifest) []string {
 	osEnviron := os.Environ()
 	env := make([]string, 0, len(osEnviron)+7)
@@ -133,6 +176,16 @@ func ClearTempObjects() error {
 	return localstorage.Objects().ClearTempObjects()
 }
 
+// MigrateLocalObjects moves any local objects stored with a different sharding
+// depth to where lfs.storage.shardingdepth says they belong, returning how many
+// were moved.
+func MigrateLocalObjects() (int, error) {
+	if localstorage.Objects() == nil {
+		return 0, nil
+	}
+	return localstorage.Objects().MigrateObjects()
+}
+
 func ScanObjectsChan() <-chan localstorage.Object {
 	return localstorage.Objects().ScanObjectsChan()
 }
@@ -2,6 +2,8 @@ package lfs_test // avoid import cycle
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"testing"
 
@@ -67,3 +69,45 @@ func TestAllCurrentObjectsSome(t *testing.T) {
 	assert.Equal(t, expected, actual, "Oids from disk should be the same as in commits")
 
 }
+
+func TestPlanPointerSmudgeToFile(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	outputs := repo.AddCommits([]*test.CommitInput{
+		{Files: []*test.FileInput{{Filename: "present.dat", Size: 30}}},
+	})
+	present := outputs[0].Files[0]
+	missing := lfs.NewPointer("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", 10, nil)
+	empty := lfs.NewPointer(lfs.EmptyObjectOid, 0, nil)
+
+	for desc, c := range map[string]struct {
+		Ptr      *lfs.Pointer
+		Download bool
+		Action   lfs.SmudgeAction
+	}{
+		"present locally":   {present, false, lfs.SmudgeFromLocal},
+		"needs download":    {missing, true, lfs.SmudgeDownload},
+		"download declined": {missing, false, lfs.SmudgePlaceholder},
+		"empty object":      {empty, false, lfs.SmudgeEmpty},
+	} {
+		filename := filepath.Join(repo.Path, "planned.dat")
+
+		plan, err := lfs.PlanPointerSmudgeToFile(filename, c.Ptr, c.Download)
+		if assert.Nil(t, err, desc) {
+			assert.Equal(t, c.Action, plan.Action, desc)
+			assert.Equal(t, filename, plan.Filename, desc)
+			assert.Equal(t, c.Ptr.Oid, plan.Oid, desc)
+			assert.Equal(t, lfs.LocalMediaPathReadOnly(c.Ptr.Oid), plan.MediaFile, desc)
+		}
+
+		_, err = os.Stat(filename)
+		assert.True(t, os.IsNotExist(err), desc)
+	}
+
+	assert.False(t, lfs.ObjectExistsOfSize(missing.Oid, missing.Size))
+}
@@ -83,12 +83,11 @@ func copyToTemp(reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid
 		return
 	}
 
-	var from io.Reader = bytes.NewReader(by)
-	if int64(len(by)) < fileSize {
-		// If there is still more data to be read from the file, tack on
-		// the original reader and continue the read from there.
-		from = io.MultiReader(from, reader)
-	}
+	// Carry on reading from where DecodeFrom stopped, streaming the rest
+	// into the temp file as it's hashed. This doesn't rely on fileSize,
+	// which is only a guess at the size, used for progress, and is 0 if the
+	// size is unknown, e.g. when reading from stdin.
+	from := io.MultiReader(bytes.NewReader(by), reader)
 
 	size, err = tools.CopyWithCallback(writer, from, fileSize, cb)
 
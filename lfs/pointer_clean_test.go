@@ -0,0 +1,66 @@
+package lfs_test // to avoid import cycles
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patternReader endlessly reads the same deterministic, non-pointer bytes.
+type patternReader struct {
+	n int
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r.n % 251)
+		r.n++
+	}
+	return len(p), nil
+}
+
+func TestPointerCleanStreamsLargeObjects(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	oldConfig := config.Config
+	config.Config = config.NewFrom(config.Values{})
+	defer func() { config.Config = oldConfig }()
+
+	const size = 32 * 1024 * 1024
+
+	h := sha256.New()
+	_, err := io.Copy(h, io.LimitReader(&patternReader{}, size))
+	require.Nil(t, err)
+	expectedOid := hex.EncodeToString(h.Sum(nil))
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	// The size isn't given, as when cleaning from stdin
+	cleaned, err := lfs.PointerClean(io.LimitReader(&patternReader{}, size), "", 0, nil)
+	require.Nil(t, err)
+	defer cleaned.Teardown()
+
+	runtime.ReadMemStats(&after)
+
+	assert.Equal(t, expectedOid, cleaned.Oid)
+	assert.EqualValues(t, size, cleaned.Size)
+
+	// Nothing like the whole object is held in memory
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < size/8,
+		"allocated %d bytes", after.TotalAlloc-before.TotalAlloc)
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/cheggaaa/pb"
 	"github.com/git-lfs/git-lfs/tools"
@@ -19,6 +20,10 @@ import (
 )
 
 func PointerSmudgeToFile(filename string, ptr *Pointer, download bool, manifest *transfer.Manifest, cb progress.CopyCallback) error {
+	if err := checkWorkingTreeFile(filename); err != nil {
+		return err
+	}
+
 	longpathos.MkdirAll(filepath.Dir(filename), 0755)
 	file, err := longpathos.Create(filename)
 	if err != nil {
@@ -38,18 +43,117 @@ func PointerSmudgeToFile(filename string, ptr *Pointer, download bool, manifest
 	return nil
 }
 
+// SmudgeAction is what PointerSmudgeToFile would do to write an object.
+type SmudgeAction int
+
+const (
+	// SmudgeFromLocal copies the object from the local store.
+	SmudgeFromLocal SmudgeAction = iota
+	// SmudgeFromReference links or copies the object from the reference
+	// repository's store, then copies it from the local store.
+	SmudgeFromReference
+	// SmudgeDownload downloads the object, then copies it.
+	SmudgeDownload
+	// SmudgePlaceholder writes the pointer, since the object isn't present
+	// and downloading it was declined.
+	SmudgePlaceholder
+	// SmudgeEmpty writes an empty file for the empty object.
+	SmudgeEmpty
+)
+
+// SmudgePlan describes what PointerSmudgeToFile would do, as returned by
+// PlanPointerSmudgeToFile.
+type SmudgePlan struct {
+	Filename  string
+	Oid       string
+	Size      int64
+	MediaFile string
+	Action    SmudgeAction
+}
+
+// PlanPointerSmudgeToFile works out what PointerSmudgeToFile would do with the
+// same arguments, without writing anything. It returns an error if the working
+// tree file couldn't be written.
+func PlanPointerSmudgeToFile(filename string, ptr *Pointer, download bool) (*SmudgePlan, error) {
+	if err := checkWorkingTreeFile(filename); err != nil {
+		return nil, err
+	}
+
+	plan := &SmudgePlan{
+		Filename:  filename,
+		Oid:       ptr.Oid,
+		Size:      ptr.Size,
+		MediaFile: LocalMediaPathReadOnly(ptr.Oid),
+	}
+
+	switch {
+	case IsEmptyObject(ptr.Oid, ptr.Size) && config.Config.SkipEmptyObjects(false):
+		plan.Action = SmudgeEmpty
+	case ptr.Size > 0 && ObjectExistsOfSize(ptr.Oid, ptr.Size):
+		plan.Action = SmudgeFromLocal
+	case ptr.Size > 0 && tools.FileExistsOfSize(LocalReferencePath(ptr.Oid), ptr.Size):
+		plan.Action = SmudgeFromReference
+	case download:
+		plan.Action = SmudgeDownload
+	default:
+		plan.Action = SmudgePlaceholder
+	}
+
+	return plan, nil
+}
+
+// checkWorkingTreeFile makes sure that writing filename won't follow a symlink,
+// either filename itself or one of its parent directories pointing out of the
+// working tree.
+func checkWorkingTreeFile(filename string) error {
+	if stat, err := longpathos.Lstat(filename); err == nil && stat.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("Not writing Git LFS content through symlink %v", filename)
+	}
+
+	if len(config.LocalWorkingDir) == 0 {
+		return nil
+	}
+
+	// Parent directories might not have been created yet, so resolve the
+	// nearest one that exists
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	for !tools.DirExists(dir) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+	}
+
+	rel, err := filepath.Rel(config.LocalWorkingDir, tools.ResolveSymlinks(dir))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("Not writing Git LFS content to %v, outside of the working tree", filename)
+	}
+
+	return nil
+}
+
 func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download bool, manifest *transfer.Manifest, cb progress.CopyCallback) error {
 	mediafile, err := LocalMediaPath(ptr.Oid)
 	if err != nil {
 		return err
 	}
 
+	if IsEmptyObject(ptr.Oid, ptr.Size) && config.Config.SkipEmptyObjects(false) {
+		// There's nothing to write, but keep the local store complete
+		if err := WriteEmptyObject(); err != nil {
+			return errors.NewSmudgeError(err, ptr.Oid, mediafile)
+		}
+		return nil
+	}
+
 	LinkOrCopyFromReference(ptr.Oid, ptr.Size)
 
 	stat, statErr := longpathos.Stat(mediafile)
 	if statErr == nil && stat != nil {
+		// Zero-byte files are left by interrupted downloads, so are only
+		// valid for the empty object itself
 		fileSize := stat.Size()
-		if fileSize == 0 || fileSize != ptr.Size {
+		if fileSize != ptr.Size || (fileSize == 0 && !IsEmptyObject(ptr.Oid, ptr.Size)) {
 			tracerx.Printf("Removing %s, size %d is invalid", mediafile, fileSize)
 			longpathos.RemoveAll(mediafile)
 			stat = nil
@@ -73,7 +177,38 @@ func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download
 	return nil
 }
 
+// diskSpaceMargin is the space left free by a download, besides the object.
+const diskSpaceMargin = 16 * 1024 * 1024
+
+// diskFree returns the space available in a directory. It is a variable so
+// that tests can simulate a full disk.
+var diskFree = tools.DiskFree
+
+// checkDiskSpace returns an error if the filesystem holding dir hasn't room
+// for size bytes, plus diskSpaceMargin. The check is skipped if the size or the
+// space available is unknown.
+func checkDiskSpace(dir string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+
+	free, ok := diskFree(dir)
+	if !ok {
+		return nil
+	}
+
+	if needed := uint64(size) + diskSpaceMargin; free < needed {
+		return fmt.Errorf("insufficient disk space in %s: %s needed, %s available",
+			dir, pb.FormatBytes(int64(needed)), pb.FormatBytes(int64(free)))
+	}
+	return nil
+}
+
 func downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string, manifest *transfer.Manifest, cb progress.CopyCallback) error {
+	if err := checkDiskSpace(filepath.Dir(mediafile), ptr.Size); err != nil {
+		return err
+	}
+
 	fmt.Fprintf(os.Stderr, "Downloading %s (%s)\n", workingfile, pb.FormatBytes(ptr.Size))
 
 	xfers := manifest.GetDownloadAdapterNames()
@@ -125,6 +260,9 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 
 	if len(ptr.Extensions) > 0 {
 		registeredExts := config.Config.Extensions()
+		if err := config.ValidateExtensions(registeredExts); err != nil {
+			return errors.Wrap(err, "smudge")
+		}
 		extensions := make(map[string]config.Extension)
 		for _, ptrExt := range ptr.Extensions {
 			ext, ok := registeredExts[ptrExt.Name]
@@ -186,10 +324,32 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 		defer reader.Close()
 	}
 
-	_, err = tools.CopyWithCallback(writer, reader, ptr.Size, cb)
+	// Extensions have already checked the object's OID
+	var src io.Reader = reader
+	var hasher *tools.HashingReader
+	if len(ptr.Extensions) == 0 && config.Config.SmudgeVerify() {
+		hasher = tools.NewHashingReader(reader)
+		src = hasher
+	}
+
+	_, err = tools.CopyWithCallback(writer, src, ptr.Size, cb)
 	if err != nil {
 		return errors.Wrapf(err, "Error reading from media file: %s", err)
 	}
 
+	// The content has already been written by the time a mismatch is found
+	if hasher != nil {
+		if oid := hasher.Hash(); oid != ptr.Oid {
+			reader.Close()
+			badFile, err := MoveBadObject(ptr.Oid, mediafile)
+			if err != nil {
+				err = errors.Wrapf(err, "Actual oid %s during smudge does not match expected %s", oid, ptr.Oid)
+			} else {
+				err = fmt.Errorf("Actual oid %s during smudge does not match expected %s, moved to %s", oid, ptr.Oid, badFile)
+			}
+			return errors.NewSmudgeError(err, ptr.Oid, mediafile)
+		}
+	}
+
 	return nil
 }
@@ -31,6 +31,9 @@ const (
 	// chanBufSize is the size of the channels used to pass data from one
 	// sub-process to another.
 	chanBufSize = 100
+
+	// symlinkMode is the mode ls-tree reports for symlinks
+	symlinkMode = "120000"
 )
 
 var (
@@ -79,8 +82,22 @@ type ScanRefsOptions struct {
 	ScanMode         ScanningMode
 	RemoteName       string
 	SkipDeletedBlobs bool
-	nameMap          map[string]string
-	mutex            *sync.Mutex
+	// MaxCommits, if greater than zero, limits the number of commits
+	// walked by `git rev-list`. It doesn't limit the blobs reported for
+	// each commit that is walked.
+	MaxCommits int
+	// Since and Until, if not zero, limit the commits walked by `git
+	// rev-list` to those committed in that range. They are ignored if
+	// SkipDeletedBlobs is set, since only the given refs are visited then.
+	Since time.Time
+	Until time.Time
+	// IncludeCommitParents associates each object with the commit it was
+	// first found in, which is available from Commit. This lists objects
+	// in commit order, which requires git 2.19 or later.
+	IncludeCommitParents bool
+	nameMap              map[string]string
+	commitMap            map[string]string
+	mutex                *sync.Mutex
 }
 
 func (o *ScanRefsOptions) GetName(sha string) (string, bool) {
@@ -96,10 +113,26 @@ func (o *ScanRefsOptions) SetName(sha, name string) {
 	o.mutex.Unlock()
 }
 
+// Commit returns the commit being walked when the object sha was scanned, if
+// IncludeCommitParents was set.
+func (o *ScanRefsOptions) Commit(sha string) (string, bool) {
+	o.mutex.Lock()
+	commit, ok := o.commitMap[sha]
+	o.mutex.Unlock()
+	return commit, ok
+}
+
+func (o *ScanRefsOptions) setCommit(sha, commit string) {
+	o.mutex.Lock()
+	o.commitMap[sha] = commit
+	o.mutex.Unlock()
+}
+
 func NewScanRefsOptions() *ScanRefsOptions {
 	return &ScanRefsOptions{
-		nameMap: make(map[string]string, 0),
-		mutex:   &sync.Mutex{},
+		nameMap:   make(map[string]string, 0),
+		commitMap: make(map[string]string, 0),
+		mutex:     &sync.Mutex{},
 	}
 }
 
@@ -294,6 +327,30 @@ func ScanIndex(ref string) ([]*WrappedPointer, error) {
 
 }
 
+// parseRevListLine parses a line of `git rev-list --objects` output into the
+// object's SHA-1 and, for blobs and trees, its path. Paths keep any spaces,
+// including leading and trailing ones. A path git has C-quoted, because it
+// contains special characters, is unquoted.
+func parseRevListLine(line string) (sha1, name string, ok bool) {
+	line = strings.TrimRight(line, "\r")
+	if len(strings.TrimSpace(line)) < 40 {
+		return "", "", false
+	}
+
+	line = strings.TrimLeft(line, " \t")
+	sha1 = line[0:40]
+	if len(line) > 41 {
+		name = line[41:]
+		if len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+		}
+	}
+
+	return sha1, name, true
+}
+
 // Get additional arguments needed to limit 'git rev-list' to just the changes
 // in revTo that are also not on remoteName.
 //
@@ -373,6 +430,26 @@ func revListShas(refLeft, refRight string, opt *ScanRefsOptions) (*StringChannel
 		return nil, errors.New("scanner: unknown scan type: " + strconv.Itoa(int(opt.ScanMode)))
 	}
 
+	if opt.MaxCommits > 0 {
+		refArgs = append(refArgs, fmt.Sprintf("--max-count=%d", opt.MaxCommits))
+	}
+
+	if opt.IncludeCommitParents {
+		if err := requireGitVersion("--in-commit-order", "2.19.0"); err != nil {
+			return nil, err
+		}
+		refArgs = append(refArgs, "--in-commit-order")
+	}
+
+	if opt.ScanMode != ScanRefsMode || !opt.SkipDeletedBlobs {
+		if !opt.Since.IsZero() {
+			refArgs = append(refArgs, "--since="+git.FormatGitDate(opt.Since))
+		}
+		if !opt.Until.IsZero() {
+			refArgs = append(refArgs, "--until="+git.FormatGitDate(opt.Until))
+		}
+	}
+
 	// Use "--" at the end of the command to disambiguate arguments as refs,
 	// so Git doesn't complain about ambiguity if you happen to also have a
 	// file named "master".
@@ -393,16 +470,29 @@ func revListShas(refLeft, refRight string, opt *ScanRefsOptions) (*StringChannel
 	errchan := make(chan error, 5) // may be multiple errors
 
 	go func() {
+		var commit string
+
 		scanner := bufio.NewScanner(cmd.Stdout)
 		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if len(line) < 40 {
+			line := scanner.Text()
+			sha1, name, ok := parseRevListLine(line)
+			if !ok {
 				continue
 			}
 
-			sha1 := line[0:40]
-			if len(line) > 40 {
-				opt.SetName(sha1, line[41:len(line)])
+			if len(name) > 0 {
+				opt.SetName(sha1, name)
+			}
+
+			if opt.IncludeCommitParents {
+				// Commits are listed by SHA-1 alone, and are
+				// followed by the objects first found in them.
+				// Trees and blobs have at least a space after.
+				if len(strings.TrimRight(line, "\r")) == 40 {
+					commit = sha1
+				} else if len(commit) > 0 {
+					opt.setCommit(sha1, commit)
+				}
 			}
 			revs <- sha1
 		}
@@ -521,6 +611,19 @@ func catFileBatch(revs *StringChannelWrapper) (*PointerChannelWrapper, error) {
 	return NewPointerChannelWrapper(pointerCh, errCh), nil
 }
 
+// gitVersionAtLeast reports whether the installed git is at least the given
+// version. It is a variable so that tests can pretend to have an older git.
+var gitVersionAtLeast = git.Config.IsGitVersionAtLeast
+
+// requireGitVersion returns an error naming the git option which the scanner
+// needs, if the installed git is older than version.
+func requireGitVersion(option, version string) error {
+	if gitVersionAtLeast(version) {
+		return nil
+	}
+	return fmt.Errorf("scanner: git %s or later is required for %s", version, option)
+}
+
 type wrappedCmd struct {
 	Stdin  io.WriteCloser
 	Stdout *bufio.Reader
@@ -532,7 +635,14 @@ type wrappedCmd struct {
 // stdout & stderr pipes, wrapped in a wrappedCmd. The stdout buffer will be of stdoutBufSize
 // bytes.
 func startCommand(command string, args ...string) (*wrappedCmd, error) {
+	return startCommandEnv(nil, command, args...)
+}
+
+// startCommandEnv is startCommand with the environment given by env, or that
+// of this process if env is nil.
+func startCommandEnv(env []string, command string, args ...string) (*wrappedCmd, error) {
 	cmd := exec.Command(command, args...)
+	cmd.Env = env
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, err
@@ -549,6 +659,9 @@ func startCommand(command string, args ...string) (*wrappedCmd, error) {
 
 	tracerx.Printf("run_command: %s %s", command, strings.Join(args, " "))
 	if err := cmd.Start(); err != nil {
+		if execErr, ok := err.(*exec.Error); ok && execErr.Err == exec.ErrNotFound {
+			return nil, fmt.Errorf("%s could not be found; check that it is installed and on your PATH: %v", command, err)
+		}
 		return nil, err
 	}
 
@@ -718,6 +831,12 @@ func parseLsTree(reader io.Reader, output chan TreeBlob) {
 			continue
 		}
 
+		// Symlinks store their target path as the blob, so they are never
+		// Git LFS files, whatever that path happens to look like
+		if attrs[0] == symlinkMode {
+			continue
+		}
+
 		sz, err := strconv.ParseInt(strings.TrimSpace(attrs[3]), 10, 64)
 		if err != nil {
 			continue
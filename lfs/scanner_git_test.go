@@ -165,3 +165,132 @@ func TestScanPreviousVersions(t *testing.T) {
 	assert.Equal(t, expected, pointers)
 
 }
+
+func TestScanRefsMaxCommits(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	inputs := []*test.CommitInput{
+		{Files: []*test.FileInput{{Filename: "file1.txt", Size: 20}}},
+		{Files: []*test.FileInput{{Filename: "file1.txt", Size: 21}}},
+		{Files: []*test.FileInput{{Filename: "file1.txt", Size: 22}}},
+		{Files: []*test.FileInput{{Filename: "file1.txt", Size: 23}}},
+	}
+	repo.AddCommits(inputs)
+
+	pointers, err := ScanRefs("master", "", nil)
+	assert.Nil(t, err)
+	assert.Len(t, pointers, 4)
+
+	opt := NewScanRefsOptions()
+	opt.MaxCommits = 2
+	pointers, err = ScanRefs("master", "", opt)
+	assert.Nil(t, err)
+	if assert.Len(t, pointers, 2) {
+		sizes := []int{int(pointers[0].Size), int(pointers[1].Size)}
+		sort.Ints(sizes)
+		assert.Equal(t, []int{22, 23}, sizes)
+	}
+}
+
+func TestScanRefsSinceUntil(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	now := time.Now()
+
+	inputs := []*test.CommitInput{
+		{
+			CommitDate: now.AddDate(0, 0, -20),
+			Files:      []*test.FileInput{{Filename: "file1.txt", Size: 20}},
+		},
+		{
+			CommitDate: now.AddDate(0, 0, -10),
+			Files:      []*test.FileInput{{Filename: "file1.txt", Size: 21}},
+		},
+		{
+			CommitDate: now.AddDate(0, 0, -5),
+			Files:      []*test.FileInput{{Filename: "file1.txt", Size: 22}},
+		},
+		{
+			CommitDate: now.AddDate(0, 0, -1),
+			Files:      []*test.FileInput{{Filename: "file1.txt", Size: 23}},
+		},
+	}
+	repo.AddCommits(inputs)
+
+	opt := NewScanRefsOptions()
+	opt.Since = now.AddDate(0, 0, -12)
+	opt.Until = now.AddDate(0, 0, -3)
+	pointers, err := ScanRefs("master", "", opt)
+	assert.Nil(t, err)
+
+	sizes := make([]int, 0, len(pointers))
+	for _, p := range pointers {
+		sizes = append(sizes, int(p.Size))
+	}
+	sort.Ints(sizes)
+	assert.Equal(t, []int{21, 22}, sizes)
+
+	// Without walking, only the ref itself is scanned, whatever its date.
+	opt = NewScanRefsOptions()
+	opt.SkipDeletedBlobs = true
+	opt.Until = now.AddDate(0, 0, -3)
+	pointers, err = ScanRefs("master", "", opt)
+	assert.Nil(t, err)
+	if assert.Len(t, pointers, 1) {
+		assert.Equal(t, int64(23), pointers[0].Size)
+	}
+}
+
+func TestScanRefsIncludeCommitParents(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	inputs := []*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+				{Filename: "file2.txt", Size: 30},
+			},
+		},
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 21},
+			},
+		},
+	}
+	outputs := repo.AddCommits(inputs)
+
+	opt := NewScanRefsOptions()
+	opt.IncludeCommitParents = true
+	pointers, err := ScanRefs("master", "", opt)
+	assert.Nil(t, err)
+	assert.Len(t, pointers, 3)
+
+	expected := map[int64]string{
+		20: outputs[0].Sha,
+		30: outputs[1].Sha, // unchanged, so first found in the newest commit
+		21: outputs[1].Sha,
+	}
+	for _, p := range pointers {
+		commit, ok := opt.Commit(p.Sha1)
+		assert.True(t, ok, p.Name)
+		assert.Equal(t, expected[p.Size], commit, p.Name)
+	}
+
+	_, ok := NewScanRefsOptions().Commit(pointers[0].Sha1)
+	assert.False(t, ok)
+}
@@ -265,6 +265,20 @@ func TestLsTreeParser(t *testing.T) {
 	}
 }
 
+func TestLsTreeParserSkipsSymlinks(t *testing.T) {
+	stdout := "120000 blob 6e7f1d5a8c0ba6a4e6ed1a8b09c0e8c3f1d2f5b1     126	link.dat\000100644 blob 4d343e022e11a8618db494dc3c501e80c7e18197     126	a.dat"
+
+	blobs := make(chan TreeBlob, 2)
+	parseLsTree(strings.NewReader(stdout), blobs)
+	close(blobs)
+
+	var names []string
+	for blob := range blobs {
+		names = append(names, blob.Filename)
+	}
+	assert.Equal(t, []string{"a.dat"}, names)
+}
+
 func BenchmarkLsTreeParser(b *testing.B) {
 	stdout := "100644 blob d899f6551a51cf19763c5955c7a06a2726f018e9      42	.gitattributes\000100644 blob 4d343e022e11a8618db494dc3c501e80c7e18197     126	PB SCN 16 Odhrán.wav"
 	blobs := make(chan TreeBlob, b.N*2)
@@ -274,3 +288,67 @@ func BenchmarkLsTreeParser(b *testing.B) {
 	}
 	close(blobs)
 }
+
+func TestParseRevListLine(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	for line, expected := range map[string]string{
+		sha:                                "",
+		sha + " file.dat":                  "file.dat",
+		sha + " dir/file with spaces.dat":  "dir/file with spaces.dat",
+		sha + "  leading and trailing  ":   " leading and trailing  ",
+		sha + ` "quoted\"name.dat"`:        `quoted"name.dat`,
+		sha + ` "tab\tname.dat"`:           "tab\tname.dat",
+		sha + ` "caf\303\251 au lait.dat"`: "café au lait.dat",
+		sha + ` "unterminated.dat`:         `"unterminated.dat`,
+		sha + " file.dat\r":                "file.dat",
+	} {
+		sha1, name, ok := parseRevListLine(line)
+		assert.True(t, ok, line)
+		assert.Equal(t, sha, sha1, line)
+		assert.Equal(t, expected, name, line)
+	}
+
+	_, _, ok := parseRevListLine("not a sha")
+	assert.False(t, ok)
+}
+
+func TestRequireGitVersion(t *testing.T) {
+	oldVersionAtLeast := gitVersionAtLeast
+	defer func() { gitVersionAtLeast = oldVersionAtLeast }()
+
+	// Pretend to have git 2.0.0.
+	gitVersionAtLeast = func(version string) bool {
+		return version == "1.8.2" || version == "2.0.0"
+	}
+
+	assert.Nil(t, requireGitVersion("--old-option", "1.8.2"))
+
+	err := requireGitVersion("--in-commit-order", "2.19.0")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "scanner: git 2.19.0 or later is required for --in-commit-order", err.Error())
+	}
+}
+
+func TestScanRefsWithCommitParentsOnOldGit(t *testing.T) {
+	oldVersionAtLeast := gitVersionAtLeast
+	defer func() { gitVersionAtLeast = oldVersionAtLeast }()
+
+	gitVersionAtLeast = func(version string) bool { return false }
+
+	opt := NewScanRefsOptions()
+	opt.IncludeCommitParents = true
+	_, err := ScanRefs("master", "", opt)
+
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "--in-commit-order")
+	}
+}
+
+func TestStartCommandMissingBinary(t *testing.T) {
+	_, err := startCommand("git-lfs-no-such-command")
+
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "git-lfs-no-such-command could not be found")
+	}
+}
@@ -1,7 +1,13 @@
 package lfs
 
 import (
+	"context"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/git-lfs/git-lfs/api"
 	"github.com/git-lfs/git-lfs/config"
@@ -15,6 +21,15 @@ import (
 const (
 	batchSize         = 100
 	defaultMaxRetries = 1
+
+	// slowBatchDuration and fastBatchDuration are how long a batch API
+	// request has to take for an adaptive batch size to grow or shrink
+	slowBatchDuration = 1 * time.Second
+	fastBatchDuration = 250 * time.Millisecond
+
+	// maxBackoffShift caps the exponent used to grow the retry backoff, so
+	// that many retries can't overflow the delay
+	maxBackoffShift = 10
 )
 
 type Transferable interface {
@@ -28,11 +43,43 @@ type Transferable interface {
 	LegacyCheck() (*api.ObjectResource, error)
 }
 
+// Prioritizable is implemented by Transferables which may need transferring
+// before others. Within each batch, objects with higher priorities are
+// transferred first, followed by larger objects. Transferables which don't
+// implement it have priority zero.
+type Prioritizable interface {
+	Priority() int
+}
+
+func priorityOf(t Transferable) int {
+	if p, ok := t.(Prioritizable); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// byPriority sorts Transferables by descending priority, then descending size.
+type byPriority []Transferable
+
+func (b byPriority) Len() int      { return len(b) }
+func (b byPriority) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byPriority) Less(i, j int) bool {
+	if pi, pj := priorityOf(b[i]), priorityOf(b[j]); pi != pj {
+		return pi > pj
+	}
+	return b[i].Size() > b[j].Size()
+}
+
 type retryCounter struct {
 	// MaxRetries is the maximum number of retries a single object can
 	// attempt to make before it will be dropped.
 	MaxRetries int `git:"lfs.transfer.maxretries"`
 
+	// RetryBackoff is the delay before the first retry of an object, as a
+	// duration like "500ms". It doubles with each further retry of the
+	// same object. Retries are immediate if it is unset.
+	RetryBackoff time.Duration `git:"lfs.transfer.retrybackoff"`
+
 	// cmu guards count
 	cmu sync.Mutex
 	// count maps OIDs to number of retry attempts
@@ -62,6 +109,11 @@ func newRetryCounter(cfg *config.Configuration) *retryCounter {
 		rc.MaxRetries = 1
 	}
 
+	if rc.RetryBackoff < 0 {
+		tracerx.Printf("rc: invalid retry backoff: %v, retrying immediately", rc.RetryBackoff)
+		rc.RetryBackoff = 0
+	}
+
 	return rc
 }
 
@@ -83,6 +135,42 @@ func (r *retryCounter) CountFor(oid string) int {
 	return r.count[oid]
 }
 
+// Counts returns the number of retries made for each OID which has been
+// retried at least once. It is safe to call across multiple goroutines.
+func (r *retryCounter) Counts() map[string]int {
+	r.cmu.Lock()
+	defer r.cmu.Unlock()
+
+	counts := make(map[string]int, len(r.count))
+	for oid, count := range r.count {
+		if count > 0 {
+			counts[oid] = count
+		}
+	}
+	return counts
+}
+
+// BackoffFor returns how long to wait before the next attempt at transferring
+// the given OID, based on the number of retries so far: the configured backoff
+// doubled for each retry after the first, plus up to 10% random jitter so that
+// objects failing together don't all retry at once. It is zero if no backoff
+// is configured.
+func (r *retryCounter) BackoffFor(oid string) time.Duration {
+	if r.RetryBackoff <= 0 {
+		return 0
+	}
+
+	shift := r.CountFor(oid) - 1
+	if shift < 0 {
+		shift = 0
+	} else if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+
+	delay := r.RetryBackoff << uint(shift)
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
 // CanRetry returns the current number of retries, and whether or not it exceeds
 // the maximum number of retries (see: retryCounter.MaxRetries).
 func (r *retryCounter) CanRetry(oid string) (int, bool) {
@@ -90,6 +178,173 @@ func (r *retryCounter) CanRetry(oid string) (int, bool) {
 	return count, count < r.MaxRetries
 }
 
+// TransferStats summarises the work done by a TransferQueue.
+type TransferStats struct {
+	// Bytes is the number of bytes transferred, across all objects
+	Bytes int64
+	// Succeeded, Failed and Retried count unique OIDs. An object which was
+	// transferred finishes as either succeeded or failed, and may have been
+	// retried any number of times first. Objects which the API says need no
+	// transfer, and those abandoned by Drain, are counted as neither.
+	Succeeded int
+	Failed    int
+	Retried   int
+	// CacheStatuses counts the objects which succeeded by the cache status
+	// their adapter gave them, "hit" or "miss" usually. Objects with no
+	// known cache status aren't counted.
+	CacheStatuses map[string]int
+	// Duration is the time since the first object was handed to a transfer
+	// adapter, up to when the queue finished
+	Duration time.Duration
+}
+
+// batchSizer adapts the number of objects sent in each batch API request to how
+// long the requests take. Slow requests suggest a high-latency link, where
+// larger batches save round trips, while fast ones can be made smaller so that
+// transfers start sooner.
+type batchSizer struct {
+	min, max int
+	// slow and fast are the request durations above which the size
+	// doubles, and below which it halves
+	slow, fast time.Duration
+}
+
+// initial returns the batch size to start with.
+func (b *batchSizer) initial() int {
+	return b.clamp(batchSize)
+}
+
+// next returns the size for the next batch, given the current size and how
+// long the last request took. Latency is paid per request whatever its size,
+// so any slow request means batches should grow.
+func (b *batchSizer) next(current int, d time.Duration) int {
+	switch {
+	case d >= b.slow:
+		return b.clamp(current * 2)
+	case d < b.fast:
+		return b.clamp(current / 2)
+	default:
+		return current
+	}
+}
+
+func (b *batchSizer) clamp(size int) int {
+	if size < b.min {
+		return b.min
+	}
+	if size > b.max {
+		return b.max
+	}
+	return size
+}
+
+// TransferEventType is the kind of progress reported by a TransferEvent.
+type TransferEventType int
+
+const (
+	// TransferStarted is sent when an object is handed to a transfer
+	// adapter, which may be more than once if it is retried
+	TransferStarted TransferEventType = iota
+	// TransferBytes is sent as an object's content is transferred
+	TransferBytes
+	// TransferFinished is sent when an object has been transferred
+	TransferFinished
+	// TransferRetried is sent when an object will be retried after an error
+	TransferRetried
+	// TransferFailed is sent when an object's transfer failed for good
+	TransferFailed
+)
+
+// TransferEvent reports the progress of a single object through a
+// TransferQueue. See WithEventChannel.
+type TransferEvent struct {
+	Type TransferEventType
+	Oid  string
+	Name string
+	// BytesSoFar is how much of the object has been transferred, out of
+	// BytesTotal. They are only set for TransferBytes events.
+	BytesSoFar int64
+	BytesTotal int64
+	// Err is why the object failed. It is only set for TransferFailed
+	// events.
+	Err error
+	// CacheStatus is whether a cache answered the transfer, as given by
+	// the adapter. It is only set for TransferFinished events, and may be
+	// blank.
+	CacheStatus string
+}
+
+// TransferQueueOption configures a TransferQueue as it is built.
+type TransferQueueOption func(*TransferQueue)
+
+// WithContext makes the TransferQueue stop when ctx is cancelled. Objects
+// which haven't been handed to a transfer adapter yet, including any added
+// afterwards, are marked as failed without being transferred, and transfers
+// in progress are abandoned at their next progress update. Wait() still has
+// to be called, but will return promptly, and Errors() will then include the
+// reason the context was cancelled.
+func WithContext(ctx context.Context) TransferQueueOption {
+	return func(q *TransferQueue) {
+		q.ctx = ctx
+	}
+}
+
+// WithAdaptiveBatchSize makes the TransferQueue change the number of objects
+// sent in each batch API request, within the given bounds, according to how
+// long each request takes. Invalid bounds are ignored, leaving the batch size
+// fixed.
+func WithAdaptiveBatchSize(min, max int) TransferQueueOption {
+	return func(q *TransferQueue) {
+		if min < 1 || max < min {
+			tracerx.Printf("tq: invalid adaptive batch size bounds [%d, %d], ignoring", min, max)
+			return
+		}
+
+		q.batchSizer = &batchSizer{
+			min:  min,
+			max:  max,
+			slow: slowBatchDuration,
+			fast: fastBatchDuration,
+		}
+	}
+}
+
+// WithEventChannel makes the TransferQueue send a TransferEvent to c as each
+// object progresses. The queue never blocks on c: events which don't fit in
+// its buffer are dropped, so it should have a generous one. No events are sent
+// once Wait() returns, after which c can be closed.
+func WithEventChannel(c chan<- TransferEvent) TransferQueueOption {
+	return func(q *TransferQueue) {
+		q.events = c
+	}
+}
+
+// WithObjectTimeout limits how long a single object may take to transfer. An
+// object which takes longer is aborted, and once the adapter has given it up
+// it is treated as having failed with a retriable error, so it is retried if
+// it hasn't been too many times already. Zero, the default, means no limit.
+func WithObjectTimeout(d time.Duration) TransferQueueOption {
+	return func(q *TransferQueue) {
+		q.objectTimeout = d
+	}
+}
+
+// WithRemote makes the TransferQueue transfer objects as many at a time as is
+// configured for the named remote, rather than for the current remote.
+func WithRemote(name string) TransferQueueOption {
+	return func(q *TransferQueue) {
+		q.remote = name
+	}
+}
+
+// WithProgressOutput makes the TransferQueue draw its progress meter on w
+// instead of stdout, e.g. when stdout is reserved for machine-readable output.
+func WithProgressOutput(w io.Writer) TransferQueueOption {
+	return func(q *TransferQueue) {
+		q.meter.SetOutput(w)
+	}
+}
+
 // TransferQueue organises the wider process of uploading and downloading,
 // including calling the API, passing the actual transfer request to transfer
 // adapters, and dealing with progress, errors and retries.
@@ -104,6 +359,7 @@ type TransferQueue struct {
 	errors            []error
 	transferables     map[string]Transferable
 	batcher           *Batcher
+	batchSizer        *batchSizer       // nil if the batch size is fixed
 	apic              chan Transferable // Channel for processing individual API requests
 	retriesc          chan Transferable // Channel for processing retries
 	errorc            chan error        // Channel for processing errors
@@ -114,32 +370,89 @@ type TransferQueue struct {
 	// wait is used to keep track of pending transfers. It is incremented
 	// once per unique OID on Add(), and is decremented when that transfer
 	// is marked as completed or failed, but not retried.
-	wait          sync.WaitGroup
-	oldApiWorkers int // Number of non-batch API workers to spawn (deprecated)
+	wait sync.WaitGroup
+	// retryMutex serializes enqueueing retries, which may happen from
+	// several goroutines once backoff delays have elapsed
+	retryMutex sync.Mutex
+	// queued holds the OIDs of objects waiting to be sent to the API, which
+	// Drain abandons. It and draining are guarded by trMutex.
+	queued        map[string]bool
+	draining      bool
+	oldApiWorkers int    // Number of non-batch API workers to spawn (deprecated)
+	remote        string // remote whose lfs.<remote>.concurrenttransfers applies
+	workers       int    // number of transfers adapters run at once
 	manifest      *transfer.Manifest
 	rc            *retryCounter
+	skipEmpty     bool // satisfy zero-byte objects without transferring them
+	// largeObjectSize is the size from which objects are sent to the API
+	// in batches of their own, or 0 to never split them out
+	largeObjectSize int64
+	ctx             context.Context
+	events          chan<- TransferEvent
+	// eventOids maps the names of transfers given to the adapter to their
+	// OIDs, for events about their progress. Guarded by trMutex.
+	eventOids map[string]string
+	// adapterUsage counts the objects transferred by each adapter, by
+	// name. Guarded by trMutex.
+	adapterUsage map[string]int
+	// objectTimeout is set by WithObjectTimeout, timers holds the timeouts
+	// of the transfers the adapter is working on, and timedOut those which
+	// have been aborted for taking too long. Guarded by trMutex.
+	objectTimeout time.Duration
+	timers        map[*transfer.Transfer]*time.Timer
+	timedOut      map[*transfer.Transfer]bool
+	// cancelOnce reports the cancellation of ctx once, however many
+	// objects it stops
+	cancelOnce sync.Once
+	// stats, started and finished are guarded by trMutex
+	stats    TransferStats
+	started  time.Time
+	finished time.Time
+	// pending holds the OIDs of objects which have been added but not yet
+	// completed, and inFlight those of objects with the adapter. Both are
+	// guarded by trMutex.
+	pending  map[string]bool
+	inFlight map[string]bool
 }
 
 // newTransferQueue builds a TransferQueue, direction and underlying mechanism determined by adapter
-func newTransferQueue(files int, size int64, dryRun bool, dir transfer.Direction) *TransferQueue {
+func newTransferQueue(files int, size int64, dryRun bool, dir transfer.Direction, options ...TransferQueueOption) *TransferQueue {
 	cfg := config.Config
 
 	logPath, _ := cfg.Os.Get("GIT_LFS_PROGRESS")
 
 	q := &TransferQueue{
-		direction:     dir,
-		dryRun:        dryRun,
-		meter:         progress.NewProgressMeter(files, size, dryRun, logPath),
-		apic:          make(chan Transferable, batchSize),
-		retriesc:      make(chan Transferable, batchSize),
-		errorc:        make(chan error),
-		oldApiWorkers: config.Config.ConcurrentTransfers(),
-		transferables: make(map[string]Transferable),
-		trMutex:       &sync.Mutex{},
-		manifest:      transfer.ConfigureManifest(transfer.NewManifest(), config.Config),
-		rc:            newRetryCounter(cfg),
+		direction:       dir,
+		dryRun:          dryRun,
+		meter:           progress.NewProgressMeter(files, size, dryRun, logPath),
+		apic:            make(chan Transferable, batchSize),
+		retriesc:        make(chan Transferable, batchSize),
+		errorc:          make(chan error),
+		remote:          cfg.CurrentRemote,
+		transferables:   make(map[string]Transferable),
+		queued:          make(map[string]bool),
+		pending:         make(map[string]bool),
+		inFlight:        make(map[string]bool),
+		eventOids:       make(map[string]string),
+		adapterUsage:    make(map[string]int),
+		timers:          make(map[*transfer.Transfer]*time.Timer),
+		timedOut:        make(map[*transfer.Transfer]bool),
+		trMutex:         &sync.Mutex{},
+		manifest:        transfer.ConfigureManifest(transfer.NewManifest(), config.Config),
+		rc:              newRetryCounter(cfg),
+		skipEmpty:       cfg.SkipEmptyObjects(dir == transfer.Upload),
+		largeObjectSize: cfg.TransferLargeObjectSize(),
+		ctx:             context.Background(),
 	}
 
+	for _, opt := range options {
+		opt(q)
+	}
+
+	q.meter.SetRefreshInterval(cfg.ProgressRefreshInterval())
+	q.workers = cfg.RemoteConcurrentTransfers(q.remote)
+	q.oldApiWorkers = q.workers
+
 	q.errorwait.Add(1)
 	q.retrywait.Add(1)
 
@@ -155,6 +468,7 @@ func (q *TransferQueue) Add(t Transferable) {
 	if _, ok := q.transferables[t.Oid()]; !ok {
 		q.wait.Add(1)
 		q.transferables[t.Oid()] = t
+		q.pending[t.Oid()] = true
 		q.trMutex.Unlock()
 	} else {
 		tracerx.Printf("already transferring %q, skipping duplicate", t)
@@ -162,6 +476,20 @@ func (q *TransferQueue) Add(t Transferable) {
 		return
 	}
 
+	if q.cancelled() {
+		q.cancelObject(t.Oid(), t.Size())
+		return
+	}
+
+	if q.skipEmpty && IsEmptyObject(t.Oid(), t.Size()) {
+		q.completeEmptyObject(t)
+		return
+	}
+
+	if !q.enqueue(t) {
+		return
+	}
+
 	if q.batcher != nil {
 		q.batcher.Add(t)
 		return
@@ -199,22 +527,89 @@ func (q *TransferQueue) finishAdapter() {
 func (q *TransferQueue) addToAdapter(t Transferable) {
 	tr := transfer.NewTransfer(t.Name(), t.Object(), t.Path())
 
+	q.trMutex.Lock()
+	if q.started.IsZero() {
+		q.started = time.Now()
+	}
+	if q.events != nil {
+		q.eventOids[t.Name()] = t.Oid()
+	}
+	q.inFlight[t.Oid()] = true
+	q.trMutex.Unlock()
+
+	q.emit(TransferEvent{Type: TransferStarted, Oid: t.Oid(), Name: t.Name()})
+
+	if q.cancelled() {
+		q.landed(t.Oid())
+		q.cancelObject(t.Oid(), t.Size())
+		return
+	}
+
 	if q.dryRun {
 		// Don't actually transfer
 		res := transfer.TransferResult{tr, nil}
-		q.handleTransferResult(res)
+		q.handleTransferResult(res, "")
 		return
 	}
 	err := q.ensureAdapterBegun()
 	if err != nil {
+		q.landed(t.Oid())
 		q.errorc <- err
 		q.Skip(t.Size())
-		q.wait.Done()
+		q.countFailed()
+		q.markDone(t.Oid())
 		return
 	}
+	q.startObjectTimer(tr)
 	q.adapter.Add(tr)
 }
 
+// startObjectTimer aborts tr if the adapter hasn't finished it within the
+// timeout given by WithObjectTimeout. The object isn't retried until the
+// adapter gives tr up, so that two workers never write the same object.
+func (q *TransferQueue) startObjectTimer(tr *transfer.Transfer) {
+	if q.objectTimeout <= 0 {
+		return
+	}
+
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	q.timers[tr] = time.AfterFunc(q.objectTimeout, func() {
+		q.trMutex.Lock()
+		_, running := q.timers[tr]
+		if running {
+			delete(q.timers, tr)
+			q.timedOut[tr] = true
+		}
+		q.trMutex.Unlock()
+
+		if running {
+			tracerx.Printf("tq: %q timed out after %v, aborting", tr.Object.Oid, q.objectTimeout)
+			tr.Abort()
+		}
+	})
+}
+
+// settleTransfer stops the timeout for tr, if any. It returns true if tr was
+// aborted because it timed out.
+func (q *TransferQueue) settleTransfer(tr *transfer.Transfer) bool {
+	if q.objectTimeout <= 0 || q.dryRun {
+		return false
+	}
+
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	if timer, ok := q.timers[tr]; ok {
+		timer.Stop()
+		delete(q.timers, tr)
+	}
+	timedOut := q.timedOut[tr]
+	delete(q.timedOut, tr)
+	return timedOut
+}
+
 func (q *TransferQueue) Skip(size int64) {
 	q.meter.Skip(size)
 }
@@ -240,23 +635,34 @@ func (q *TransferQueue) ensureAdapterBegun() error {
 	// Progress callback - receives byte updates
 	cb := func(name string, total, read int64, current int) error {
 		q.meter.TransferBytes(q.transferKind(), name, read, total, current)
-		return nil
+
+		q.trMutex.Lock()
+		q.stats.Bytes += int64(current)
+		oid := q.eventOids[name]
+		q.trMutex.Unlock()
+
+		q.emit(TransferEvent{Type: TransferBytes, Oid: oid, Name: name, BytesSoFar: read, BytesTotal: total})
+
+		// Abandons the transfer if the queue has been cancelled
+		return q.ctx.Err()
 	}
 
 	tracerx.Printf("tq: starting transfer adapter %q", q.adapter.Name())
-	err := q.adapter.Begin(config.Config.ConcurrentTransfers(), cb, adapterResultChan)
+	err := q.adapter.Begin(q.workers, cb, adapterResultChan)
 	if err != nil {
 		return err
 	}
 	q.adapterInProgress = true
 
 	// Collector for completed transfers
-	// q.wait.Done() in handleTransferResult is enough to know when this is complete for all transfers
-	go func() {
+	// q.markDone() in handleTransferResult is enough to know when this is complete for all transfers
+	// The adapter may be switched before all of its results are collected,
+	// so remember which one they came from
+	go func(adapterName string) {
 		for res := range adapterResultChan {
-			q.handleTransferResult(res)
+			q.handleTransferResult(res, adapterName)
 		}
-	}()
+	}(q.adapter.Name())
 
 	return nil
 }
@@ -270,10 +676,27 @@ func (q *TransferQueue) ensureAdapterBegun() error {
 // transfer will be marked as having failed, and the error will be reported.
 //
 // If the transfer was successful, the watchers of this transfer queue will be
-// notified, and the transfer will be marked as having been completed.
-func (q *TransferQueue) handleTransferResult(res transfer.TransferResult) {
+// notified, and the transfer will be marked as having been completed, and
+// counted against the adapter which made it, "adapterName", if any.
+func (q *TransferQueue) handleTransferResult(res transfer.TransferResult, adapterName string) {
 	oid := res.Transfer.Object.Oid
 
+	if q.settleTransfer(res.Transfer) && res.Error != nil {
+		res.Error = errors.NewRetriableError(errors.Errorf("Git LFS: %v timed out after %v", res.Transfer.Name, q.objectTimeout))
+	}
+
+	q.landed(oid)
+
+	if res.Error != nil {
+		// A retry starts the file over, so stop showing its progress either way
+		q.meter.AbortTransfer(res.Transfer.Name)
+	}
+
+	if res.Error != nil && q.cancelled() {
+		q.cancelObject(oid, 0)
+		return
+	}
+
 	if res.Error != nil {
 		if q.canRetryObject(oid, res.Error) {
 			tracerx.Printf("tq: retrying object %s", oid)
@@ -284,18 +707,37 @@ func (q *TransferQueue) handleTransferResult(res transfer.TransferResult) {
 				q.retry(t)
 			} else {
 				q.errorc <- res.Error
+				q.countFailed()
+				q.emit(TransferEvent{Type: TransferFailed, Oid: oid, Name: res.Transfer.Name, Err: res.Error})
 			}
 		} else {
 			q.errorc <- res.Error
-			q.wait.Done()
+			q.countFailed()
+			q.emit(TransferEvent{Type: TransferFailed, Oid: oid, Name: res.Transfer.Name, Err: res.Error})
+			q.markDone(oid)
 		}
 	} else {
+		q.trMutex.Lock()
+		q.stats.Succeeded++
+		if len(adapterName) > 0 {
+			q.adapterUsage[adapterName]++
+		}
+		if status := res.Transfer.CacheStatus; len(status) > 0 {
+			if q.stats.CacheStatuses == nil {
+				q.stats.CacheStatuses = make(map[string]int)
+			}
+			q.stats.CacheStatuses[status]++
+		}
+		q.trMutex.Unlock()
+
+		q.emit(TransferEvent{Type: TransferFinished, Oid: oid, Name: res.Transfer.Name, CacheStatus: res.Transfer.CacheStatus})
+
 		for _, c := range q.watchers {
 			c <- oid
 		}
 
 		q.meter.FinishTransfer(res.Transfer.Name)
-		q.wait.Done()
+		q.markDone(oid)
 	}
 }
 
@@ -309,6 +751,10 @@ func (q *TransferQueue) Wait() {
 
 	q.wait.Wait()
 
+	q.trMutex.Lock()
+	q.finished = time.Now()
+	q.trMutex.Unlock()
+
 	// Handle any retries
 	close(q.retriesc)
 	q.retrywait.Wait()
@@ -323,6 +769,238 @@ func (q *TransferQueue) Wait() {
 
 	q.meter.Finish()
 	q.errorwait.Wait()
+
+	for name, count := range q.AdapterUsage() {
+		tracerx.Printf("tq: %d object(s) transferred by adapter %q", count, name)
+	}
+}
+
+// Drain abandons every object which hasn't been sent to the API yet, including
+// any added afterwards, and returns their OIDs. Transfers which have already
+// started are left to finish. Abandoned objects are neither succeeded nor
+// failed, and no errors are reported for them. Wait() must still be called.
+// It is safe to call concurrently with Add().
+func (q *TransferQueue) Drain() []string {
+	q.trMutex.Lock()
+	q.draining = true
+	abandoned := make([]Transferable, 0, len(q.queued))
+	for oid := range q.queued {
+		abandoned = append(abandoned, q.transferables[oid])
+	}
+	q.queued = make(map[string]bool)
+	q.trMutex.Unlock()
+
+	tracerx.Printf("tq: draining, abandoning %d queued object(s)", len(abandoned))
+
+	oids := make([]string, 0, len(abandoned))
+	for _, t := range abandoned {
+		q.Skip(t.Size())
+		q.markDone(t.Oid())
+		oids = append(oids, t.Oid())
+	}
+	sort.Strings(oids)
+	return oids
+}
+
+// enqueue records that t is waiting to be sent to the API. If the queue has
+// been drained, t is abandoned instead, and enqueue returns false.
+func (q *TransferQueue) enqueue(t Transferable) bool {
+	q.trMutex.Lock()
+	draining := q.draining
+	if !draining {
+		q.queued[t.Oid()] = true
+	}
+	q.trMutex.Unlock()
+
+	if draining {
+		q.abandon(t)
+		return false
+	}
+	return true
+}
+
+// abandon drops an object which arrived after the queue was drained.
+func (q *TransferQueue) abandon(t Transferable) {
+	tracerx.Printf("tq: drained, abandoning %q", t.Oid())
+	q.Skip(t.Size())
+	q.markDone(t.Oid())
+}
+
+// claim takes an object off the waiting list as it's about to be sent to the
+// API. It returns false if Drain abandoned the object first, in which case it
+// must be ignored.
+func (q *TransferQueue) claim(oid string) bool {
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	if !q.queued[oid] {
+		return false
+	}
+	delete(q.queued, oid)
+	return true
+}
+
+// Stats returns a summary of the transfers made so far. After Wait returns,
+// it covers every object added to the queue.
+func (q *TransferQueue) Stats() TransferStats {
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	stats := q.stats
+	if q.stats.CacheStatuses != nil {
+		stats.CacheStatuses = make(map[string]int, len(q.stats.CacheStatuses))
+		for status, n := range q.stats.CacheStatuses {
+			stats.CacheStatuses[status] = n
+		}
+	}
+	if !q.started.IsZero() {
+		end := q.finished
+		if end.IsZero() {
+			end = time.Now()
+		}
+		stats.Duration = end.Sub(q.started)
+	}
+	return stats
+}
+
+// completeEmptyObject marks the zero-byte object as transferred without asking
+// the API about it. Downloads only need the empty file creating locally, and
+// uploads are never needed since every client can do the same.
+func (q *TransferQueue) completeEmptyObject(t Transferable) {
+	tracerx.Printf("tq: %q is empty, not transferring", t.Name())
+
+	if q.direction == transfer.Download && !q.dryRun {
+		if err := WriteEmptyObject(); err != nil {
+			q.errorc <- errors.Wrapf(err, "Error creating empty object for %v", t.Name())
+			q.countFailed()
+			q.markDone(t.Oid())
+			return
+		}
+	}
+
+	q.trMutex.Lock()
+	q.stats.Succeeded++
+	q.trMutex.Unlock()
+
+	for _, c := range q.watchers {
+		c <- t.Oid()
+	}
+
+	q.Skip(0)
+	q.markDone(t.Oid())
+}
+
+// cancelled returns whether the queue's context has been cancelled.
+func (q *TransferQueue) cancelled() bool {
+	return q.ctx.Err() != nil
+}
+
+// cancelObject marks an object as failed because the queue was cancelled,
+// reporting the cancellation as an error the first time.
+func (q *TransferQueue) cancelObject(oid string, size int64) {
+	q.cancelOnce.Do(func() {
+		q.errorc <- errors.Wrap(q.ctx.Err(), "transfer cancelled")
+	})
+	q.Skip(size)
+	q.countFailed()
+	q.markDone(oid)
+}
+
+// emit sends an event to the channel given by WithEventChannel, if any,
+// dropping it rather than waiting if the channel is full.
+func (q *TransferQueue) emit(e TransferEvent) {
+	if q.events == nil {
+		return
+	}
+
+	select {
+	case q.events <- e:
+	default:
+		tracerx.Printf("tq: event channel full, dropping event for %q", e.Oid)
+	}
+}
+
+// AdapterUsage returns how many objects each transfer adapter has transferred,
+// by adapter name. The server chooses the adapter for each batch, so more than
+// one may be used, for example if some objects fall back to "basic". Objects
+// which didn't need transferring, or failed, aren't counted.
+func (q *TransferQueue) AdapterUsage() map[string]int {
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	usage := make(map[string]int, len(q.adapterUsage))
+	for name, count := range q.adapterUsage {
+		usage[name] = count
+	}
+	return usage
+}
+
+// RetrySummary returns how many times each object which needed retrying was
+// retried, by OID. Objects which succeeded or failed at the first attempt
+// aren't included.
+func (q *TransferQueue) RetrySummary() map[string]int {
+	return q.rc.Counts()
+}
+
+// Pending returns the OIDs of the objects which have been added to the queue
+// but haven't yet succeeded, failed or been abandoned, in sorted order. It is
+// empty once Wait returns.
+func (q *TransferQueue) Pending() []string {
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	return sortedKeys(q.pending)
+}
+
+// InFlight returns the OIDs of the objects which have been handed to the
+// transfer adapter, and whose results haven't come back yet, in sorted order.
+// They are all also Pending.
+func (q *TransferQueue) InFlight() []string {
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	return sortedKeys(q.inFlight)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// landed records that the adapter has given back the object with the given
+// OID, whether or not it was transferred.
+func (q *TransferQueue) landed(oid string) {
+	q.trMutex.Lock()
+	delete(q.inFlight, oid)
+	q.trMutex.Unlock()
+}
+
+// markDone records that the object with the given OID has succeeded, failed
+// or been abandoned, and stops waiting for it.
+func (q *TransferQueue) markDone(oid string) {
+	q.trMutex.Lock()
+	delete(q.pending, oid)
+	q.trMutex.Unlock()
+
+	q.wait.Done()
+}
+
+// countFailed records that an object has failed and won't be retried.
+func (q *TransferQueue) countFailed() {
+	q.trMutex.Lock()
+	q.stats.Failed++
+	q.trMutex.Unlock()
+}
+
+// SetVerbose makes the progress meter show a line for each file being
+// transferred, as well as the overall progress. It must be called before any
+// transfers are added.
+func (q *TransferQueue) SetVerbose(verbose bool) {
+	q.meter.SetVerbose(verbose)
 }
 
 // Watch returns a channel where the queue will write the OID of each transfer
@@ -340,13 +1018,23 @@ func (q *TransferQueue) Watch() chan string {
 // TODO LEGACY API: remove when legacy API removed
 func (q *TransferQueue) individualApiRoutine(apiWaiter chan interface{}) {
 	for t := range q.apic {
+		if !q.claim(t.Oid()) {
+			continue
+		}
+
+		if q.cancelled() {
+			q.cancelObject(t.Oid(), t.Size())
+			continue
+		}
+
 		obj, err := t.LegacyCheck()
 		if err != nil {
 			if q.canRetryObject(t.Oid(), err) {
 				q.retry(t)
 			} else {
 				q.errorc <- err
-				q.wait.Done()
+				q.countFailed()
+				q.markDone(t.Oid())
 			}
 			continue
 		}
@@ -367,7 +1055,7 @@ func (q *TransferQueue) individualApiRoutine(apiWaiter chan interface{}) {
 			q.addToAdapter(t)
 		} else {
 			q.Skip(t.Size())
-			q.wait.Done()
+			q.markDone(t.Oid())
 		}
 	}
 }
@@ -376,13 +1064,17 @@ func (q *TransferQueue) individualApiRoutine(apiWaiter chan interface{}) {
 // not support the batch endpoint. When this happens, the Transferables are
 // fed from the batcher into apic to be processed individually.
 // TODO LEGACY API: remove when legacy API removed
-func (q *TransferQueue) legacyFallback(failedBatch []interface{}) {
+func (q *TransferQueue) legacyFallback(failedBatch []Transferable) {
 	tracerx.Printf("tq: batch api not implemented, falling back to individual")
 
 	q.launchIndividualApiRoutines()
 
 	for _, t := range failedBatch {
-		q.apic <- t.(Transferable)
+		// These were claimed for the failed batch request, so put them
+		// back on the waiting list for the individual API routines
+		if q.enqueue(t) {
+			q.apic <- t
+		}
 	}
 
 	for {
@@ -403,80 +1095,203 @@ func (q *TransferQueue) legacyFallback(failedBatch []interface{}) {
 func (q *TransferQueue) batchApiRoutine() {
 	var startProgress sync.Once
 
-	transferAdapterNames := q.manifest.GetAdapterNames(q.direction)
-
 	for {
 		batch := q.batcher.Next()
 		if batch == nil {
 			break
 		}
 
-		tracerx.Printf("tq: sending batch of size %d", len(batch))
-
-		transfers := make([]*api.ObjectResource, 0, len(batch))
+		// Servers answer in the order objects are requested, so sorting
+		// here decides which transfers start first
+		sorted := make(byPriority, 0, len(batch))
 		for _, i := range batch {
-			t := i.(Transferable)
-			transfers = append(transfers, &api.ObjectResource{Oid: t.Oid(), Size: t.Size()})
+			if t := i.(Transferable); q.claim(t.Oid()) {
+				sorted = append(sorted, t)
+			}
 		}
+		sort.Stable(sorted)
 
-		if len(transfers) == 0 {
+		if len(sorted) == 0 {
 			continue
 		}
 
-		objs, adapterName, err := api.Batch(config.Config, transfers, q.transferKind(), transferAdapterNames)
-		if err != nil {
-			if errors.IsNotImplementedError(err) {
-				git.Config.SetLocal("", "lfs.batch", "false")
-				go q.legacyFallback(batch)
+		// Large objects are requested on their own, so that their
+		// transfers start without waiting for the rest of the batch
+		var batches []*adapterBatch
+		for _, b := range splitLargeObjects(sorted, q.largeObjectSize) {
+			batches = append(batches, q.splitByAdapter(b)...)
+		}
+
+		for i, b := range batches {
+			if !q.sendBatch(b.objects, b.adapterNames, &startProgress) {
+				// The batch API isn't supported, so the rest
+				// go to the legacy API too
+				failed := b.objects
+				for _, rest := range batches[i+1:] {
+					failed = append(failed, rest.objects...)
+				}
+				go q.legacyFallback(failed)
 				return
 			}
+		}
+	}
+}
 
-			var errOnce sync.Once
-			for _, o := range batch {
-				t := o.(Transferable)
+// splitLargeObjects splits a sorted batch so that each object of at least
+// threshold bytes is on its own, keeping the order of the objects. Runs of
+// smaller objects stay together. A threshold of 0 leaves the batch as is.
+func splitLargeObjects(batch []Transferable, threshold int64) [][]Transferable {
+	if threshold <= 0 {
+		return [][]Transferable{batch}
+	}
 
-				if q.canRetryObject(t.Oid(), err) {
-					q.retry(t)
-				} else {
-					errOnce.Do(func() { q.errorc <- err })
-					q.wait.Done()
-				}
+	var batches [][]Transferable
+	var small []Transferable
+	for _, t := range batch {
+		if t.Size() < threshold {
+			small = append(small, t)
+			continue
+		}
+
+		if len(small) > 0 {
+			batches = append(batches, small)
+			small = nil
+		}
+		batches = append(batches, []Transferable{t})
+	}
+	if len(small) > 0 {
+		batches = append(batches, small)
+	}
+	return batches
+}
+
+// adapterBatch is a batch of objects to send to the API, along with the names
+// of the transfer adapters to offer the server for them.
+type adapterBatch struct {
+	objects      []Transferable
+	adapterNames []string
+}
+
+// splitByAdapter splits a batch into groups of objects for which the same
+// transfer adapters can be used, since the server picks one adapter for each
+// request. This keeps e.g. small objects from being uploaded with tus. The
+// order of the objects is kept within each group.
+func (q *TransferQueue) splitByAdapter(batch []Transferable) []*adapterBatch {
+	var batches []*adapterBatch
+	groups := make(map[string]*adapterBatch)
+	for _, t := range batch {
+		names := q.manifest.GetAdapterNamesForSize(q.direction, t.Size())
+		key := strings.Join(names, ",")
+
+		b, ok := groups[key]
+		if !ok {
+			b = &adapterBatch{adapterNames: names}
+			groups[key] = b
+			batches = append(batches, b)
+		}
+		b.objects = append(b.objects, t)
+	}
+	return batches
+}
+
+// sendBatch requests the given objects from the batch API, and hands those
+// which need transferring to the adapter. It returns false, without handling
+// any of the objects, if the server doesn't implement the batch API.
+func (q *TransferQueue) sendBatch(batch []Transferable, transferAdapterNames []string, startProgress *sync.Once) bool {
+	if q.cancelled() {
+		q.cancelBatch(batch)
+		return true
+	}
+
+	tracerx.Printf("tq: sending batch of size %d", len(batch))
+
+	transfers := make([]*api.ObjectResource, 0, len(batch))
+	for _, t := range batch {
+		transfers = append(transfers, &api.ObjectResource{Oid: t.Oid(), Size: t.Size()})
+	}
+
+	requested := time.Now()
+	objs, adapterName, err := api.Batch(config.Config, transfers, q.transferKind(), transferAdapterNames)
+	if err != nil {
+		if errors.IsNotImplementedError(err) {
+			git.Config.SetLocal("", "lfs.batch", "false")
+			return false
+		}
+
+		var errOnce sync.Once
+		for _, t := range batch {
+			if q.canRetryObject(t.Oid(), err) {
+				q.retry(t)
+			} else {
+				errOnce.Do(func() { q.errorc <- err })
+				q.countFailed()
+				q.markDone(t.Oid())
 			}
+		}
 
-			continue
+		return true
+	}
+
+	if q.cancelled() {
+		q.cancelBatch(batch)
+		return true
+	}
+
+	if q.batchSizer != nil {
+		elapsed := time.Since(requested)
+		current := q.batcher.BatchSize()
+		if size := q.batchSizer.next(current, elapsed); size != current {
+			tracerx.Printf("tq: batch of %d took %v, changing batch size to %d", len(transfers), elapsed, size)
+			q.batcher.SetBatchSize(size)
 		}
+	}
 
-		q.useAdapter(adapterName)
-		startProgress.Do(q.meter.Start)
+	q.useAdapter(adapterName)
+	startProgress.Do(q.meter.Start)
 
-		for _, o := range objs {
-			if o.Error != nil {
-				q.errorc <- errors.Wrapf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
-				q.Skip(o.Size)
-				q.wait.Done()
-				continue
+	for _, o := range objs {
+		if o.Error != nil {
+			err := errors.Wrapf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+			if q.direction == transfer.Download && (o.Error.Code == 404 || o.Error.Code == 410) {
+				err = errors.NewObjectMissingError(err, o.Oid)
 			}
+			q.errorc <- err
+			q.Skip(o.Size)
+			q.countFailed()
+			q.markDone(o.Oid)
+			continue
+		}
 
-			if _, ok := o.Rel(q.transferKind()); ok {
-				// This object needs to be transferred
-				q.trMutex.Lock()
-				transfer, ok := q.transferables[o.Oid]
-				q.trMutex.Unlock()
-
-				if ok {
-					transfer.SetObject(o)
-					q.meter.Add(transfer.Name())
-					q.addToAdapter(transfer)
-				} else {
-					q.Skip(transfer.Size())
-					q.wait.Done()
-				}
+		if _, ok := o.Rel(q.transferKind()); ok {
+			// This object needs to be transferred
+			q.trMutex.Lock()
+			transfer, ok := q.transferables[o.Oid]
+			q.trMutex.Unlock()
+
+			if ok {
+				transfer.SetObject(o)
+				q.meter.Add(transfer.Name())
+				q.addToAdapter(transfer)
 			} else {
-				q.Skip(o.Size)
-				q.wait.Done()
+				q.Skip(transfer.Size())
+				q.markDone(o.Oid)
 			}
+		} else {
+			q.Skip(o.Size)
+			q.markDone(o.Oid)
 		}
 	}
+
+	return true
+}
+
+// cancelBatch marks every object in a batch as failed because the queue was
+// cancelled.
+func (q *TransferQueue) cancelBatch(batch []Transferable) {
+	tracerx.Printf("tq: cancelled, dropping batch of size %d", len(batch))
+	for _, t := range batch {
+		q.cancelObject(t.Oid(), t.Size())
+	}
 }
 
 // This goroutine collects errors returned from transfers
@@ -498,23 +1313,47 @@ func (q *TransferQueue) retryCollector() {
 		q.rc.Increment(t.Oid())
 		count := q.rc.CountFor(t.Oid())
 
-		tracerx.Printf("tq: enqueue retry #%d for %q (size: %d)", count, t.Oid(), t.Size())
-
-		// XXX(taylor): reuse some of the logic in
-		// `*TransferQueue.Add(t)` here to circumvent banned duplicate
-		// OIDs
-		if q.batcher != nil {
-			tracerx.Printf("tq: flushing batch in response to retry #%d for %q (size: %d)", count, t.Oid(), t.Size())
-
-			q.batcher.Add(t)
-			q.batcher.Flush()
-		} else {
-			q.apic <- t
+		// Back off for this object only. Its pending transfer keeps
+		// q.wait from completing, so Wait() can't return before the
+		// delayed retry is enqueued.
+		if delay := q.rc.BackoffFor(t.Oid()); delay > 0 {
+			tracerx.Printf("tq: delaying retry #%d for %q by %v", count, t.Oid(), delay)
+			go func(t Transferable, count int) {
+				select {
+				case <-time.After(delay):
+				case <-q.ctx.Done():
+				}
+				q.enqueueRetry(t, count)
+			}(t, count)
+			continue
 		}
+
+		q.enqueueRetry(t, count)
 	}
 	q.retrywait.Done()
 }
 
+// enqueueRetry adds a transfer being retried to the next batch, or legacy API
+// channel.
+func (q *TransferQueue) enqueueRetry(t Transferable, count int) {
+	q.retryMutex.Lock()
+	defer q.retryMutex.Unlock()
+
+	tracerx.Printf("tq: enqueue retry #%d for %q (size: %d)", count, t.Oid(), t.Size())
+
+	// XXX(taylor): reuse some of the logic in
+	// `*TransferQueue.Add(t)` here to circumvent banned duplicate
+	// OIDs
+	if q.batcher != nil {
+		tracerx.Printf("tq: flushing batch in response to retry #%d for %q (size: %d)", count, t.Oid(), t.Size())
+
+		q.batcher.Add(t)
+		q.batcher.Flush()
+	} else {
+		q.apic <- t
+	}
+}
+
 // launchIndividualApiRoutines first launches a single api worker. When it
 // receives the first successful api request it launches workers - 1 more
 // workers. This prevents being prompted for credentials multiple times at once
@@ -534,14 +1373,19 @@ func (q *TransferQueue) launchIndividualApiRoutines() {
 
 // run starts the transfer queue, doing individual or batch transfers depending
 // on the Config.BatchTransfer() value. run will transfer files sequentially or
-// concurrently depending on the Config.ConcurrentTransfers() value.
+// concurrently depending on the Config.RemoteConcurrentTransfers() value.
 func (q *TransferQueue) run() {
 	go q.errorCollector()
 	go q.retryCollector()
 
 	if config.Config.BatchTransfer() {
-		tracerx.Printf("tq: running as batched queue, batch size of %d", batchSize)
-		q.batcher = NewBatcher(batchSize)
+		size := batchSize
+		if q.batchSizer != nil {
+			size = q.batchSizer.initial()
+		}
+
+		tracerx.Printf("tq: running as batched queue, batch size of %d", size)
+		q.batcher = NewBatcher(size)
 		go q.batchApiRoutine()
 	} else {
 		tracerx.Printf("tq: running as individual queue")
@@ -549,7 +1393,32 @@ func (q *TransferQueue) run() {
 	}
 }
 
+// retry queues an object to be transferred again. An object which is already
+// waiting to be retried isn't queued twice, since it only has one pending
+// transfer in q.wait.
 func (q *TransferQueue) retry(t Transferable) {
+	q.trMutex.Lock()
+	duplicate := q.queued[t.Oid()]
+	draining := q.draining
+	if !duplicate && !draining {
+		q.queued[t.Oid()] = true
+		// Count objects, not attempts
+		if q.rc.CountFor(t.Oid()) == 0 {
+			q.stats.Retried++
+		}
+	}
+	q.trMutex.Unlock()
+
+	if duplicate {
+		tracerx.Printf("tq: %q is already waiting to be retried, skipping duplicate", t.Oid())
+		return
+	}
+	if draining {
+		q.abandon(t)
+		return
+	}
+
+	q.emit(TransferEvent{Type: TransferRetried, Oid: t.Oid(), Name: t.Name()})
 	q.retriesc <- t
 }
 
@@ -1,10 +1,24 @@
 package lfs
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/git-lfs/git-lfs/api"
 	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/transfer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRetryCounterDefaultsToFixedRetries(t *testing.T) {
@@ -60,3 +74,929 @@ func TestRetryCounterCanNotRetryAfterExceedingRetryCount(t *testing.T) {
 	assert.Equal(t, 1, count)
 	assert.False(t, canRetry)
 }
+
+func TestRetryCounterBackoffDefaultsToOff(t *testing.T) {
+	rc := newRetryCounter(config.NewFrom(config.Values{}))
+
+	rc.Increment("oid")
+
+	assert.Equal(t, time.Duration(0), rc.BackoffFor("oid"))
+}
+
+func TestRetryCounterIgnoresInvalidBackoff(t *testing.T) {
+	for _, v := range []string{"soon", "-1s", "10"} {
+		rc := newRetryCounter(config.NewFrom(config.Values{
+			Git: map[string]string{
+				"lfs.transfer.retrybackoff": v,
+			},
+		}))
+
+		rc.Increment("oid")
+
+		assert.Equal(t, time.Duration(0), rc.BackoffFor("oid"), v)
+	}
+}
+
+func TestRetryCounterBackoffGrowsPerObject(t *testing.T) {
+	rc := newRetryCounter(config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.transfer.retrybackoff": "100ms",
+		},
+	}))
+
+	rc.Increment("a")
+	first := rc.BackoffFor("a")
+	assert.True(t, first >= 100*time.Millisecond && first <= 110*time.Millisecond, "first backoff: %v", first)
+
+	rc.Increment("a")
+	second := rc.BackoffFor("a")
+	assert.True(t, second >= 200*time.Millisecond && second <= 220*time.Millisecond, "second backoff: %v", second)
+
+	// other objects are unaffected by "a" failing
+	rc.Increment("b")
+	other := rc.BackoffFor("b")
+	assert.True(t, other >= 100*time.Millisecond && other <= 110*time.Millisecond, "other backoff: %v", other)
+}
+
+// retryTransferable is a Transferable with only an OID, for exercising the
+// TransferQueue without touching local storage.
+type retryTransferable struct {
+	oid string
+	obj *api.ObjectResource
+}
+
+func (r *retryTransferable) Oid() string                               { return r.oid }
+func (r *retryTransferable) Size() int64                               { return 0 }
+func (r *retryTransferable) Name() string                              { return r.oid }
+func (r *retryTransferable) Path() string                              { return "" }
+func (r *retryTransferable) Object() *api.ObjectResource               { return r.obj }
+func (r *retryTransferable) SetObject(o *api.ObjectResource)           { r.obj = o }
+func (r *retryTransferable) LegacyCheck() (*api.ObjectResource, error) { return nil, nil }
+
+func newRetryQueue(backoff string) *TransferQueue {
+	q := &TransferQueue{
+		apic:     make(chan Transferable, 10),
+		retriesc: make(chan Transferable, 10),
+		queued:   make(map[string]bool),
+		trMutex:  &sync.Mutex{},
+		ctx:      context.Background(),
+		meter:    progress.NewProgressMeter(0, 0, true, ""),
+		rc: newRetryCounter(config.NewFrom(config.Values{
+			Git: map[string]string{
+				"lfs.transfer.retrybackoff": backoff,
+			},
+		})),
+	}
+	q.retrywait.Add(1)
+	go q.retryCollector()
+	return q
+}
+
+func TestTransferQueueDelaysRetryByBackoff(t *testing.T) {
+	q := newRetryQueue("50ms")
+	defer close(q.retriesc)
+
+	start := time.Now()
+	q.retry(&retryTransferable{oid: "oid"})
+
+	select {
+	case tr := <-q.apic:
+		assert.Equal(t, "oid", tr.Oid())
+		assert.True(t, time.Since(start) >= 50*time.Millisecond, "retried after %v", time.Since(start))
+	case <-time.After(time.Second):
+		t.Fatal("retry was never enqueued")
+	}
+}
+
+func TestTransferQueueBackoffIsPerObject(t *testing.T) {
+	q := newRetryQueue("20ms")
+	defer close(q.retriesc)
+
+	// "slow" has already been retried several times, so waits much longer
+	for i := 0; i < 4; i++ {
+		q.rc.Increment("slow")
+	}
+
+	q.retry(&retryTransferable{oid: "slow"})
+	q.retry(&retryTransferable{oid: "fast"})
+
+	first := <-q.apic
+	second := <-q.apic
+	assert.Equal(t, "fast", first.Oid())
+	assert.Equal(t, "slow", second.Oid())
+}
+
+func TestTransferQueueRetriesImmediatelyWithoutBackoff(t *testing.T) {
+	q := newRetryQueue("")
+	defer close(q.retriesc)
+
+	start := time.Now()
+	q.retry(&retryTransferable{oid: "oid"})
+
+	select {
+	case tr := <-q.apic:
+		assert.Equal(t, "oid", tr.Oid())
+		assert.True(t, time.Since(start) < 20*time.Millisecond, "retried after %v", time.Since(start))
+	case <-time.After(time.Second):
+		t.Fatal("retry was never enqueued")
+	}
+}
+
+func TestTransferQueueStatsCountsObjects(t *testing.T) {
+	server := newFakeBatchServer("", func(req *fakeBatchRequest) {
+		for _, o := range req.Objects {
+			if o.Oid == "missing" {
+				o.Error = &api.ObjectError{Code: 404, Message: "not found"}
+			}
+		}
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadCheckQueue(0, 0)
+	for _, oid := range []string{"a", "b", "a", "missing"} {
+		q.Add(&retryTransferable{oid: oid})
+	}
+	q.Wait()
+
+	stats := q.Stats()
+	assert.Equal(t, 2, stats.Succeeded)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, 0, stats.Retried)
+	assert.Equal(t, int64(0), stats.Bytes)
+	assert.True(t, stats.Duration > 0)
+	assert.Len(t, q.Errors(), 1)
+}
+
+func TestTransferQueueStatsCountsRetriedObjectsOnce(t *testing.T) {
+	q := newRetryQueue("")
+	defer close(q.retriesc)
+
+	for i := 0; i < 3; i++ {
+		q.retry(&retryTransferable{oid: "oid"})
+		retried := <-q.apic
+		require.True(t, q.claim(retried.Oid()))
+	}
+
+	assert.Equal(t, 3, q.rc.CountFor("oid"))
+	assert.Equal(t, 1, q.Stats().Retried)
+}
+
+func TestTransferQueueStopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var requests int32
+	server := newFakeBatchServer("", func(req *fakeBatchRequest) {
+		atomic.AddInt32(&requests, 1)
+		cancel()
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadCheckQueue(0, 0, WithContext(ctx))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10*batchSize; i++ {
+			q.Add(&retryTransferable{oid: fmt.Sprintf("oid-%d", i)})
+		}
+		q.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue did not stop after being cancelled")
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	stats := q.Stats()
+	assert.Equal(t, 0, stats.Succeeded)
+	assert.Equal(t, 10*batchSize, stats.Failed)
+
+	require.Len(t, q.Errors(), 1)
+	assert.Contains(t, q.Errors()[0].Error(), context.Canceled.Error())
+}
+
+func TestTransferQueueSkipsEmptyObjects(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	for _, skip := range []string{"", "false"} {
+		atomic.StoreInt32(&requests, 0)
+
+		restore := useGitConfig(map[string]string{
+			"lfs.url":                       server.URL,
+			"lfs.transfer.skipemptyobjects": skip,
+		})
+
+		q := NewDownloadCheckQueue(0, 0)
+		watch := q.Watch()
+		q.Add(&retryTransferable{oid: EmptyObjectOid})
+		q.Wait()
+		restore()
+
+		if skip == "false" {
+			assert.NotEqual(t, int32(0), atomic.LoadInt32(&requests))
+			assert.Equal(t, 1, q.Stats().Failed)
+			continue
+		}
+
+		assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+		assert.Equal(t, 1, q.Stats().Succeeded)
+		assert.Empty(t, q.Errors())
+		assert.Equal(t, EmptyObjectOid, <-watch)
+	}
+}
+
+func TestTransferQueueUploadsEmptyObjectsByDefault(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	for _, skip := range []string{"", "true"} {
+		atomic.StoreInt32(&requests, 0)
+
+		restore := useGitConfig(map[string]string{
+			"lfs.url":                       server.URL,
+			"lfs.transfer.skipemptyobjects": skip,
+		})
+
+		q := NewUploadQueue(0, 0, true)
+		q.Add(&retryTransferable{oid: EmptyObjectOid})
+		q.Wait()
+		restore()
+
+		if skip == "true" {
+			assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+			assert.Equal(t, 1, q.Stats().Succeeded)
+			continue
+		}
+
+		assert.NotEqual(t, int32(0), atomic.LoadInt32(&requests))
+		assert.Equal(t, 1, q.Stats().Failed)
+	}
+}
+
+func TestBatchSizerGrowsWhenSlowAndShrinksWhenFast(t *testing.T) {
+	b := &batchSizer{min: 10, max: 400, slow: time.Second, fast: 100 * time.Millisecond}
+
+	assert.Equal(t, 100, b.initial())
+	assert.Equal(t, 200, b.next(100, 2*time.Second))
+	assert.Equal(t, 400, b.next(300, 2*time.Second))
+	assert.Equal(t, 100, b.next(100, 500*time.Millisecond))
+	assert.Equal(t, 50, b.next(100, 10*time.Millisecond))
+	assert.Equal(t, 10, b.next(15, 10*time.Millisecond))
+}
+
+func TestTransferQueueAdaptiveBatchSizeGrowsUnderLatency(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	server := newFakeBatchServer("", func(req *fakeBatchRequest) {
+		mu.Lock()
+		sizes = append(sizes, len(req.Objects))
+		mu.Unlock()
+
+		// a slow link
+		time.Sleep(20 * time.Millisecond)
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadCheckQueue(0, 0, WithAdaptiveBatchSize(25, 400), func(q *TransferQueue) {
+		q.batchSizer.slow = 10 * time.Millisecond
+		q.batchSizer.fast = time.Millisecond
+	})
+	for i := 0; i < 2000; i++ {
+		q.Add(&retryTransferable{oid: fmt.Sprintf("oid-%d", i)})
+	}
+	q.Wait()
+
+	assert.Empty(t, q.Errors())
+	assert.Equal(t, 2000, q.Stats().Succeeded)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.True(t, len(sizes) > 2, "batch sizes: %v", sizes)
+	assert.Equal(t, 100, sizes[0])
+	assert.Equal(t, 400, sizes[len(sizes)-2], "batch sizes: %v", sizes)
+	for i := 1; i < len(sizes)-1; i++ {
+		assert.True(t, sizes[i] >= sizes[i-1], "batch sizes: %v", sizes)
+	}
+}
+
+// prioritizedTransferable is a retryTransferable with a size and priority.
+type prioritizedTransferable struct {
+	retryTransferable
+	priority int
+	size     int64
+}
+
+func (p *prioritizedTransferable) Size() int64   { return p.size }
+func (p *prioritizedTransferable) Priority() int { return p.priority }
+
+func TestByPrioritySortsByPriorityThenSize(t *testing.T) {
+	ts := byPriority{
+		&retryTransferable{oid: "none"},
+		&prioritizedTransferable{retryTransferable{oid: "small"}, 1, 0},
+		&prioritizedTransferable{retryTransferable{oid: "large"}, 0, 100},
+		&prioritizedTransferable{retryTransferable{oid: "urgent"}, 5, 0},
+		&prioritizedTransferable{retryTransferable{oid: "big"}, 1, 50},
+	}
+	sort.Stable(ts)
+
+	oids := make([]string, 0, len(ts))
+	for _, tr := range ts {
+		oids = append(oids, tr.Oid())
+	}
+	assert.Equal(t, []string{"urgent", "big", "small", "large", "none"}, oids)
+}
+
+func TestTransferQueueTransfersHigherPrioritiesFirst(t *testing.T) {
+	server := newFakeBatchServer("")
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadCheckQueue(0, 0)
+	watch := q.Watch()
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "later"}, 0, 10})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "first"}, 2, 1})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "second"}, 1, 1})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "first"}, 2, 1})
+	q.Wait()
+
+	var oids []string
+	for oid := range watch {
+		oids = append(oids, oid)
+	}
+	assert.Equal(t, []string{"first", "second", "later"}, oids)
+}
+
+func TestSplitLargeObjectsKeepsOrder(t *testing.T) {
+	batch := []Transferable{
+		&prioritizedTransferable{retryTransferable{oid: "a"}, 1, 10},
+		&prioritizedTransferable{retryTransferable{oid: "b"}, 1, 500},
+		&prioritizedTransferable{retryTransferable{oid: "c"}, 0, 20},
+		&prioritizedTransferable{retryTransferable{oid: "d"}, 0, 30},
+		&prioritizedTransferable{retryTransferable{oid: "e"}, 0, 100},
+	}
+
+	var oids [][]string
+	for _, b := range splitLargeObjects(batch, 100) {
+		var names []string
+		for _, t := range b {
+			names = append(names, t.Oid())
+		}
+		oids = append(oids, names)
+	}
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c", "d"}, {"e"}}, oids)
+
+	assert.Len(t, splitLargeObjects(batch, 0), 1)
+}
+
+func TestTransferQueueSendsLargeObjectsInTheirOwnBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	server := newFakeBatchServer("", func(req *fakeBatchRequest) {
+		var oids []string
+		for _, o := range req.Objects {
+			oids = append(oids, o.Oid)
+		}
+		mu.Lock()
+		batches = append(batches, oids)
+		mu.Unlock()
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{
+		"lfs.url":                      server.URL,
+		"lfs.transfer.largeobjectsize": "100",
+	})()
+
+	q := NewDownloadCheckQueue(0, 0)
+	watch := q.Watch()
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "small1"}, 0, 10})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "huge"}, 0, 300})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "small2"}, 0, 5})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "large"}, 0, 200})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "small3"}, 0, 1})
+	q.Wait()
+
+	var found []string
+	for oid := range watch {
+		found = append(found, oid)
+	}
+	assert.Len(t, found, 5)
+
+	assert.Equal(t, [][]string{
+		{"huge"},
+		{"large"},
+		{"small1", "small2", "small3"},
+	}, batches)
+}
+
+func TestTransferQueueDrainAbandonsQueuedObjects(t *testing.T) {
+	var once sync.Once
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	server := newFakeBatchServer("", func(req *fakeBatchRequest) {
+		// hold the first batch until the queue has been drained
+		once.Do(func() { close(requested) })
+		<-release
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadCheckQueue(0, 0)
+	watch := q.Watch()
+
+	added := make(chan struct{})
+	go func() {
+		for i := 0; i < 3*batchSize; i++ {
+			q.Add(&retryTransferable{oid: fmt.Sprintf("oid-%03d", i)})
+		}
+		close(added)
+	}()
+
+	<-requested
+	abandoned := q.Drain()
+	close(release)
+	<-added
+
+	done := make(chan struct{})
+	go func() {
+		q.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait() did not return after Drain()")
+	}
+
+	var transferred []string
+	for oid := range watch {
+		transferred = append(transferred, oid)
+	}
+	sort.Strings(transferred)
+
+	// only the batch already sent to the API is transferred, and every
+	// object is either transferred or abandoned
+	require.Len(t, transferred, batchSize)
+	assert.Equal(t, "oid-000", transferred[0])
+	assert.Equal(t, fmt.Sprintf("oid-%03d", batchSize-1), transferred[batchSize-1])
+	assert.NotEmpty(t, abandoned)
+	assert.True(t, sort.StringsAreSorted(abandoned))
+	for _, oid := range abandoned {
+		assert.True(t, oid >= fmt.Sprintf("oid-%03d", batchSize), oid)
+	}
+
+	stats := q.Stats()
+	assert.Equal(t, batchSize, stats.Succeeded)
+	assert.Equal(t, 0, stats.Failed)
+	assert.Empty(t, q.Errors())
+}
+
+func TestTransferQueueSendsEvents(t *testing.T) {
+	server := newFakeBatchServer("")
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	events := make(chan TransferEvent, 100)
+	q := NewDownloadCheckQueue(0, 0, WithEventChannel(events))
+	for _, oid := range []string{"a", "b", "c", "a"} {
+		q.Add(&retryTransferable{oid: oid})
+	}
+	q.Wait()
+	close(events)
+
+	started := make(map[string]int)
+	finished := make(map[string]int)
+	for e := range events {
+		switch e.Type {
+		case TransferStarted:
+			started[e.Oid]++
+		case TransferFinished:
+			finished[e.Oid]++
+		default:
+			t.Errorf("unexpected event: %+v", e)
+		}
+		assert.Equal(t, e.Oid, e.Name)
+	}
+
+	expected := map[string]int{"a": 1, "b": 1, "c": 1}
+	assert.Equal(t, expected, started)
+	assert.Equal(t, expected, finished)
+}
+
+func TestTransferQueueDropsEventsWhenChannelIsFull(t *testing.T) {
+	events := make(chan TransferEvent, 1)
+	q := &TransferQueue{events: events}
+
+	q.emit(TransferEvent{Type: TransferStarted, Oid: "a"})
+	q.emit(TransferEvent{Type: TransferFinished, Oid: "a"})
+
+	require.Len(t, events, 1)
+	assert.Equal(t, TransferStarted, (<-events).Type)
+}
+
+func TestTransferQueueDoesNotRetryAnObjectTwice(t *testing.T) {
+	q := newRetryQueue("50ms")
+	defer close(q.retriesc)
+
+	tr := &retryTransferable{oid: "oid"}
+	q.transferables = map[string]Transferable{"oid": tr}
+	q.wait.Add(1)
+
+	// the adapter reports the same failure twice
+	res := transfer.TransferResult{
+		Transfer: transfer.NewTransfer("oid", &api.ObjectResource{Oid: "oid"}, ""),
+		Error:    errors.NewRetriableError(errors.New("failed")),
+	}
+	q.handleTransferResult(res, "")
+	q.handleTransferResult(res, "")
+
+	select {
+	case retried := <-q.apic:
+		assert.Equal(t, "oid", retried.Oid())
+	case <-time.After(time.Second):
+		t.Fatal("retry was never enqueued")
+	}
+
+	select {
+	case retried := <-q.apic:
+		t.Fatalf("%q was retried twice", retried.Oid())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Equal(t, 1, q.rc.CountFor("oid"))
+	assert.Equal(t, 1, q.Stats().Retried)
+
+	// the single pending transfer completes cleanly
+	assert.True(t, q.claim("oid"))
+	q.wait.Done()
+	q.wait.Wait()
+}
+
+// fakeAdapter completes every transfer it is given immediately, except for the
+// object "stuck", if set, which only completes once it is aborted. If flaky is
+// set, the first attempt at each object fails with a retriable error. It
+// records the concurrency it was begun with in maxConcurrency, and sets
+// overlapped if it is given an object it is still working on. Objects in
+// cacheStatuses are given that cache status.
+type fakeAdapter struct {
+	name           string
+	stuck          string
+	flaky          bool
+	cacheStatuses  map[string]string
+	attempted      map[string]bool
+	maxConcurrency int
+	completion     chan transfer.TransferResult
+	mu             sync.Mutex
+	working        map[string]bool
+	overlapped     bool
+}
+
+func (a *fakeAdapter) Name() string                  { return a.name }
+func (a *fakeAdapter) Direction() transfer.Direction { return transfer.Download }
+func (a *fakeAdapter) ClearTempStorage() error       { return nil }
+
+func (a *fakeAdapter) Begin(maxConcurrency int, cb transfer.TransferProgressCallback, completion chan transfer.TransferResult) error {
+	a.maxConcurrency = maxConcurrency
+	a.completion = completion
+	return nil
+}
+
+func (a *fakeAdapter) Add(t *transfer.Transfer) {
+	if t.Object.Oid == a.stuck {
+		a.mu.Lock()
+		if a.working == nil {
+			a.working = make(map[string]bool)
+		}
+		a.overlapped = a.overlapped || a.working[t.Object.Oid]
+		a.working[t.Object.Oid] = true
+		a.mu.Unlock()
+
+		go func() {
+			<-t.Context().Done()
+			a.mu.Lock()
+			delete(a.working, t.Object.Oid)
+			a.mu.Unlock()
+			a.completion <- transfer.TransferResult{Transfer: t, Error: errors.New("aborted")}
+		}()
+		return
+	}
+
+	if a.flaky && !a.attempted[t.Object.Oid] {
+		if a.attempted == nil {
+			a.attempted = make(map[string]bool)
+		}
+		a.attempted[t.Object.Oid] = true
+		a.completion <- transfer.TransferResult{
+			Transfer: t,
+			Error:    errors.NewRetriableError(errors.New("flaky")),
+		}
+		return
+	}
+
+	t.CacheStatus = a.cacheStatuses[t.Object.Oid]
+	a.completion <- transfer.TransferResult{Transfer: t}
+}
+
+func (a *fakeAdapter) End() {
+	close(a.completion)
+}
+
+// withFakeAdapters makes the TransferQueue use the given adapters, by name,
+// for downloads. Each adapter is reused for every batch which asks for it.
+func withFakeAdapters(adapters ...*fakeAdapter) TransferQueueOption {
+	manifest := transfer.NewManifest()
+	for _, adapter := range adapters {
+		adapter := adapter
+		manifest.RegisterNewTransferAdapterFunc(adapter.name, transfer.Download, func(name string, dir transfer.Direction) transfer.TransferAdapter {
+			return adapter
+		})
+	}
+
+	return func(q *TransferQueue) {
+		q.manifest = manifest
+	}
+}
+
+// fakeBatchRequest is a batch API request received by a fake batch server.
+// Adapter is the name of the adapter the server will answer with, if any.
+type fakeBatchRequest struct {
+	Operation string                `json:"operation"`
+	Objects   []*api.ObjectResource `json:"objects"`
+	Transfers []string              `json:"transfers"`
+	Adapter   string                `json:"-"`
+}
+
+// newFakeBatchServer starts a batch API server which gives every object an
+// action for the requested operation, and answers with the given adapter if
+// it isn't blank. Each of fns is called with every request first, and may
+// change the adapter or fail objects by setting their Error.
+func newFakeBatchServer(adapter string, fns ...func(req *fakeBatchRequest)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &fakeBatchRequest{Adapter: adapter}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		for _, fn := range fns {
+			fn(req)
+		}
+
+		for _, o := range req.Objects {
+			if o.Error == nil {
+				o.Actions = map[string]*api.LinkRelation{
+					req.Operation: {Href: "http://example.com/" + o.Oid},
+				}
+			}
+		}
+
+		res := map[string]interface{}{"objects": req.Objects}
+		if len(req.Adapter) > 0 {
+			res["transfer"] = req.Adapter
+		}
+		w.Header().Set("Content-Type", api.MediaType)
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+// useGitConfig replaces config.Config with one holding the given git config,
+// returning a func which restores it.
+func useGitConfig(git map[string]string) func() {
+	oldConfig := config.Config
+	config.Config = config.NewFrom(config.Values{Git: git})
+	return func() { config.Config = oldConfig }
+}
+
+func TestTransferQueueCountsAdapterUsage(t *testing.T) {
+	var requests int32
+	server := newFakeBatchServer("fake-a", func(req *fakeBatchRequest) {
+		// the server switches adapters after the first batch
+		if atomic.AddInt32(&requests, 1) > 1 {
+			req.Adapter = "fake-b"
+		}
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadQueue(0, 0, false, withFakeAdapters(&fakeAdapter{name: "fake-a"}, &fakeAdapter{name: "fake-b"}))
+	for i := 0; i < batchSize+50; i++ {
+		q.Add(&retryTransferable{oid: fmt.Sprintf("oid-%d", i)})
+	}
+	q.Wait()
+
+	assert.Empty(t, q.Errors())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, map[string]int{"fake-a": batchSize, "fake-b": 50}, q.AdapterUsage())
+}
+
+func TestTransferQueueRetrySummary(t *testing.T) {
+	server := newFakeBatchServer("fake")
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	// one adapter is shared by every batch, so it remembers which objects
+	// have already failed once
+	q := NewDownloadQueue(0, 0, false, withFakeAdapters(&fakeAdapter{name: "fake", flaky: true}))
+	for _, oid := range []string{"a", "b", "c"} {
+		q.Add(&retryTransferable{oid: oid})
+	}
+	q.Wait()
+
+	assert.Empty(t, q.Errors())
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, q.RetrySummary())
+	assert.Equal(t, 3, q.Stats().Succeeded)
+}
+
+func TestTransferQueueCountsCacheStatuses(t *testing.T) {
+	server := newFakeBatchServer("fake")
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	adapter := &fakeAdapter{name: "fake", cacheStatuses: map[string]string{
+		"a": "hit",
+		"b": "miss",
+		"c": "hit",
+	}}
+	events := make(chan TransferEvent, 100)
+	q := NewDownloadQueue(0, 0, false, WithEventChannel(events), withFakeAdapters(adapter))
+	for _, oid := range []string{"a", "b", "c", "d"} {
+		q.Add(&retryTransferable{oid: oid})
+	}
+	q.Wait()
+	close(events)
+
+	assert.Empty(t, q.Errors())
+	assert.Equal(t, map[string]int{"hit": 2, "miss": 1}, q.Stats().CacheStatuses)
+
+	finished := make(map[string]string)
+	for e := range events {
+		if e.Type == TransferFinished {
+			finished[e.Oid] = e.CacheStatus
+		}
+	}
+	assert.Equal(t, map[string]string{"a": "hit", "b": "miss", "c": "hit", "d": ""}, finished)
+}
+
+func TestTransferQueueTimesOutStuckObjects(t *testing.T) {
+	server := newFakeBatchServer("fake")
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	adapter := &fakeAdapter{name: "fake", stuck: "stuck"}
+	q := NewDownloadQueue(0, 0, false, WithObjectTimeout(50*time.Millisecond), withFakeAdapters(adapter))
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		q.Add(&retryTransferable{oid: "ok"})
+		q.Add(&retryTransferable{oid: "stuck"})
+		q.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue never gave up on the stuck object")
+	}
+
+	// tried once, then retried once, timing out each time
+	assert.True(t, time.Since(start) >= 100*time.Millisecond, "finished after %v", time.Since(start))
+
+	stats := q.Stats()
+	assert.Equal(t, 1, stats.Succeeded)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, 1, stats.Retried)
+
+	require.Len(t, q.Errors(), 1)
+	assert.Contains(t, q.Errors()[0].Error(), "stuck timed out after 50ms")
+
+	// the retry waited for the adapter to give up the first attempt
+	assert.False(t, adapter.overlapped)
+}
+
+func TestTransferQueueBeginsAdapterWithRemoteConcurrency(t *testing.T) {
+	server := newFakeBatchServer("fake")
+	defer server.Close()
+	defer useGitConfig(map[string]string{
+		"lfs.url":                        server.URL,
+		"lfs.concurrenttransfers":        "4",
+		"lfs.mirror.concurrenttransfers": "16",
+		"lfs.public.concurrenttransfers": "1",
+	})()
+
+	for remote, expected := range map[string]int{"mirror": 16, "public": 1, "origin": 4} {
+		adapter := &fakeAdapter{name: "fake"}
+		q := NewDownloadQueue(0, 0, false, WithRemote(remote), withFakeAdapters(adapter))
+		q.Add(&retryTransferable{oid: "a"})
+		q.Wait()
+
+		assert.Empty(t, q.Errors(), remote)
+		assert.Equal(t, expected, adapter.maxConcurrency, remote)
+	}
+}
+
+func TestTransferQueuePendingShrinksToEmpty(t *testing.T) {
+	server := newFakeBatchServer("")
+	defer server.Close()
+	defer useGitConfig(map[string]string{"lfs.url": server.URL})()
+
+	q := NewDownloadQueue(0, 0, true)
+	for _, oid := range []string{"c", "a", "b", "a"} {
+		q.Add(&retryTransferable{oid: oid})
+	}
+
+	// nothing is sent to the API until the batch is full, or Wait is called
+	assert.Equal(t, []string{"a", "b", "c"}, q.Pending())
+	assert.Empty(t, q.InFlight())
+
+	q.Wait()
+
+	assert.Empty(t, q.Errors())
+	assert.Empty(t, q.Pending())
+	assert.Empty(t, q.InFlight())
+}
+
+func TestTransferQueueReportsObjectsInFlight(t *testing.T) {
+	server := newFakeBatchServer("fake")
+	defer server.Close()
+	defer useGitConfig(map[string]string{
+		"lfs.url":                 server.URL,
+		"lfs.transfer.maxretries": "1",
+	})()
+
+	q := NewDownloadQueue(0, 0, false, WithObjectTimeout(300*time.Millisecond), withFakeAdapters(&fakeAdapter{name: "fake", stuck: "stuck"}))
+
+	done := make(chan struct{})
+	go func() {
+		q.Add(&retryTransferable{oid: "ok"})
+		q.Add(&retryTransferable{oid: "stuck"})
+		q.Wait()
+		close(done)
+	}()
+
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for len(q.InFlight()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"stuck"}, q.InFlight())
+	assert.Equal(t, []string{"stuck"}, q.Pending())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue never finished")
+	}
+
+	assert.Empty(t, q.Pending())
+	assert.Empty(t, q.InFlight())
+}
+
+func TestTransferQueueOffersTusOnlyForLargeObjects(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string][]string)
+	server := newFakeBatchServer("", func(req *fakeBatchRequest) {
+		mu.Lock()
+		for _, o := range req.Objects {
+			requested[o.Oid] = req.Transfers
+		}
+		mu.Unlock()
+	})
+	defer server.Close()
+	defer useGitConfig(map[string]string{
+		"lfs.url":          server.URL,
+		"lfs.tustransfers": "true",
+		"lfs.tus.minsize":  "100",
+	})()
+
+	q := NewUploadQueue(0, 0, true)
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "small1"}, 0, 10})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "large"}, 0, 300})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "small2"}, 0, 99})
+	q.Add(&prioritizedTransferable{retryTransferable{oid: "exact"}, 0, 100})
+	q.Wait()
+
+	assert.Empty(t, q.Errors())
+	assert.Equal(t, map[string][]string{
+		"large":  {"basic", "tus"},
+		"exact":  {"basic", "tus"},
+		"small1": nil,
+		"small2": nil,
+	}, requested)
+}
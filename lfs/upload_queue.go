@@ -18,6 +18,7 @@ type Uploadable struct {
 	Filename string
 	size     int64
 	object   *api.ObjectResource
+	priority int
 }
 
 func (u *Uploadable) Object() *api.ObjectResource {
@@ -44,6 +45,17 @@ func (u *Uploadable) Path() string {
 	return u.OidPath
 }
 
+// Priority implements Prioritizable.
+func (u *Uploadable) Priority() int {
+	return u.priority
+}
+
+// SetPriority makes this upload start before others in the same batch with a
+// lower priority. The default is zero.
+func (u *Uploadable) SetPriority(priority int) {
+	u.priority = priority
+}
+
 // TODO LEGACY API: remove when legacy API removed
 func (u *Uploadable) LegacyCheck() (*api.ObjectResource, error) {
 	return api.UploadCheck(config.Config, u.Oid(), u.Size())
@@ -72,8 +84,8 @@ func NewUploadable(oid, filename string) (*Uploadable, error) {
 }
 
 // NewUploadQueue builds an UploadQueue, allowing `workers` concurrent uploads.
-func NewUploadQueue(files int, size int64, dryRun bool) *TransferQueue {
-	return newTransferQueue(files, size, dryRun, transfer.Upload)
+func NewUploadQueue(files int, size int64, dryRun bool, options ...TransferQueueOption) *TransferQueue {
+	return newTransferQueue(files, size, dryRun, transfer.Upload, options...)
 }
 
 // ensureFile makes sure that the cleanPath exists before pushing it.  If it
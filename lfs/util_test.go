@@ -37,3 +37,30 @@ func TestWriterWithCallback(t *testing.T) {
 	assert.Equal(t, 3, int(calledRead[0]))
 	assert.Equal(t, 5, int(calledRead[1]))
 }
+
+func TestCheckDiskSpace(t *testing.T) {
+	oldDiskFree := diskFree
+	defer func() { diskFree = oldDiskFree }()
+
+	var free uint64
+	known := true
+	diskFree = func(dir string) (uint64, bool) {
+		assert.Equal(t, "objects", dir)
+		return free, known
+	}
+
+	free = 100*1024*1024 + diskSpaceMargin
+	assert.Nil(t, checkDiskSpace("objects", 100*1024*1024))
+
+	free--
+	err := checkDiskSpace("objects", 100*1024*1024)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "insufficient disk space in objects")
+	}
+
+	// an unknown size or amount of space skips the check
+	free = 0
+	assert.Nil(t, checkDiskSpace("objects", 0))
+	known = false
+	assert.Nil(t, checkDiskSpace("objects", 100*1024*1024))
+}
@@ -43,6 +43,7 @@ func InitStorage() error {
 		return errors.Wrap(err, "init LocalStorage")
 	}
 
+	objs.ShardingDepth = config.Config.StorageShardingDepth()
 	objects = objs
 	config.LocalLogDir = filepath.Join(objs.RootDir, "logs")
 	if err := longpathos.MkdirAll(config.LocalLogDir, localLogDirPerms); err != nil {
@@ -13,6 +13,11 @@ import (
 
 const (
 	chanBufSize = 100
+
+	// defaultShardingDepth is used if a LocalStorage has no ShardingDepth
+	defaultShardingDepth = 2
+	// maxShardingDepth is the deepest layout objects are looked for in
+	maxShardingDepth = 3
 )
 
 var (
@@ -24,6 +29,10 @@ var (
 type LocalStorage struct {
 	RootDir string
 	TempDir string
+	// ShardingDepth is how many levels of two-character directories new
+	// objects are stored under, e.g. 2 for "ab/cd/abcd...". Objects stored
+	// at any other depth are still found.
+	ShardingDepth int
 }
 
 // Object represents a locally stored LFS object.
@@ -41,15 +50,27 @@ func NewStorage(storageDir, tempDir string) (*LocalStorage, error) {
 		return nil, err
 	}
 
-	return &LocalStorage{storageDir, tempDir}, nil
+	return &LocalStorage{RootDir: storageDir, TempDir: tempDir}, nil
 }
 
+// ObjectPath returns the path of the given object, wherever it is stored. If
+// it isn't stored yet, the path it would be stored at is returned.
 func (s *LocalStorage) ObjectPath(oid string) string {
-	return filepath.Join(localObjectDir(s, oid), oid)
+	depth := s.storedDepth(oid)
+	if depth == 0 {
+		depth = s.shardingDepth()
+	}
+	return filepath.Join(localObjectDir(s, oid, depth), oid)
 }
 
+// BuildObjectPath is like ObjectPath, but also creates the directory the object
+// would be stored in.
 func (s *LocalStorage) BuildObjectPath(oid string) (string, error) {
-	dir := localObjectDir(s, oid)
+	if depth := s.storedDepth(oid); depth > 0 {
+		return filepath.Join(localObjectDir(s, oid, depth), oid), nil
+	}
+
+	dir := localObjectDir(s, oid, s.shardingDepth())
 	if err := longpathos.MkdirAll(dir, dirPerms); err != nil {
 		return "", fmt.Errorf("Error trying to create local storage directory in %q: %s", dir, err)
 	}
@@ -57,6 +78,74 @@ func (s *LocalStorage) BuildObjectPath(oid string) (string, error) {
 	return filepath.Join(dir, oid), nil
 }
 
-func localObjectDir(s *LocalStorage, oid string) string {
-	return filepath.Join(s.RootDir, oid[0:2], oid[2:4])
+// MigrateObjects moves every stored object which was stored with a different
+// sharding depth to its path for the current one, returning how many were
+// moved. Directories left empty are removed.
+func (s *LocalStorage) MigrateObjects() (int, error) {
+	depth := s.shardingDepth()
+
+	var moved int
+	for _, obj := range s.AllObjects() {
+		stored := s.storedDepth(obj.Oid)
+		if stored == 0 || stored == depth {
+			continue
+		}
+
+		from := filepath.Join(localObjectDir(s, obj.Oid, stored), obj.Oid)
+		to := filepath.Join(localObjectDir(s, obj.Oid, depth), obj.Oid)
+		if err := longpathos.MkdirAll(filepath.Dir(to), dirPerms); err != nil {
+			return moved, err
+		}
+		if err := longpathos.Rename(from, to); err != nil {
+			return moved, err
+		}
+		moved++
+
+		// Remove the directories the object was in, if it was the last
+		// one there. Removing a directory that isn't empty fails.
+		dir := filepath.Dir(from)
+		for i := 0; i < stored && longpathos.Remove(dir) == nil; i++ {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return moved, nil
+}
+
+// storedDepth returns the sharding depth the given object is stored with,
+// trying the current one first, or 0 if it isn't stored.
+func (s *LocalStorage) storedDepth(oid string) int {
+	depth := s.shardingDepth()
+	if objectExists(filepath.Join(localObjectDir(s, oid, depth), oid)) {
+		return depth
+	}
+
+	for d := 1; d <= maxShardingDepth; d++ {
+		if d != depth && objectExists(filepath.Join(localObjectDir(s, oid, d), oid)) {
+			return d
+		}
+	}
+	return 0
+}
+
+func (s *LocalStorage) shardingDepth() int {
+	if s.ShardingDepth < 1 || s.ShardingDepth > maxShardingDepth {
+		return defaultShardingDepth
+	}
+	return s.ShardingDepth
+}
+
+func objectExists(path string) bool {
+	fi, err := longpathos.Stat(path)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// localObjectDir returns the directory the given object is stored in with the
+// given sharding depth, one directory for each pair of leading characters.
+func localObjectDir(s *LocalStorage, oid string, depth int) string {
+	parts := make([]string, 0, depth+1)
+	parts = append(parts, s.RootDir)
+	for i := 0; i < depth; i++ {
+		parts = append(parts, oid[i*2:i*2+2])
+	}
+	return filepath.Join(parts...)
 }
@@ -0,0 +1,85 @@
+package localstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOid = "abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789"
+
+func TestObjectPathShardingDepth(t *testing.T) {
+	s := &LocalStorage{RootDir: "objects"}
+
+	for depth, expected := range map[int]string{
+		0: filepath.Join("objects", "ab", "cd", testOid),
+		1: filepath.Join("objects", "ab", testOid),
+		2: filepath.Join("objects", "ab", "cd", testOid),
+		3: filepath.Join("objects", "ab", "cd", "ef", testOid),
+		4: filepath.Join("objects", "ab", "cd", testOid),
+	} {
+		s.ShardingDepth = depth
+		assert.Equal(t, expected, s.ObjectPath(testOid), "depth %d", depth)
+	}
+}
+
+func TestObjectPathFindsOtherDepths(t *testing.T) {
+	s := newTestStorage(t, 1)
+	defer os.RemoveAll(s.RootDir)
+
+	old := writeTestObject(t, s, testOid, 3)
+
+	assert.Equal(t, old, s.ObjectPath(testOid))
+
+	path, err := s.BuildObjectPath(testOid)
+	require.Nil(t, err)
+	assert.Equal(t, old, path)
+}
+
+func TestMigrateObjects(t *testing.T) {
+	s := newTestStorage(t, 3)
+	defer os.RemoveAll(s.RootDir)
+
+	otherOid := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	writeTestObject(t, s, testOid, 2)
+	current := writeTestObject(t, s, otherOid, 3)
+
+	moved, err := s.MigrateObjects()
+	require.Nil(t, err)
+	assert.Equal(t, 1, moved)
+
+	assert.Equal(t, filepath.Join(s.RootDir, "ab", "cd", "ef", testOid), s.ObjectPath(testOid))
+	assert.Equal(t, current, s.ObjectPath(otherOid))
+
+	// the directories left empty are removed, leaving only the new layout
+	entries, err := ioutil.ReadDir(filepath.Join(s.RootDir, "ab", "cd"))
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "ef", entries[0].Name())
+
+	moved, err = s.MigrateObjects()
+	require.Nil(t, err)
+	assert.Equal(t, 0, moved)
+}
+
+func newTestStorage(t *testing.T, depth int) *LocalStorage {
+	dir, err := ioutil.TempDir("", "localstorage")
+	require.Nil(t, err)
+
+	return &LocalStorage{RootDir: dir, ShardingDepth: depth}
+}
+
+// writeTestObject stores an object for oid with the given sharding depth,
+// returning its path.
+func writeTestObject(t *testing.T, s *LocalStorage, oid string, depth int) string {
+	dir := localObjectDir(s, oid, depth)
+	require.Nil(t, os.MkdirAll(dir, 0755))
+
+	path := filepath.Join(dir, oid)
+	require.Nil(t, ioutil.WriteFile(path, []byte("object"), 0644))
+	return path
+}
@@ -2,7 +2,9 @@ package progress
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -30,8 +32,38 @@ type ProgressMeter struct {
 	fileIndex         map[string]int64 // Maps a file name to its transfer number
 	fileIndexMutex    *sync.Mutex
 	dryRun            bool
+	refreshInterval   time.Duration
+	out               io.Writer
+	lastOutput        string
+	updateMutex       *sync.Mutex
+	verbose           bool
+	active            map[string]*transferProgress // Per file progress, only kept when verbose
+	verboseOffset     int                          // First active transfer shown when cycling
+	verboseRotated    time.Time                    // When the shown transfers last moved on
+	verboseDrawn      int                          // Per file lines drawn by the last redraw
 }
 
+// transferProgress is the progress of a single file, as shown in verbose mode.
+type transferProgress struct {
+	read    int64
+	total   int64
+	started time.Time
+}
+
+// MaxVerboseLines is the number of per file progress lines shown at once in
+// verbose mode. When more files are transferring than this, the lines cycle
+// through them every VerboseRotateInterval.
+const MaxVerboseLines = 4
+
+// VerboseRotateInterval is how long each set of per file progress lines is
+// shown before moving on to the next, so they stay readable however often the
+// meter redraws.
+const VerboseRotateInterval = time.Second
+
+// DefaultRefreshInterval is how often the ProgressMeter redraws itself unless
+// told otherwise with SetRefreshInterval.
+const DefaultRefreshInterval = 100 * time.Millisecond
+
 // NewProgressMeter creates a new ProgressMeter for the number and size of
 // files given.
 func NewProgressMeter(estFiles int, estBytes int64, dryRun bool, logPath string) *ProgressMeter {
@@ -41,14 +73,41 @@ func NewProgressMeter(estFiles int, estBytes int64, dryRun bool, logPath string)
 	}
 
 	return &ProgressMeter{
-		logger:         logger,
-		startTime:      time.Now(),
-		fileIndex:      make(map[string]int64),
-		fileIndexMutex: &sync.Mutex{},
-		finished:       make(chan interface{}),
-		estimatedFiles: int32(estFiles),
-		estimatedBytes: estBytes,
-		dryRun:         dryRun,
+		logger:          logger,
+		startTime:       time.Now(),
+		fileIndex:       make(map[string]int64),
+		fileIndexMutex:  &sync.Mutex{},
+		finished:        make(chan interface{}),
+		estimatedFiles:  int32(estFiles),
+		estimatedBytes:  estBytes,
+		dryRun:          dryRun,
+		refreshInterval: DefaultRefreshInterval,
+		out:             os.Stdout,
+		updateMutex:     &sync.Mutex{},
+		active:          make(map[string]*transferProgress),
+	}
+}
+
+// SetVerbose makes the meter draw a progress line for each transferring file
+// below the aggregate line. It must be called before Start.
+func (p *ProgressMeter) SetVerbose(verbose bool) {
+	p.verbose = verbose
+}
+
+// SetOutput changes where the meter is drawn, which is stdout by default. It
+// must be called before Start.
+func (p *ProgressMeter) SetOutput(w io.Writer) {
+	p.out = w
+}
+
+// SetRefreshInterval changes how often the display is redrawn. Counts from the
+// Add, Skip, TransferBytes and FinishTransfer callbacks are accumulated between
+// redraws, so callers firing many callbacks only pay for one redraw per
+// interval. It must be called before Start; intervals of zero or less are
+// ignored.
+func (p *ProgressMeter) SetRefreshInterval(d time.Duration) {
+	if d > 0 {
+		p.refreshInterval = d
 	}
 }
 
@@ -82,6 +141,18 @@ func (p *ProgressMeter) Skip(size int64) {
 func (p *ProgressMeter) TransferBytes(direction, name string, read, total int64, current int) {
 	atomic.AddInt64(&p.currentBytes, int64(current))
 	p.logBytes(direction, name, read, total)
+
+	if p.verbose {
+		p.fileIndexMutex.Lock()
+		tp, ok := p.active[name]
+		if !ok {
+			tp = &transferProgress{started: time.Now()}
+			p.active[name] = tp
+		}
+		tp.read = read
+		tp.total = total
+		p.fileIndexMutex.Unlock()
+	}
 }
 
 // FinishTransfer increments the finished transfer count
@@ -89,6 +160,15 @@ func (p *ProgressMeter) FinishTransfer(name string) {
 	atomic.AddInt64(&p.finishedFiles, 1)
 	p.fileIndexMutex.Lock()
 	delete(p.fileIndex, name)
+	delete(p.active, name)
+	p.fileIndexMutex.Unlock()
+}
+
+// AbortTransfer stops showing the progress of a file whose transfer failed. It
+// isn't counted as finished, and if it is retried its progress starts over.
+func (p *ProgressMeter) AbortTransfer(name string) {
+	p.fileIndexMutex.Lock()
+	delete(p.active, name)
 	p.fileIndexMutex.Unlock()
 }
 
@@ -97,8 +177,10 @@ func (p *ProgressMeter) Finish() {
 	close(p.finished)
 	p.update()
 	p.logger.Close()
-	if !p.dryRun && p.estimatedBytes > 0 {
-		fmt.Fprintf(os.Stdout, "\n")
+	if !p.dryRun && atomic.LoadInt64(&p.estimatedBytes) > 0 {
+		p.updateMutex.Lock()
+		fmt.Fprintf(p.out, "\n")
+		p.updateMutex.Unlock()
 	}
 }
 
@@ -114,18 +196,31 @@ func (p *ProgressMeter) logBytes(direction, name string, read, total int64) {
 
 func (p *ProgressMeter) writer() {
 	p.update()
+
+	ticker := time.NewTicker(p.refreshInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-p.finished:
 			return
-		case <-time.After(time.Millisecond * 200):
+		case <-ticker.C:
 			p.update()
 		}
 	}
 }
 
 func (p *ProgressMeter) update() {
-	if p.dryRun || (p.estimatedFiles == 0 && p.skippedFiles == 0) {
+	// Callbacks keep firing while the display is drawn, so take a consistent
+	// snapshot of the counts accumulated since the last redraw
+	finishedFiles := atomic.LoadInt64(&p.finishedFiles)
+	skippedFiles := atomic.LoadInt64(&p.skippedFiles)
+	estimatedFiles := atomic.LoadInt32(&p.estimatedFiles)
+	currentBytes := atomic.LoadInt64(&p.currentBytes)
+	estimatedBytes := atomic.LoadInt64(&p.estimatedBytes)
+	skippedBytes := atomic.LoadInt64(&p.skippedBytes)
+
+	if p.dryRun || (estimatedFiles == 0 && skippedFiles == 0) {
 		return
 	}
 
@@ -138,13 +233,13 @@ func (p *ProgressMeter) update() {
 	// (%d of %d files, %d skipped) %f B / %f B, %f B skipped
 	// skipped counts only show when > 0
 
-	out := fmt.Sprintf("\rGit LFS: (%d of %d files", p.finishedFiles, p.estimatedFiles)
-	if p.skippedFiles > 0 {
-		out += fmt.Sprintf(", %d skipped", p.skippedFiles)
+	out := fmt.Sprintf("\rGit LFS: (%d of %d files", finishedFiles, estimatedFiles)
+	if skippedFiles > 0 {
+		out += fmt.Sprintf(", %d skipped", skippedFiles)
 	}
-	out += fmt.Sprintf(") %s / %s", formatBytes(p.currentBytes), formatBytes(p.estimatedBytes))
-	if p.skippedBytes > 0 {
-		out += fmt.Sprintf(", %s skipped", formatBytes(p.skippedBytes))
+	out += fmt.Sprintf(") %s / %s", formatBytes(currentBytes), formatBytes(estimatedBytes))
+	if skippedBytes > 0 {
+		out += fmt.Sprintf(", %s skipped", formatBytes(skippedBytes))
 	}
 
 	padlen := width - len(out)
@@ -152,7 +247,98 @@ func (p *ProgressMeter) update() {
 		out += strings.Repeat(" ", padlen)
 	}
 
-	fmt.Fprintf(os.Stdout, out)
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
+
+	if p.verbose {
+		out = p.verboseOutput(out, width)
+	}
+
+	// Nothing changed since the last redraw, so don't flicker the terminal
+	if out == p.lastOutput {
+		return
+	}
+	p.lastOutput = out
+
+	fmt.Fprint(p.out, out)
+}
+
+// verboseOutput adds a line for each of the files currently shown below the
+// aggregate line, moving the cursor back up over the lines from the previous
+// redraw first. It must be called with updateMutex held.
+func (p *ProgressMeter) verboseOutput(aggregate string, width int) string {
+	lines := p.verboseLines(width)
+
+	out := aggregate
+	if p.verboseDrawn > 0 {
+		out = fmt.Sprintf("\x1b[%dA", p.verboseDrawn) + out
+	}
+	for _, line := range lines {
+		out += "\n\r" + line
+	}
+	// Clear any lines left over from a redraw showing more files
+	if len(lines) < p.verboseDrawn {
+		out += "\x1b[J"
+	}
+	p.verboseDrawn = len(lines)
+
+	return out
+}
+
+// verboseLines returns the progress lines for at most MaxVerboseLines of the
+// active transfers, ordered by name. When there are more, it moves on to the
+// next set once every VerboseRotateInterval so every transfer is shown in turn.
+func (p *ProgressMeter) verboseLines(width int) []string {
+	p.fileIndexMutex.Lock()
+	defer p.fileIndexMutex.Unlock()
+
+	names := make([]string, 0, len(p.active))
+	for name := range p.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > MaxVerboseLines {
+		if p.verboseRotated.IsZero() {
+			p.verboseRotated = time.Now()
+		} else if time.Since(p.verboseRotated) >= VerboseRotateInterval {
+			p.verboseOffset = (p.verboseOffset + MaxVerboseLines) % len(names)
+			p.verboseRotated = time.Now()
+		}
+
+		shown := make([]string, MaxVerboseLines)
+		for i := range shown {
+			shown[i] = names[(p.verboseOffset+i)%len(names)]
+		}
+		names = shown
+	} else {
+		p.verboseOffset = 0
+		p.verboseRotated = time.Time{}
+	}
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		tp := p.active[name]
+		line := fmt.Sprintf("  %s %s / %s, %s/s", name,
+			formatBytes(tp.read), formatBytes(tp.total), formatBytes(tp.rate()))
+		if len(line) > width {
+			line = line[:width]
+		} else {
+			line += strings.Repeat(" ", width-len(line))
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// rate returns the average number of bytes per second transferred so far.
+func (tp *transferProgress) rate() int64 {
+	elapsed := time.Since(tp.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(tp.read) / elapsed)
 }
 
 func formatBytes(i int64) string {
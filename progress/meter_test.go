@@ -0,0 +1,157 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressMeterCoalescesUpdates(t *testing.T) {
+	files := 5000
+	buf := &bytes.Buffer{}
+
+	meter := NewProgressMeter(files, int64(files), false, "")
+	meter.out = buf
+	meter.SetRefreshInterval(time.Hour)
+	meter.Start()
+
+	for i := 0; i < files; i++ {
+		name := fmt.Sprintf("file%d", i)
+		meter.Add(name)
+		meter.TransferBytes("download", name, 1, 1, 1)
+		meter.FinishTransfer(name)
+	}
+	meter.Finish()
+
+	// The writer goroutine may still be mid redraw, so read under its lock
+	meter.updateMutex.Lock()
+	output := buf.String()
+	meter.updateMutex.Unlock()
+
+	// Every redraw begins with a carriage return. Expect one redraw on
+	// Start, and at most one more when finishing.
+	updates := strings.Count(output, "\r")
+	assert.True(t, updates > 0 && updates <= 2, "expected coalesced updates, got %d", updates)
+
+	final := strings.TrimSpace(output[strings.LastIndex(output, "\r"):])
+	assert.Equal(t, fmt.Sprintf("Git LFS: (%d of %d files) 4.88 KB / 4.88 KB", files, files), final)
+}
+
+func TestProgressMeterSetRefreshIntervalIgnoresInvalid(t *testing.T) {
+	meter := NewProgressMeter(1, 1, false, "")
+	meter.SetRefreshInterval(0)
+	assert.Equal(t, DefaultRefreshInterval, meter.refreshInterval)
+
+	meter.SetRefreshInterval(-time.Second)
+	assert.Equal(t, DefaultRefreshInterval, meter.refreshInterval)
+
+	meter.SetRefreshInterval(time.Second)
+	assert.Equal(t, time.Second, meter.refreshInterval)
+}
+
+func TestProgressMeterVerboseTracksTransfersByName(t *testing.T) {
+	meter := NewProgressMeter(2, 30, false, "")
+	meter.SetVerbose(true)
+
+	meter.Add("a.dat")
+	meter.Add("b.dat")
+	meter.TransferBytes("download", "a.dat", 5, 10, 5)
+	meter.TransferBytes("download", "b.dat", 10, 20, 10)
+	meter.TransferBytes("download", "a.dat", 8, 10, 3)
+
+	if assert.Len(t, meter.active, 2) {
+		assert.Equal(t, int64(8), meter.active["a.dat"].read)
+		assert.Equal(t, int64(10), meter.active["a.dat"].total)
+		assert.Equal(t, int64(10), meter.active["b.dat"].read)
+		assert.Equal(t, int64(20), meter.active["b.dat"].total)
+	}
+
+	meter.FinishTransfer("a.dat")
+	assert.Len(t, meter.active, 1)
+	assert.Nil(t, meter.active["a.dat"])
+}
+
+func TestProgressMeterNotVerboseSkipsTransfers(t *testing.T) {
+	meter := NewProgressMeter(1, 10, false, "")
+
+	meter.Add("a.dat")
+	meter.TransferBytes("download", "a.dat", 5, 10, 5)
+
+	assert.Empty(t, meter.active)
+}
+
+func TestProgressMeterVerboseLinesCycle(t *testing.T) {
+	meter := NewProgressMeter(6, 6, false, "")
+	meter.SetVerbose(true)
+
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("file%d", i)
+		meter.Add(name)
+		meter.TransferBytes("download", name, 1, 1, 1)
+	}
+
+	shown := func() []string {
+		var names []string
+		for _, line := range meter.verboseLines(80) {
+			assert.Len(t, line, 80)
+			names = append(names, strings.Fields(line)[0])
+		}
+		return names
+	}
+
+	// Pretend a full rotation interval passed since the lines last moved on
+	rotate := func() {
+		meter.verboseRotated = time.Now().Add(-VerboseRotateInterval)
+	}
+
+	assert.Equal(t, []string{"file0", "file1", "file2", "file3"}, shown())
+	assert.Equal(t, []string{"file0", "file1", "file2", "file3"}, shown())
+	rotate()
+	assert.Equal(t, []string{"file4", "file5", "file0", "file1"}, shown())
+	assert.Equal(t, []string{"file4", "file5", "file0", "file1"}, shown())
+	rotate()
+	assert.Equal(t, []string{"file2", "file3", "file4", "file5"}, shown())
+
+	meter.FinishTransfer("file0")
+	meter.FinishTransfer("file1")
+	assert.Equal(t, []string{"file2", "file3", "file4", "file5"}, shown())
+}
+
+func TestProgressMeterAbortTransfer(t *testing.T) {
+	meter := NewProgressMeter(2, 2, false, "")
+	meter.SetVerbose(true)
+
+	meter.Add("a.dat")
+	meter.Add("b.dat")
+	meter.TransferBytes("download", "a.dat", 1, 2, 1)
+	meter.TransferBytes("download", "b.dat", 1, 2, 1)
+
+	meter.AbortTransfer("a.dat")
+	assert.Len(t, meter.active, 1)
+	assert.Contains(t, meter.active, "b.dat")
+	assert.Equal(t, int64(0), meter.finishedFiles)
+}
+
+func TestProgressMeterVerboseOutputRedrawsLines(t *testing.T) {
+	meter := NewProgressMeter(2, 2, false, "")
+	meter.SetVerbose(true)
+
+	meter.Add("a.dat")
+	meter.Add("b.dat")
+	meter.TransferBytes("download", "a.dat", 1, 1, 1)
+	meter.TransferBytes("download", "b.dat", 1, 1, 1)
+
+	out := meter.verboseOutput("\rGit LFS", 80)
+	assert.True(t, strings.HasPrefix(out, "\rGit LFS\n\r  a.dat"), out)
+	assert.Equal(t, 2, strings.Count(out, "\n"))
+
+	meter.FinishTransfer("a.dat")
+	out = meter.verboseOutput("\rGit LFS", 80)
+	assert.True(t, strings.HasPrefix(out, "\x1b[2A\rGit LFS\n\r  b.dat"), out)
+	assert.True(t, strings.HasSuffix(out, "\x1b[J"), out)
+	assert.Equal(t, 1, meter.verboseDrawn)
+}
@@ -32,10 +32,11 @@ import (
 )
 
 var (
-	repoDir      string
-	largeObjects = newLfsStorage()
-	server       *httptest.Server
-	serverTLS    *httptest.Server
+	repoDir       string
+	largeObjects  = newLfsStorage()
+	cachedObjects = newLfsStorage()
+	server        *httptest.Server
+	serverTLS     *httptest.Server
 
 	// maps OIDs to content strings. Both the LFS and Storage test servers below
 	// see OIDs.
@@ -71,6 +72,7 @@ func main() {
 
 	mux.HandleFunc("/storage/", storageHandler)
 	mux.HandleFunc("/redirect307/", redirect307Handler)
+	mux.HandleFunc("/cache/", cacheHandler)
 	mux.HandleFunc("/locks", locksHandler)
 	mux.HandleFunc("/locks/", locksHandler)
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -732,6 +734,55 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// cacheHandler emulates a read-through cache in front of the storage server,
+// at /cache/<repo>/<oid>. Tests populate it with PUT requests; GET requests
+// for anything else are a miss. Objects cached as "status-cache-500" make the
+// cache fail instead.
+func cacheHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := reqId(w)
+	if !ok {
+		return
+	}
+
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 4 {
+		w.WriteHeader(404)
+		return
+	}
+	repo, oid := parts[2], parts[3]
+
+	debug(id, "cache %s %s repo: %s", r.Method, oid, repo)
+	switch r.Method {
+	case "PUT":
+		by, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		cachedObjects.Set(repo, oid, by)
+		w.WriteHeader(200)
+	case "GET":
+		if len(r.Header.Get("Authorization")) > 0 {
+			w.WriteHeader(400)
+			w.Write([]byte("Should not send authentication"))
+			return
+		}
+
+		by, ok := cachedObjects.Get(repo, oid)
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		if string(by) == "status-cache-500" {
+			w.WriteHeader(500)
+			return
+		}
+		w.Write(by)
+	default:
+		w.WriteHeader(405)
+	}
+}
+
 func validateTusHeaders(r *http.Request, id string) bool {
 	if len(r.Header.Get("Tus-Resumable")) == 0 {
 		debug(id, "Missing Tus-Resumable header in request")
@@ -19,9 +19,10 @@ begin_test "batch storage upload causes retries"
   git commit -m "initial commit"
 
   git config --local lfs.transfer.maxretries 3
-  git push origin master
+  git push origin master 2>&1 | tee push.log
 
   assert_server_object "$reponame" "$oid"
+  grep "1 object(s) required retries" push.log
 )
 end_test
 
@@ -56,9 +57,10 @@ begin_test "batch storage download causes retries"
     git config credential.helper lfstest
     git config --local lfs.transfer.maxretries 3
 
-    git lfs pull origin
+    git lfs pull origin 2>&1 | tee pull.log
 
     assert_local_object "$oid" "${#contents}"
+    grep "1 object(s) required retries" pull.log
   popd
 )
 end_test
@@ -0,0 +1,100 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+reponame="$(basename "$0" ".sh")"
+contents_a="cached"
+contents_a_oid=$(calc_oid "$contents_a")
+contents_b="not cached"
+contents_b_oid=$(calc_oid "$contents_b")
+contents_c="uploaded"
+contents_c_oid=$(calc_oid "$contents_c")
+contents_d="cache fails"
+contents_d_oid=$(calc_oid "$contents_d")
+contents_e="cache corrupt"
+contents_e_oid=$(calc_oid "$contents_e")
+
+begin_test "cache url: init"
+(
+  set -e
+
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" repo
+
+  git lfs track "*.dat"
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  assert_server_object "$reponame" "$contents_a_oid"
+  assert_server_object "$reponame" "$contents_b_oid"
+
+  # Only a.dat is in the cache
+  curl -X PUT --data "$contents_a" "$GITSERVER/cache/$reponame/$contents_a_oid"
+)
+end_test
+
+begin_test "cache url: downloads from cache, falls back to server on miss"
+(
+  set -e
+
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" clone-fetch
+
+  git config lfs.cacheurl "$GITSERVER/cache/$reponame"
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+
+  grep "xfer: cache hit for \"$contents_a_oid\"" fetch.log
+  grep "xfer: cache miss for \"$contents_b_oid\", downloading from server" fetch.log
+  [ "0" -eq "$(grep -c "xfer: cache hit for \"$contents_b_oid\"" fetch.log)" ]
+
+  assert_local_object "$contents_a_oid" "${#contents_a}"
+  assert_local_object "$contents_b_oid" "${#contents_b}"
+)
+end_test
+
+begin_test "cache url: falls back to server when the cache fails"
+(
+  set -e
+
+  cd repo
+  printf "$contents_d" > d.dat
+  printf "$contents_e" > e.dat
+  git add d.dat e.dat
+  git commit -m "add d.dat and e.dat"
+  git push origin master
+  cd ..
+
+  curl -X PUT --data "status-cache-500" "$GITSERVER/cache/$reponame/$contents_d_oid"
+  curl -X PUT --data "not what was asked for" "$GITSERVER/cache/$reponame/$contents_e_oid"
+
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" clone-broken
+
+  git config lfs.cacheurl "$GITSERVER/cache/$reponame"
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+
+  grep "xfer: cache failed for \"$contents_d_oid\", downloading from server" fetch.log
+  grep "xfer: cache failed for \"$contents_e_oid\", downloading from server" fetch.log
+
+  assert_local_object "$contents_d_oid" "${#contents_d}"
+  assert_local_object "$contents_e_oid" "${#contents_e}"
+)
+end_test
+
+begin_test "cache url: uploads go to the server"
+(
+  set -e
+
+  cd repo
+  git config lfs.cacheurl "$GITSERVER/cache/$reponame"
+
+  printf "$contents_c" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+  git push origin master
+
+  assert_server_object "$reponame" "$contents_c_oid"
+  [ "404" = "$(curl -s -o /dev/null -w "%{http_code}" "$GITSERVER/cache/$reponame/$contents_c_oid")" ]
+)
+end_test
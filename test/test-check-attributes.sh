@@ -0,0 +1,118 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "check-attributes --consistency"
+(
+  set -e
+
+  reponame="check-attributes-consistency"
+  git init "$reponame"
+  cd "$reponame"
+
+  # stored as a blob before *.dat was tracked
+  printf "untracked" > early.dat
+  git add early.dat
+  git commit -m "add early.dat"
+
+  git lfs track "*.dat"
+  mkdir -p vendor docs
+  printf "root" > root.dat
+  printf "vendored" > vendor/lib.dat
+  printf "doc" > docs/guide.dat
+  git add .gitattributes root.dat vendor/lib.dat docs/guide.dat
+  git commit -m "add tracked files"
+
+  git lfs check-attributes --consistency 2>&1 | tee check.log
+  grep "early.dat: tracked by Git LFS, but stored as a Git blob" check.log
+
+  # a subtree .gitattributes which un-tracks what the root tracks leaves the
+  # files already stored there as pointers
+  printf "*.dat -filter -diff -merge text\n" > vendor/.gitattributes
+  git add vendor/.gitattributes
+  git commit -m "untrack vendored files"
+
+  set +e
+  git lfs check-attributes --consistency 2>&1 | tee check.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" = "$res" ]
+  grep "early.dat: tracked by Git LFS, but stored as a Git blob" check.log
+  grep "vendor/lib.dat: stored as a Git LFS pointer, but not tracked by Git LFS" check.log
+  grep "2 file(s) stored inconsistently with .gitattributes at master" check.log
+  [ "0" = "$(grep -c "root.dat" check.log)" ]
+  [ "0" = "$(grep -c "docs/guide.dat" check.log)" ]
+
+  # the .gitattributes files at the given ref apply, not those in the index
+  set +e
+  git lfs check-attributes --consistency HEAD~1 2>&1 | tee check.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" = "$res" ]
+  grep "early.dat: tracked by Git LFS, but stored as a Git blob" check.log
+  grep "1 file(s) stored inconsistently with .gitattributes" check.log
+  [ "0" = "$(grep -c "vendor/lib.dat" check.log)" ]
+
+  # nothing was tracked before .gitattributes was added
+  git lfs check-attributes --consistency HEAD~2 2>&1 | tee check.log
+  grep "are consistent with .gitattributes" check.log
+  [ "0" = "$(grep -c "early.dat" check.log)" ]
+
+  # uncommitted changes to .gitattributes don't apply to any ref
+  git lfs untrack "*.dat"
+  git add .gitattributes
+  printf "*.dat filter=lfs diff=lfs merge=lfs -text\n" > vendor/.gitattributes
+
+  set +e
+  git lfs check-attributes --consistency 2>&1 | tee check.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" = "$res" ]
+  grep "early.dat: tracked by Git LFS, but stored as a Git blob" check.log
+  grep "vendor/lib.dat: stored as a Git LFS pointer, but not tracked by Git LFS" check.log
+  grep "2 file(s) stored inconsistently with .gitattributes at master" check.log
+
+  git lfs check-attributes --consistency HEAD~2 2>&1 | tee check.log
+  grep "are consistent with .gitattributes" check.log
+)
+end_test
+
+begin_test "check-attributes --consistency with consistent files"
+(
+  set -e
+
+  reponame="check-attributes-consistent"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.txt
+  git add .gitattributes a.dat b.txt
+  git commit -m "add files"
+
+  git lfs check-attributes --consistency 2>&1 | tee check.log
+  grep "Git LFS files at master are consistent with .gitattributes" check.log
+)
+end_test
+
+begin_test "check-attributes requires --consistency"
+(
+  set -e
+
+  reponame="check-attributes-usage"
+  git init "$reponame"
+  cd "$reponame"
+
+  set +e
+  git lfs check-attributes 2>&1 | tee check.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" = "$res" ]
+  grep "Usage: git lfs check-attributes --consistency" check.log
+)
+end_test
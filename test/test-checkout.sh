@@ -119,3 +119,67 @@ begin_test "checkout: outside git repository"
   grep "Not in a git repository" checkout.log
 )
 end_test
+
+begin_test "checkout: preserves tracked symlinks"
+(
+  set -e
+
+  reponame="checkout-symlinks"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  contents="symlinked"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > real.dat
+
+  # A symlink whose target path reads as a Git LFS pointer
+  target="$(printf "version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n" "$contents_oid" "${#contents}")"
+  ln -s "$target" pointer-link.dat
+  ln -s real.dat link.dat
+
+  git add .gitattributes real.dat pointer-link.dat link.dat
+  git commit -m "add symlinks"
+
+  [ "120000" = "$(git ls-tree HEAD pointer-link.dat | cut -d' ' -f1)" ]
+
+  rm real.dat pointer-link.dat link.dat
+  git checkout -- pointer-link.dat link.dat
+  git lfs checkout 2>&1 | tee checkout.log
+
+  [ "$contents" = "$(cat real.dat)" ]
+  [ -L link.dat ]
+  [ "real.dat" = "$(readlink link.dat)" ]
+  [ -L pointer-link.dat ]
+  [ "$target" = "$(readlink pointer-link.dat)" ]
+  [ ! -e "$target" ]
+  [ -z "$(git status --porcelain -- "*.dat")" ]
+)
+end_test
+
+begin_test "checkout: does not follow symlinks out of the working tree"
+(
+  set -e
+
+  reponame="checkout-symlink-outside"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  contents="outside"
+  mkdir sub
+  printf "$contents" > sub/a.dat
+  git add .gitattributes sub/a.dat
+  git commit -m "add sub/a.dat"
+
+  # replace the directory with a symlink pointing outside the repository
+  mkdir ../outside
+  rm -rf sub
+  ln -s ../outside sub
+
+  git lfs checkout 2>&1 | tee checkout.log
+  grep "Could not checkout file" checkout.log
+  git lfs logs last | grep "Not writing Git LFS content to sub/a.dat, outside of the working tree"
+  [ ! -e ../outside/a.dat ]
+)
+end_test
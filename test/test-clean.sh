@@ -56,3 +56,73 @@ This is my test pointer.  There are many like it, but this one is mine.\n" | git
   [ "$(pointer c2f909f6961bf85a92e2942ef3ed80c938a3d0ebaee6e72940692581052333be 586)" = "$(cat clean.log)" ]
 )
 end_test
+
+begin_test "clean large file from stdin"
+(
+  set -e
+  clean_setup "large-stdin"
+
+  # no file name is given, so the size of the content isn't known up front
+  head -c 100000 /dev/urandom > large.dat
+  oid="$(calc_oid_file large.dat)"
+
+  git lfs clean < large.dat | tee clean.log
+  [ "$(pointer "$oid" 100000)" = "$(cat clean.log)" ]
+  assert_local_object "$oid" 100000
+)
+end_test
+
+begin_test "clean with lfs.clean.minsize"
+(
+  set -e
+  clean_setup "minsize"
+
+  git config lfs.clean.minsize 10
+
+  # "whatever\n" is 9 bytes, so it is kept in Git as it is
+  echo "whatever" | git lfs clean | tee clean.log
+  [ "whatever" = "$(cat clean.log)" ]
+  refute_local_object "cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411"
+
+  # "whatever!\n" is 10 bytes, so it is cleaned as usual
+  echo "whatever!" | git lfs clean | tee clean.log
+  [ "$(pointer a86658670317939f2c2137219a469888cee227b6d53252bb2fad5f1e77b664d5 10)" = "$(cat clean.log)" ]
+  assert_local_object "a86658670317939f2c2137219a469888cee227b6d53252bb2fad5f1e77b664d5" 10
+)
+end_test
+
+begin_test "clean with lfs.clean.minsize keeps stored objects as pointers"
+(
+  set -e
+  clean_setup "minsize-stored"
+
+  echo "whatever" | git lfs clean | tee clean.log
+  [ "$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9)" = "$(cat clean.log)" ]
+
+  git config lfs.clean.minsize 10
+
+  # the object was stored before lfs.clean.minsize was set
+  echo "whatever" | git lfs clean | tee clean.log
+  [ "$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9)" = "$(cat clean.log)" ]
+)
+end_test
+
+begin_test "clean with lfs.clean.minsize round trips small files"
+(
+  set -e
+  clean_setup "minsize-roundtrip"
+
+  git lfs track "*.dat"
+  git config lfs.clean.minsize 10
+  echo "small" > small.dat
+  git add .gitattributes small.dat
+  git commit -m "add small file"
+
+  [ "small" = "$(git cat-file -p HEAD:small.dat)" ]
+
+  rm small.dat
+  git checkout -- small.dat
+  [ "small" = "$(cat small.dat)" ]
+  [ -z "$(git status --porcelain)" ]
+)
+end_test
@@ -0,0 +1,116 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "diff-remote"
+(
+  set -e
+
+  reponame="diff-remote"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  pushed="pushed"
+  pushed_oid=$(calc_oid "$pushed")
+  unpushed="unpushed"
+  unpushed_oid=$(calc_oid "$unpushed")
+
+  printf "$pushed" > pushed.dat
+  git add .gitattributes pushed.dat
+  git commit -m "add pushed.dat"
+  git push origin master
+
+  printf "$unpushed" > unpushed.dat
+  git add unpushed.dat
+  git commit -m "add unpushed.dat"
+
+  assert_server_object "$reponame" "$pushed_oid"
+  refute_server_object "$reponame" "$unpushed_oid"
+
+  git lfs diff-remote 2>&1 | tee diff.log
+  grep "1 local object(s) not on origin" diff.log
+  grep " \* $unpushed_oid (8 B)" diff.log
+  [ "0" = "$(grep -c "$pushed_oid" diff.log)" ]
+  [ "0" = "$(grep -c "fetchable" diff.log)" ]
+
+  # objects missing locally but on the remote are fetchable
+  rm -rf .git/lfs/objects/${pushed_oid:0:2}/${pushed_oid:2:2}/$pushed_oid
+  refute_local_object "$pushed_oid"
+
+  git lfs diff-remote --fetchable origin 2>&1 | tee diff.log
+  grep "1 local object(s) not on origin" diff.log
+  grep "1 object(s) fetchable from origin" diff.log
+  grep " \* pushed.dat ($pushed_oid)" diff.log
+)
+end_test
+
+begin_test "diff-remote --json"
+(
+  set -e
+
+  reponame="diff-remote-json"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="a"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git lfs diff-remote --json > diff.json
+  expected="{\"remote\":\"origin\",\"unpushed\":[{\"oid\":\"$contents_oid\",\"size\":1}]}"
+  [ "$expected" = "$(cat diff.json)" ]
+
+  git push origin master
+  rm -rf .git/lfs/objects
+
+  git lfs diff-remote --json --fetchable > diff.json
+  expected="{\"remote\":\"origin\",\"unpushed\":[],\"fetchable\":[{\"name\":\"a.dat\",\"oid\":\"$contents_oid\",\"size\":1}]}"
+  [ "$expected" = "$(cat diff.json)" ]
+)
+end_test
+
+begin_test "diff-remote with invalid remote"
+(
+  set -e
+
+  reponame="diff-remote-invalid"
+  git init "$reponame"
+  cd "$reponame"
+
+  set +e
+  git lfs diff-remote not-a-remote 2>&1 | tee diff.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" = "$res" ]
+  grep "Invalid remote name \"not-a-remote\"" diff.log
+)
+end_test
+
+begin_test "diff-remote fails when the remote can't be asked"
+(
+  set -e
+
+  reponame="diff-remote-server-error"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="status-batch-500"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  set +e
+  git lfs diff-remote 2>&1 | tee diff.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "0" != "$res" ]
+  [ "0" = "$(grep -c "local object(s) not on origin" diff.log)" ]
+)
+end_test
@@ -0,0 +1,27 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "doctor"
+(
+  set -e
+
+  reponame="doctor"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs doctor | tee doctor.log
+  grep "Endpoint: $GITSERVER/$reponame.git/info/lfs" doctor.log
+  grep "Concurrent transfers: 3" doctor.log
+  grep "Object storage: $(native_path "$TRASHDIR/$reponame/.git/lfs/objects")" doctor.log
+  grep "No problems found." doctor.log
+
+  git config lfs.concurrenttransfers 0
+  git config lfs.batch false
+
+  git lfs doctor | tee doctor.log
+  grep "2 possible problem(s):" doctor.log
+  grep ' \* lfs.concurrenttransfers is "0", but 3 is used instead.' doctor.log
+  grep ' \* lfs.batch is false.' doctor.log
+)
+end_test
@@ -235,12 +235,16 @@ begin_test "fetch with missing object"
 
   # should return non-zero, but should also download all the other valid files too
   set +e
-  git lfs fetch origin master newbranch
-  fetch_exit=$?
+  git lfs fetch origin master newbranch 2>&1 | tee fetch.log
+  fetch_exit=${PIPESTATUS[0]}
   set -e
   [ "$fetch_exit" != "0" ]
   assert_local_object "$contents_oid" 1
   refute_local_object "$b_oid"
+
+  grep "Missing on server:" fetch.log
+  grep " \* b.dat ($b_oid)" fetch.log
+  grep "Warning: 1 object(s) missing on the server" fetch.log
 )
 end_test
 
@@ -534,3 +538,312 @@ begin_test "fetch with invalid remote"
   grep "Invalid remote name" fetch.log
 )
 end_test
+
+begin_test "fetch --tags"
+(
+  set -e
+
+  reponame="fetch-tags"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents_branch="on branch"
+  contents_branch_oid=$(calc_oid "$contents_branch")
+  contents_release="release asset"
+  contents_release_oid=$(calc_oid "$contents_release")
+  contents_light="lightweight asset"
+  contents_light_oid=$(calc_oid "$contents_light")
+
+  printf "$contents_branch" > branch.dat
+  git add .gitattributes branch.dat
+  git commit -m "add branch.dat"
+
+  # commits only reachable from tags, one annotated and one lightweight
+  git checkout -b release
+  printf "$contents_release" > release.dat
+  git add release.dat
+  git commit -m "add release.dat"
+  git tag -a -m "release" v1.0
+  printf "$contents_light" > light.dat
+  git add light.dat
+  git commit -m "add light.dat"
+  git tag v1.1
+
+  git push origin master v1.0 v1.1
+  git checkout master
+  git branch -D release
+
+  assert_server_object "$reponame" "$contents_branch_oid"
+  assert_server_object "$reponame" "$contents_release_oid"
+  assert_server_object "$reponame" "$contents_light_oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  [ -n "$(git tag -l v1.0)" ]
+
+  rm -rf .git/lfs/objects
+  git lfs fetch
+  assert_local_object "$contents_branch_oid" "${#contents_branch}"
+  refute_local_object "$contents_release_oid"
+  refute_local_object "$contents_light_oid"
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --tags 2>&1 | tee fetch.log
+  grep "Fetching tag v1.0" fetch.log
+  grep "Fetching tag v1.1" fetch.log
+  assert_local_object "$contents_branch_oid" "${#contents_branch}"
+  assert_local_object "$contents_release_oid" "${#contents_release}"
+  assert_local_object "$contents_light_oid" "${#contents_light}"
+
+  git lfs fetch --all --tags 2>&1 | tee fetch.log
+  grep "Cannot combine --all with ref arguments, --recent or --tags" fetch.log
+)
+end_test
+
+begin_test "fetch with GIT_LFS_REMOTE"
+(
+  set -e
+
+  reponame="fetch-env-remote"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$reponame-mirror"
+
+  clone_repo "$reponame" "$reponame"
+  git remote add mirror "$GITSERVER/$reponame-mirror"
+
+  git lfs track "*.dat"
+  contents="env remote"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+  git push mirror master 2>&1 | tee push.log
+
+  delete_server_object "$reponame-mirror" "$contents_oid"
+  refute_server_object "$reponame-mirror" "$contents_oid"
+  assert_server_object "$reponame" "$contents_oid"
+
+  # the remote from the environment is used when none is given
+  rm -rf .git/lfs/objects
+  GIT_LFS_REMOTE=mirror git lfs fetch 2>&1 | tee fetch.log
+  refute_local_object "$contents_oid"
+
+  # an explicit remote takes precedence
+  GIT_LFS_REMOTE=mirror git lfs fetch origin
+  assert_local_object "$contents_oid" 10
+
+  rm -rf .git/lfs/objects
+  GIT_LFS_REMOTE=origin git lfs fetch
+  assert_local_object "$contents_oid" 10
+
+  rm -rf .git/lfs/objects
+  GIT_LFS_REMOTE=missing git lfs fetch 2>&1 | tee fetch.log
+  grep "Invalid remote name \"missing\" in GIT_LFS_REMOTE" fetch.log
+  refute_local_object "$contents_oid"
+)
+end_test
+
+begin_test "fetch --keep-going"
+(
+  set -e
+
+  reponame="fetch-keep-going"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="keep going"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+
+  set +e
+  git lfs fetch origin no-such-ref master > fetch.log 2>&1
+  res=$?
+  set -e
+  cat fetch.log
+  [ "$res" -ne 0 ]
+  refute_local_object "$contents_oid"
+
+  set +e
+  git lfs fetch --keep-going origin no-such-ref master > fetch.log 2>&1
+  res=$?
+  set -e
+  cat fetch.log
+  [ "$res" -ne 0 ]
+  grep "Invalid ref argument: no-such-ref" fetch.log
+  grep "Fetching master" fetch.log
+  grep "Failed to fetch 1 ref(s):" fetch.log
+  grep " \* no-such-ref" fetch.log
+  assert_local_object "$contents_oid" 10
+)
+end_test
+
+begin_test "fetch --check"
+(
+  set -e
+
+  reponame="fetch-check"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  for name in a b c; do
+    printf "check $name" > $name.dat
+  done
+  a_oid=$(calc_oid "check a")
+  b_oid=$(calc_oid "check b")
+  c_oid=$(calc_oid "check c")
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+  git push origin master
+
+  # c.dat is only available locally
+  git add c.dat
+  git commit -m "add c.dat"
+  delete_server_object "$reponame" "$b_oid"
+  rm -rf .git/lfs/objects/${a_oid:0:2} .git/lfs/objects/${b_oid:0:2}
+  refute_server_object "$reponame" "$c_oid"
+
+  set +e
+  git lfs fetch --check 2>&1 | tee fetch.log
+  fetch_exit=${PIPESTATUS[0]}
+  set -e
+  [ "$fetch_exit" != "0" ]
+
+  grep "Available:" fetch.log
+  grep " \* a.dat ($a_oid)" fetch.log
+  grep " \* c.dat ($c_oid)" fetch.log
+  grep "Missing on server:" fetch.log
+  grep " \* b.dat ($b_oid)" fetch.log
+  grep "Warning: 1 object(s) missing on the server" fetch.log
+  refute_local_object "$a_oid"
+  refute_local_object "$b_oid"
+
+  git lfs fetch --check --prune 2>&1 | tee fetch.log
+  grep "Cannot combine --check with --prune or --json" fetch.log
+)
+end_test
+
+begin_test "fetch --json"
+(
+  set -e
+
+  reponame="fetch-json"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="json"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --json > fetch.json 2> fetch.log
+  cat fetch.json
+
+  [ "2" -eq "$(wc -l < fetch.json)" ]
+  grep "{\"type\":\"object\",\"name\":\"a.dat\",\"oid\":\"$contents_oid\",\"size\":4}" fetch.json
+  grep "{\"type\":\"summary\",\"objects\":1,\"bytes\":4,\"missing\":0,\"failed\":0,\"errors\":\[\]}" fetch.json
+  grep "Fetching master" fetch.log
+  assert_local_object "$contents_oid" 4
+)
+end_test
+
+begin_test "fetch --verbose"
+(
+  set -e
+
+  reponame="fetch-verbose"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="verbose"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --verbose 2>&1 | tee fetch.log
+  grep "Git LFS: (1 of 1 files)" fetch.log
+  assert_local_object "$contents_oid" 7
+)
+end_test
+
+begin_test "fetch --resume discards state when the ref moves"
+(
+  set -e
+
+  reponame="fetch-resume"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  a="a"
+  a_oid=$(calc_oid "$a")
+  b="b"
+  b_oid=$(calc_oid "$b")
+  c="c"
+  c_oid=$(calc_oid "$c")
+
+  printf "$a" > a.dat
+  printf "$b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+  git push origin master
+
+  # b is missing on the server, so the first fetch can't finish
+  delete_server_object "$reponame" "$b_oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+
+  set +e
+  git lfs fetch --resume 2>&1 | tee fetch.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "0" != "$res" ]
+  assert_local_object "$a_oid" 1
+  refute_local_object "$b_oid"
+  [ "1" = "$(ls .git/lfs/fetch-state | wc -l)" ]
+
+  # the ref hasn't changed, so the saved work list is used
+  set +e
+  git lfs fetch --resume 2>&1 | tee fetch.log
+  set -e
+  grep "Resuming fetch of master" fetch.log
+
+  # advance the ref; the saved work list doesn't include c.dat
+  cd "../$reponame"
+  printf "$c" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+  git push origin master
+  git lfs push --object-id origin "$b_oid"
+
+  cd "../$reponame-clone"
+  GIT_LFS_SKIP_SMUDGE=1 git pull origin master
+
+  git lfs fetch --resume 2>&1 | tee fetch.log
+  grep "Discarding stale fetch state for master" fetch.log
+  [ "0" = "$(grep -c "Resuming fetch" fetch.log)" ]
+  assert_local_object "$b_oid" 1
+  assert_local_object "$c_oid" 1
+
+  # a successful fetch leaves no state behind
+  [ "0" = "$(ls .git/lfs/fetch-state | wc -l)" ]
+)
+end_test
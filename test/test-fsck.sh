@@ -104,6 +104,101 @@ begin_test "fsck dry run"
 )
 end_test
 
+begin_test "fsck progress"
+(
+  set -e
+
+  reponame="fsck-progress"
+  git init $reponame
+  cd $reponame
+
+  git lfs track *.dat
+  for i in 1 2 3; do
+    echo "test data $i" > $i.dat
+  done
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  git lfs fsck 2> fsck.err | tee fsck.log
+  [ "Git LFS fsck OK" = "$(cat fsck.log)" ]
+  grep "Checking object 3/3" fsck.err
+  grep "Checked 3 object(s)" fsck.err
+
+  oid=$(calc_oid "test data 1
+")
+  echo "CORRUPTION" >> .git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid
+
+  git lfs fsck --dry-run 2> fsck.err | tee fsck.log
+  [ "Object 1.dat ($oid) is corrupt" = "$(cat fsck.log)" ]
+  grep "Checked 3 object(s)" fsck.err
+)
+end_test
+
+begin_test "fsck catches objects of the wrong size without hashing them"
+(
+  set -e
+
+  reponame="fsck-size"
+  git init $reponame
+  cd $reponame
+
+  git lfs track *.dat
+  echo "test data" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "first commit"
+
+  aOid=$(calc_oid "test data
+")
+  aPath=".git/lfs/objects/${aOid:0:2}/${aOid:2:2}/$aOid"
+  printf "test" > "$aPath"
+
+  GIT_TRACE=1 git lfs fsck --dry-run 2> fsck.err | tee fsck.log
+  [ "Object a.dat ($aOid) is corrupt" = "$(cat fsck.log)" ]
+  grep "fsck: $aOid is 4 bytes, expected 10" fsck.err
+)
+end_test
+
+begin_test "fsck --pointers"
+(
+  set -e
+
+  reponame="fsck-pointers"
+  git init $reponame
+  cd $reponame
+
+  git lfs track *.dat
+  echo "test data" > a.dat
+  echo "test data 2" > b.dat
+  echo "test data 3" > c.dat
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  [ "Git LFS fsck OK" = "$(git lfs fsck --pointers)" ]
+
+  aOid=$(git log --patch a.dat | grep "^+oid" | cut -d ":" -f 2)
+  aOid12=$(echo $aOid | cut -b 1-2)
+  aOid34=$(echo $aOid | cut -b 3-4)
+
+  # a.dat is changed without being added again, b.dat is changed and staged,
+  # and c.dat is deleted from the working tree
+  echo "changed" >> a.dat
+  echo "changed" >> b.dat
+  git add b.dat
+  rm c.dat
+
+  [ "File a.dat does not match its pointer ($aOid)" = "$(git lfs fsck --pointers)" ]
+
+  # The objects aren't checked unless asked for as well
+  echo "CORRUPTION" >> .git/lfs/objects/$aOid12/$aOid34/$aOid
+  git lfs fsck --pointers | tee fsck.log
+  [ "0" -eq "$(grep -c "is corrupt" fsck.log)" ]
+
+  git lfs fsck --pointers --objects --dry-run | tee fsck.log
+  grep "File a.dat does not match its pointer ($aOid)" fsck.log
+  grep "Object a.dat ($aOid) is corrupt" fsck.log
+)
+end_test
+
 begin_test "fsck: outside git repository"
 (
   set +e
@@ -88,3 +88,25 @@ begin_test "list locks with pagination"
   grep "4 lock(s) matched query" locks.log
 )
 end_test
+
+begin_test "list locks older than a duration"
+(
+  set -e
+
+  setup_remote_repo_with_file "locks_list_older_than" "old.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs lock "old.dat" | tee lock.log
+  assert_server_lock "$(grep -oh "\((.*)\)" lock.log | tr -d "()")"
+
+  GITLFSLOCKSENABLED=1 git lfs locks --path "old.dat" --older-than 1h | tee locks.log
+  grep "0 lock(s) matched query" locks.log
+
+  sleep 1
+  GITLFSLOCKSENABLED=1 git lfs locks --path "old.dat" --older-than 1ms | tee locks.log
+  grep "1 lock(s) matched query" locks.log
+  grep "old.dat" locks.log
+
+  GITLFSLOCKSENABLED=1 git lfs locks --older-than yesterday 2>&1 | tee locks.log
+  grep "Invalid duration for --older-than: \"yesterday\"" locks.log
+)
+end_test
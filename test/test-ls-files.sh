@@ -109,3 +109,100 @@ a1fff0ffefb9eace7230c24e50731f0a91c62f9cefdfe77121c2f607125dffae * two.tgz")"
   [ "$expected" = "$(git lfs ls-files --long)" ]
 )
 end_test
+
+begin_test "ls-files: --size and --json"
+(
+  set -e
+
+  reponame="ls-files-size-json"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "some data" > some.dat
+  printf "more data here" > more.dat
+  git add .gitattributes some.dat more.dat
+  git commit -m "add files"
+  rm more.dat
+
+  some_oid=$(calc_oid "some data")
+  more_oid=$(calc_oid "more data here")
+
+  git lfs ls-files --size | tee ls.log
+  grep "${some_oid:0:10} \* some.dat (9 B)" ls.log
+  grep "${more_oid:0:10} - more.dat (14 B)" ls.log
+
+  git lfs ls-files --json | tee ls.json
+  [ "2" -eq "$(wc -l < ls.json)" ]
+  grep "{\"name\":\"some.dat\",\"oid\":\"$some_oid\",\"size\":9,\"present\":true}" ls.json
+  grep "{\"name\":\"more.dat\",\"oid\":\"$more_oid\",\"size\":14,\"present\":false}" ls.json
+)
+end_test
+
+begin_test "ls-files: --include and --exclude"
+(
+  set -e
+
+  reponame="ls-files-include-exclude"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  mkdir -p images/raw docs
+  echo "a" > a.dat
+  echo "image" > images/image.dat
+  echo "raw" > images/raw/raw.dat
+  echo "doc" > docs/doc.dat
+  git add .gitattributes a.dat images docs
+  git commit -m "add files"
+
+  git lfs ls-files --include "images" | tee ls.log
+  [ "2" -eq "$(wc -l < ls.log)" ]
+  grep "images/image.dat" ls.log
+  grep "images/raw/raw.dat" ls.log
+
+  git lfs ls-files --include "images" --exclude "images/raw" | tee ls.log
+  [ "1" -eq "$(wc -l < ls.log)" ]
+  grep "images/image.dat" ls.log
+
+  git lfs ls-files -X "images,docs" | tee ls.log
+  [ "1" -eq "$(wc -l < ls.log)" ]
+  grep "a.dat" ls.log
+
+  git config lfs.fetchexclude "images"
+  git lfs ls-files | tee ls.log
+  [ "4" -eq "$(wc -l < ls.log)" ]
+)
+end_test
+
+begin_test "ls-files: --deleted"
+(
+  set -e
+
+  reponame="ls-files-deleted"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  echo "present" > present.dat
+  echo "missing" > missing.dat
+  git add .gitattributes present.dat missing.dat
+  git commit -m "add files"
+
+  git lfs ls-files --deleted | tee ls.log
+  [ "0" -eq "$(wc -l < ls.log)" ]
+
+  delete_local_object "$(calc_oid "missing\n")"
+
+  git lfs ls-files --deleted | tee ls.log
+  [ "1" -eq "$(wc -l < ls.log)" ]
+  grep "missing.dat" ls.log
+
+  git lfs ls-files --not-downloaded | tee ls.log
+  [ "1" -eq "$(wc -l < ls.log)" ]
+  grep "missing.dat" ls.log
+
+  # present in the working tree but not in the object store
+  grep "* missing.dat" ls.log
+)
+end_test
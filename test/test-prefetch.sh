@@ -0,0 +1,146 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+reponame="$(basename "$0" ".sh")"
+contents_a="a"
+contents_a_oid=$(calc_oid "$contents_a")
+contents_b="b"
+contents_b_oid=$(calc_oid "$contents_b")
+contents_c="c"
+contents_c_oid=$(calc_oid "$contents_c")
+
+begin_test "init for prefetch"
+(
+  set -e
+
+  setup_remote_repo $reponame
+  clone_repo $reponame repo
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  mkdir -p module-a module-b
+  printf "$contents_a" > module-a/a.dat
+  printf "$contents_b" > module-b/b.dat
+
+  git add .gitattributes module-a module-b
+  git commit -m "add modules" 2>&1 | tee commit.log
+  grep "master (root-commit)" commit.log
+
+  git checkout -b other
+  printf "$contents_c" > module-a/c.dat
+  git add module-a/c.dat
+  git commit -m "add module-a/c.dat"
+
+  git push origin master other 2>&1 | tee push.log
+  grep "master -> master" push.log
+  grep "other -> other" push.log
+
+  assert_server_object "$reponame" "$contents_a_oid"
+  assert_server_object "$reponame" "$contents_b_oid"
+  assert_server_object "$reponame" "$contents_c_oid"
+
+  # This clone is used for subsequent tests
+  clone_repo "$reponame" clone
+  git checkout master
+)
+end_test
+
+begin_test "prefetch only fetches objects under the pathspec"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  git lfs prefetch -- module-a 2>&1 | tee prefetch.log
+  grep "Prefetching master" prefetch.log
+
+  assert_local_object "$contents_a_oid" 1
+  refute_local_object "$contents_b_oid"
+  refute_local_object "$contents_c_oid"
+)
+end_test
+
+begin_test "prefetch with pathspec relative to subdirectory"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  cd module-b
+  git lfs prefetch -- b.dat
+
+  cd ..
+  refute_local_object "$contents_a_oid"
+  assert_local_object "$contents_b_oid" 1
+)
+end_test
+
+begin_test "prefetch at ref"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  git lfs prefetch --ref=origin/other -- module-a/c.dat 2>&1 | tee prefetch.log
+  grep "Prefetching origin/other" prefetch.log
+
+  refute_local_object "$contents_a_oid"
+  refute_local_object "$contents_b_oid"
+  assert_local_object "$contents_c_oid" 1
+)
+end_test
+
+begin_test "prefetch does not update the working copy"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+  rm -f module-a/a.dat
+
+  git lfs prefetch -- module-a
+
+  assert_local_object "$contents_a_oid" 1
+  [ ! -e "module-a/a.dat" ]
+)
+end_test
+
+begin_test "prefetch with GIT_LFS_REMOTE"
+(
+  set -e
+  setup_remote_repo "$reponame-mirror"
+  cd "$TRASHDIR/clone"
+
+  # the mirror has the commits, but none of the Git LFS objects
+  git remote add mirror "$GITSERVER/$reponame-mirror"
+  git push --no-verify mirror master
+  refute_server_object "$reponame-mirror" "$contents_a_oid"
+  rm -rf .git/lfs/objects
+
+  GIT_LFS_REMOTE=mirror git lfs prefetch -- module-a 2>&1 | tee prefetch.log
+  refute_local_object "$contents_a_oid"
+
+  GIT_LFS_REMOTE=missing git lfs prefetch -- module-a 2>&1 | tee prefetch.log
+  grep "Invalid remote name \"missing\" in GIT_LFS_REMOTE" prefetch.log
+  refute_local_object "$contents_a_oid"
+
+  GIT_LFS_REMOTE=origin git lfs prefetch -- module-a
+  assert_local_object "$contents_a_oid" 1
+)
+end_test
+
+begin_test "prefetch requires a path"
+(
+  set -e
+  cd clone
+
+  set +e
+  git lfs prefetch 2>&1 | tee prefetch.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Usage: git lfs prefetch" prefetch.log
+)
+end_test
@@ -210,6 +210,60 @@ begin_test "prune keep unpushed"
 )
 end_test
 
+begin_test "prune keep stashed"
+(
+  set -e
+
+  reponame="prune_keep_stashed"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_base="Keep: commit the stash was made on"
+  content_stashed="Keep: stashed change"
+  content_head="Keep: HEAD"
+  oid_base=$(calc_oid "$content_base")
+  oid_stashed=$(calc_oid "$content_stashed")
+  oid_head=$(calc_oid "$content_head")
+
+  printf "$content_base" > file.dat
+  git add .gitattributes file.dat
+  git commit -m "base"
+  git push origin master
+
+  printf "$content_stashed" > file.dat
+  git add file.dat
+  git stash
+
+  printf "$content_head" > file.dat
+  git add file.dat
+  git commit -m "head"
+  git push origin master
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+
+  git lfs prune --verbose 2>&1 | tee prune.log
+  grep "3 local objects, 3 retained" prune.log
+  grep "Nothing to prune" prune.log
+  assert_local_object "$oid_base" "${#content_base}"
+  assert_local_object "$oid_stashed" "${#content_stashed}"
+
+  git stash drop
+
+  git lfs prune --verbose 2>&1 | tee prune.log
+  grep "3 local objects, 1 retained" prune.log
+  grep "Pruning 2 files" prune.log
+  refute_local_object "$oid_base"
+  refute_local_object "$oid_stashed"
+  assert_local_object "$oid_head" "${#content_head}"
+)
+end_test
+
 begin_test "prune keep recent"
 (
   set -e
@@ -537,4 +591,107 @@ begin_test "prune verify"
   refute_local_object "$oid_commit3"
 
 )
-end_test
\ No newline at end of file
+end_test
+
+begin_test "prune grace period"
+(
+  set -e
+
+  reponame="prune_grace"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_committed="Keep: committed and current"
+  content_new="Keep: added within the grace period"
+  content_old="To delete: added before the grace period"
+  oid_committed=$(calc_oid "$content_committed")
+  oid_new=$(calc_oid "$content_new")
+  oid_old=$(calc_oid "$content_old")
+
+  printf "$content_committed" > committed.dat
+  git add .gitattributes committed.dat
+  git commit -m "add committed.dat"
+  git push origin master
+
+  # add but never commit, as if a commit is still to come
+  printf "$content_new" > new.dat
+  git add new.dat
+  printf "$content_old" > old.dat
+  git add old.dat
+  git rm --cached -q new.dat old.dat
+
+  touch -d "$(get_date -2H)" ".git/lfs/objects/${oid_old:0:2}/${oid_old:2:2}/$oid_old"
+
+  # without a grace period, all unreferenced objects go
+  git lfs prune --dry-run --verbose 2>&1 | tee prune.log
+  grep "2 files would be pruned" prune.log
+
+  git config lfs.prune.grace 1h
+  git lfs prune --verbose 2>&1 | tee prune.log
+  grep "Pruning 1 files" prune.log
+  grep "$oid_old" prune.log
+
+  assert_local_object "$oid_committed" "${#content_committed}"
+  assert_local_object "$oid_new" "${#content_new}"
+  refute_local_object "$oid_old"
+)
+end_test
+
+begin_test "prune --older-than"
+(
+  set -e
+
+  reponame="prune_older_than"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_new="Keep: stored recently"
+  content_old="To delete: stored long ago"
+  oid_new=$(calc_oid "$content_new")
+  oid_old=$(calc_oid "$content_old")
+
+  printf "$content_new" > new.dat
+  printf "$content_old" > old.dat
+  git add .gitattributes new.dat old.dat
+  git commit -m "add new.dat and old.dat"
+  git rm -q new.dat old.dat
+  git commit -m "remove new.dat and old.dat"
+  git push origin master
+
+  touch -d "$(get_date -3d)" ".git/lfs/objects/${oid_old:0:2}/${oid_old:2:2}/$oid_old"
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+  git config lfs.prune.grace 100h
+
+  # a shorter --older-than must not cut lfs.prune.grace short
+  git lfs prune --older-than 48h --dry-run --verbose 2>&1 | tee prune.log
+  [ "0" -eq "$(grep -c "$oid_old" prune.log)" ]
+  [ "0" -eq "$(grep -c "$oid_new" prune.log)" ]
+
+  git config lfs.prune.grace 1h
+
+  git lfs prune --older-than 48h --dry-run --verbose 2>&1 | tee prune.log
+  grep "1 files would be pruned" prune.log
+  grep "$oid_old" prune.log
+  [ "0" -eq "$(grep -c "$oid_new" prune.log)" ]
+  assert_local_object "$oid_old" "${#content_old}"
+
+  git lfs prune --older-than 48h --verbose 2>&1 | tee prune.log
+  grep "Pruning 1 files" prune.log
+  refute_local_object "$oid_old"
+  assert_local_object "$oid_new" "${#content_new}"
+
+  git lfs prune --older-than 2x 2>&1 | tee prune.log
+  grep "Invalid duration for --older-than: \"2x\"" prune.log
+)
+end_test
@@ -154,3 +154,174 @@ begin_test "pull: outside git repository"
   grep "Not in a git repository" pull.log
 )
 end_test
+
+begin_test "pull --require-all with missing objects"
+(
+  set -e
+
+  reponame="pull-require-all"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" pull-require-all
+
+  git lfs track "*.dat"
+
+  contents_present="present"
+  contents_present_oid=$(calc_oid "$contents_present")
+  contents_missing="missing"
+  contents_missing_oid=$(calc_oid "$contents_missing")
+
+  printf "$contents_present" > present.dat
+  printf "$contents_missing" > missing.dat
+  git add .gitattributes present.dat missing.dat
+  git commit -m "add files"
+  git push origin master
+
+  delete_server_object "$reponame" "$contents_missing_oid"
+  refute_server_object "$reponame" "$contents_missing_oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" pull-require-all-clone
+
+  # lenient by default
+  git lfs pull 2>&1 | tee pull.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+  assert_local_object "$contents_present_oid" "${#contents_present}"
+  refute_local_object "$contents_missing_oid"
+
+  rm -rf .git/lfs/objects
+
+  set +e
+  git lfs pull --require-all 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" -eq "$res" ]
+  grep "Could not pull all Git LFS objects, 1 missing:" pull.log
+  grep "  missing.dat ($contents_missing_oid)" pull.log
+  [ "0" -eq "$(grep -c "present.dat ($contents_present_oid)" pull.log)" ]
+  assert_local_object "$contents_present_oid" "${#contents_present}"
+  [ "$contents_present" = "$(cat present.dat)" ]
+
+  # objects filtered out are not required
+  git lfs pull --require-all --exclude="missing.dat"
+
+  git config lfs.pull.requireall true
+
+  set +e
+  git lfs pull 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "2" -eq "$res" ]
+  grep "  missing.dat ($contents_missing_oid)" pull.log
+)
+end_test
+
+begin_test "pull --dry-run"
+(
+  set -e
+
+  reponame="pull-dry-run"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" pull-dry-run
+
+  git lfs track "*.dat"
+
+  contents_present="present"
+  contents_present_oid=$(calc_oid "$contents_present")
+  contents_missing="missing data"
+  contents_missing_oid=$(calc_oid "$contents_missing")
+
+  printf "$contents_present" > present.dat
+  printf "$contents_missing" > missing.dat
+  git add .gitattributes present.dat missing.dat
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" pull-dry-run-clone
+  git lfs fetch --include="present.dat"
+  assert_local_object "$contents_present_oid" "${#contents_present}"
+  refute_local_object "$contents_missing_oid"
+
+  git lfs pull --dry-run 2>&1 | tee pull.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  printf "download missing.dat (12 B)\ncheckout present.dat\n2 file(s), 1 object(s) to download (12 B)\n" > expected.log
+  diff -u expected.log pull.log
+
+  # nothing was downloaded or checked out
+  refute_local_object "$contents_missing_oid"
+  grep "oid sha256:$contents_present_oid" present.dat
+  grep "oid sha256:$contents_missing_oid" missing.dat
+)
+end_test
+
+begin_test "pull with several checkout workers"
+(
+  set -e
+
+  reponame="pull-checkout-workers"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" pull-checkout-workers
+
+  git lfs track "*.dat"
+  mkdir dir
+  for i in $(seq 1 50); do
+    printf "file $i" > "file$i.dat"
+    printf "file $i" > "dir/file$i.dat"
+  done
+  git add .gitattributes *.dat dir
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" pull-checkout-workers-clone
+  git config lfs.checkout.workers 4
+
+  git lfs pull
+
+  for i in $(seq 1 50); do
+    [ "file $i" = "$(cat "file$i.dat")" ]
+    [ "file $i" = "$(cat "dir/file$i.dat")" ]
+  done
+
+  # every file was updated in the index, so none show as modified
+  [ "101" -eq "$(git ls-files | wc -l)" ]
+  [ -z "$(git diff-files --name-only)" ]
+)
+end_test
+
+begin_test "pull with GIT_LFS_REMOTE"
+(
+  set -e
+
+  reponame="pull-env-remote"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$reponame-mirror"
+
+  clone_repo "$reponame" "$reponame"
+  git remote add mirror "$GITSERVER/$reponame-mirror"
+
+  git lfs track "*.dat"
+  contents="env remote"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push mirror master
+
+  refute_server_object "$reponame" "$contents_oid"
+  assert_server_object "$reponame-mirror" "$contents_oid"
+
+  rm -rf .git/lfs/objects a.dat
+
+  # origin does not have the object, so pulling from it leaves the pointer
+  git lfs pull 2>&1 | tee pull.log
+  refute_local_object "$contents_oid"
+
+  GIT_LFS_REMOTE=mirror git lfs pull 2>&1 | tee pull.log
+  assert_local_object "$contents_oid" 10
+  [ "$contents" = "$(cat a.dat)" ]
+)
+end_test
@@ -572,3 +572,57 @@ begin_test "push (with invalid object size)"
   refute_server_object "$reponame" "$(calc_oid "$contents")"
 )
 end_test
+
+begin_test "push with GIT_LFS_REMOTE"
+(
+  set -e
+
+  reponame="push-env-remote"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$reponame-mirror"
+
+  clone_repo "$reponame" "$reponame"
+  git remote add mirror "$GITSERVER/$reponame-mirror"
+
+  git lfs track "*.dat"
+  contents="env remote"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  GIT_LFS_REMOTE=mirror git lfs push --all 2>&1 | tee push.log
+  assert_server_object "$reponame-mirror" "$contents_oid"
+  refute_server_object "$reponame" "$contents_oid"
+
+  # an explicit remote takes precedence
+  GIT_LFS_REMOTE=mirror git lfs push origin master 2>&1 | tee push.log
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
+
+begin_test "push with lfs.verifyupload"
+(
+  set -e
+
+  reponame="push-verify-upload"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="verify upload"
+  oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git config lfs.verifyupload true
+  GIT_TRACE=1 git lfs push origin master 2>&1 | tee push.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  assert_server_object "$reponame" "$oid"
+
+  # one batch request to upload, and another to check the server has it
+  [ "2" -eq "$(grep -c "tq: sending batch of size 1" push.log)" ]
+)
+end_test
@@ -85,3 +85,90 @@ begin_test "resume-http-range-fallback"
 )
 end_test
 
+begin_test "resume-http-range: corrupt partial download"
+(
+  set -e
+
+  reponame="resume-http-range-corrupt"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" $reponame
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  contents="status-batch-resume-206"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat
+  git add .gitattributes
+  git commit -m "add a.dat" 2>&1 | tee commit.log
+  git push origin master
+
+  assert_server_object "$reponame" "$contents_oid"
+
+  # leave a partial download whose bytes don't match the object
+  rm -rf .git/lfs/objects
+  mkdir -p .git/lfs/objects/incomplete
+  printf "XXXXXXXXXX" > ".git/lfs/objects/incomplete/$contents_oid.tmp"
+
+  # resuming from it must not succeed, and must not be tried again
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchcorrupt.log
+  grep "xfer: discarding partial download of \"$contents_oid\" resumed from byte 10" fetchcorrupt.log
+  [ "XXXXXXXXXX" != "$(cat ".git/lfs/objects/incomplete/$contents_oid.tmp")" ]
+
+  # a later fetch completes the object from good bytes
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchresume.log
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
+begin_test "resume-http-range: partial download locked by another process"
+(
+  set -e
+
+  reponame="resume-http-range-locked"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" $reponame
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  contents="status-batch-resume-206"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat
+  git add .gitattributes
+  git commit -m "add a.dat" 2>&1 | tee commit.log
+  git push origin master
+
+  assert_server_object "$reponame" "$contents_oid"
+
+  # leave a partial download behind, as if the process had been killed
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetchinterrupted.log
+  refute_local_object "$contents_oid"
+  [ -s ".git/lfs/objects/incomplete/$contents_oid.tmp" ]
+
+  # another process which is still running holds the lock, so the partial
+  # download must be left alone
+  printf "$$" > ".git/lfs/objects/incomplete/$contents_oid.lock"
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchlocked.log
+  grep "Download of $contents_oid is in progress in process $$" fetchlocked.log
+  [ "0" -eq "$(grep -c "Attempting to resume download" fetchlocked.log)" ]
+  refute_local_object "$contents_oid"
+
+  # the process holding the lock has gone away, so its partial download is
+  # discovered and resumed
+  printf "2147483646" > ".git/lfs/objects/incomplete/$contents_oid.lock"
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetchresume.log
+  grep "xfer: taking over stale download lock for \"$contents_oid\"" fetchresume.log
+  grep "xfer: Attempting to resume download of \"$contents_oid\"" fetchresume.log
+  grep "xfer: server accepted resume" fetchresume.log
+  assert_local_object "$contents_oid" "${#contents}"
+  [ ! -e ".git/lfs/objects/incomplete/$contents_oid.lock" ]
+)
+end_test
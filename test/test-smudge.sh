@@ -56,6 +56,33 @@ begin_test "smudge with temp file"
 )
 end_test
 
+begin_test "smudge empty object without a server"
+(
+  set -e
+
+  cd repo
+
+  empty="e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+  rm -rf .git/lfs/objects
+  output="$(pointer "$empty" 0 | git -c lfs.url=http://127.0.0.1:1/nowhere lfs smudge)"
+  [ -z "$output" ]
+  assert_local_object "$empty" 0
+
+  rm -rf .git/lfs/objects
+  set +e
+  pointer "$empty" 0 | git -c lfs.url=http://127.0.0.1:1/nowhere \
+    -c lfs.transfer.skipemptyobjects=false lfs smudge > smudge.log 2>&1
+  res=$?
+  set -e
+
+  if [ "$res" = "0" ]; then
+    echo "expected smudge to contact the server"
+    exit 1
+  fi
+  refute_local_object "$empty"
+)
+end_test
+
 begin_test "smudge with invalid pointer"
 (
   set -e
@@ -233,3 +260,92 @@ begin_test "smudge skip download failure"
 
 )
 end_test
+
+begin_test "smudge with lfs.smudge.verify"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-verify"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" smudgeverify
+
+  git lfs track "*.dat"
+  echo "smudge a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  oid="fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254"
+  pointer="$(pointer $oid 9)"
+  objectpath=".git/lfs/objects/fc/f5/$oid"
+
+  git config lfs.smudge.verify true
+  [ "smudge a" = "$(echo "$pointer" | git lfs smudge a.dat)" ]
+
+  # corrupt the object, keeping its size
+  chmod u+w "$objectpath"
+  printf "smudge b\n" > "$objectpath"
+
+  git config lfs.smudge.verify false
+  [ "smudge b" = "$(echo "$pointer" | git lfs smudge a.dat)" ]
+
+  git config lfs.smudge.verify true
+  set +e
+  echo "$pointer" | git lfs smudge a.dat > smudge.log 2>&1
+  res=${PIPESTATUS[1]}
+  set -e
+  if [ "$res" = "0" ]; then
+    echo "smudge of a corrupt object should fail"
+    cat smudge.log
+    exit 1
+  fi
+
+  git lfs logs last | grep "does not match expected $oid"
+  [ ! -e "$objectpath" ]
+  [ "smudge b" = "$(cat .git/lfs/bad/$oid)" ]
+
+  # the failure is a smudge error, which lfs.skipdownloaderrors lets through
+  # with the pointer written after whatever was already written
+  cp ".git/lfs/bad/$oid" "$objectpath"
+  git config lfs.skipdownloaderrors true
+  echo "$pointer" | git lfs smudge a.dat > smudged.dat
+  grep "oid sha256:$oid" smudged.dat
+  [ ! -e "$objectpath" ]
+)
+end_test
+
+begin_test "smudge with invalid extensions"
+(
+  set -e
+
+  reponame="smudge_invalid_extensions"
+  git init "$reponame"
+  cd "$reponame"
+
+  git config lfs.extension.foo.clean "tr a-z A-Z"
+  git config lfs.extension.foo.smudge "tr A-Z a-z"
+  git config lfs.extension.foo.priority 0
+
+  git lfs track "*.dat"
+  printf "smudge extensions" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git cat-file -p :a.dat | grep "ext-0-foo"
+
+  # an extension the pointer doesn't use clashes with one it does
+  git config lfs.extension.bar.clean "cat"
+  git config lfs.extension.bar.smudge "cat"
+  git config lfs.extension.bar.priority 0
+
+  set +e
+  git cat-file -p :a.dat | git lfs smudge a.dat > smudge.log 2>&1
+  res=${PIPESTATUS[1]}
+  set -e
+  if [ "$res" = "0" ]; then
+    echo "smudge with invalid extensions should fail"
+    cat smudge.log
+    exit 1
+  fi
+
+  git lfs logs last | grep "duplicate priority 0 on bar and foo"
+)
+end_test
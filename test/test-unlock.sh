@@ -50,3 +50,115 @@ begin_test "unlocking a lock without sufficient info"
   assert_server_lock $id
 )
 end_test
+
+begin_test "unlocking multiple locks by path"
+(
+  set -e
+
+  reponame="unlock_multiple"
+  setup_remote_repo "remote_$reponame"
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat"
+  for name in m_1 m_2 m_3; do
+    echo "$name" > "$name.dat"
+  done
+  git add .gitattributes m_1.dat m_2.dat m_3.dat
+  git commit -m "add files"
+  git push origin master
+
+  GITLFSLOCKSENABLED=1 git lfs lock "m_1.dat" | tee lock.log
+  id1=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  GITLFSLOCKSENABLED=1 git lfs lock "m_3.dat" | tee lock.log
+  id3=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+
+  set +e
+  GITLFSLOCKSENABLED=1 git lfs unlock "m_1.dat" "m_2.dat" "m_3.dat" 2>&1 | tee unlock.log
+  unlock_exit=${PIPESTATUS[0]}
+  set -e
+  [ "$unlock_exit" != "0" ]
+
+  grep "'m_1.dat' was unlocked ($id1)" unlock.log
+  grep "'m_2.dat' was not unlocked: lfs: no matching locks found" unlock.log
+  grep "'m_3.dat' was unlocked ($id3)" unlock.log
+  grep "2 of 3 lock(s) unlocked" unlock.log
+  refute_server_lock $id1
+  refute_server_lock $id3
+)
+end_test
+
+begin_test "unlocking all of my locks"
+(
+  set -e
+
+  reponame="unlock_all_mine"
+  setup_remote_repo "remote_$reponame"
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat"
+  echo "mine" > mine_1.dat
+  echo "mine" > mine_2.dat
+  echo "theirs" > theirs.dat
+  git add .gitattributes mine_1.dat mine_2.dat theirs.dat
+  git commit -m "add files"
+  git push origin master
+
+  git config user.email "all-mine@example.com"
+  GITLFSLOCKSENABLED=1 git lfs lock "mine_1.dat" | tee lock.log
+  id1=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  GITLFSLOCKSENABLED=1 git lfs lock "mine_2.dat" | tee lock.log
+  id2=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+
+  git config user.email "someone-else@example.com"
+  GITLFSLOCKSENABLED=1 git lfs lock "theirs.dat" | tee lock.log
+  id3=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+
+  git config user.email "all-mine@example.com"
+  GITLFSLOCKSENABLED=1 git lfs unlock --all-mine 2>&1 | tee unlock.log
+  grep "'mine_1.dat' was unlocked ($id1)" unlock.log
+  grep "'mine_2.dat' was unlocked ($id2)" unlock.log
+  grep "2 of 2 lock(s) unlocked" unlock.log
+  refute_server_lock $id1
+  refute_server_lock $id2
+  assert_server_lock $id3
+
+  GITLFSLOCKSENABLED=1 git lfs unlock --all-mine "theirs.dat" 2>&1 | tee unlock.log
+  grep "Usage: git lfs unlock" unlock.log
+  assert_server_lock $id3
+)
+end_test
+
+begin_test "unlocking a lock from a subdirectory"
+(
+  set -e
+
+  reponame="unlock_from_subdirectory"
+  setup_remote_repo "remote_$reponame"
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat"
+  mkdir -p sub/dir
+  echo "sub" > sub/dir/s.dat
+  git add .gitattributes sub/dir/s.dat
+  git commit -m "add sub/dir/s.dat"
+  git push origin master
+
+  pushd sub > /dev/null
+    GITLFSLOCKSENABLED=1 git lfs lock "dir/s.dat" | tee lock.log
+    id=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+    assert_server_lock $id
+  popd > /dev/null
+
+  GITLFSLOCKSENABLED=1 git lfs locks --path "sub/dir/s.dat" | tee locks.log
+  grep "1 lock(s) matched query" locks.log
+
+  pushd sub/dir > /dev/null
+    GITLFSLOCKSENABLED=1 git lfs unlock "s.dat" 2>&1 | tee unlock.log
+    grep "'s.dat' was unlocked ($id)" unlock.log
+    refute_server_lock $id
+
+    GITLFSLOCKSENABLED=1 git lfs unlock "../../../s.dat" 2>&1 | tee unlock.log
+    grep "is outside repository" unlock.log
+  popd > /dev/null
+)
+end_test
@@ -164,3 +164,43 @@ begin_test "update: outside git repository"
   grep "Not in a git repository" check.log
 )
 end_test
+
+begin_test "update moves objects to the configured sharding depth"
+(
+  set -e
+
+  reponame="update-sharding-depth"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  contents="sharded"
+  oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  assert_local_object "$oid" "${#contents}"
+  [ -f ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid" ]
+
+  git config lfs.storage.shardingdepth 3
+
+  # objects in the old layout are still found
+  git lfs fsck
+  rm a.dat
+  git checkout -- a.dat
+  [ "$contents" = "$(cat a.dat)" ]
+
+  # objects are only moved when asked
+  git lfs update | tee update.log
+  [ "0" = "$(grep -c "storage layout" update.log)" ]
+  [ -f ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid" ]
+
+  git lfs update --migrate-storage | tee update.log
+  grep "Moved 1 object(s) to the current storage layout." update.log
+
+  [ -f ".git/lfs/objects/${oid:0:2}/${oid:2:2}/${oid:4:2}/$oid" ]
+  [ ! -e ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid" ]
+  git lfs fsck
+)
+end_test
@@ -0,0 +1,10 @@
+// +build !linux,!darwin,!freebsd
+
+package tools
+
+// DiskFree returns the number of bytes available to an unprivileged user on
+// the filesystem holding dir, and whether it could be found. It can't be found
+// on this platform.
+func DiskFree(dir string) (uint64, bool) {
+	return 0, false
+}
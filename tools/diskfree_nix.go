@@ -0,0 +1,15 @@
+// +build linux darwin freebsd
+
+package tools
+
+import "syscall"
+
+// DiskFree returns the number of bytes available to an unprivileged user on
+// the filesystem holding dir, and whether it could be found.
+func DiskFree(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
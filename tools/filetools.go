@@ -142,14 +142,35 @@ type FastWalkCallback func(parentDir string, info os.FileInfo, err error)
 //    determine absolute path rather than tracking it yourself
 //  * Automatically ignores any .git directories
 //  * Respects .gitignore contents and skips ignored files/dirs
+//  * Does not follow symlinks to directories
 func FastWalkGitRepo(dir string, cb FastWalkCallback) {
+	FastWalkGitRepoWithOptions(dir, FastWalkOptions{}, cb)
+}
+
+// FastWalkOptions changes how FastWalkGitRepoWithOptions walks a repo. The
+// zero value walks it the same way as FastWalkGitRepo.
+type FastWalkOptions struct {
+	// FollowSymlinks descends into directories that symlinks point to. The
+	// callback is given the target's os.FileInfo for such a symlink. Each
+	// directory is only walked once, so cycles of symlinks are not followed
+	// round.
+	FollowSymlinks bool
+
+	// MaxDepth stops the walk descending more than this many levels below
+	// the root, so that 1 only walks the root's own entries. 0 means no
+	// limit.
+	MaxDepth int
+}
+
+// FastWalkGitRepoWithOptions is FastWalkGitRepo, walking according to opts.
+func FastWalkGitRepoWithOptions(dir string, opts FastWalkOptions, cb FastWalkCallback) {
 	// Ignore all git metadata including subrepos
 	excludePaths := []filepathfilter.Pattern{
 		filepathfilter.NewPattern(".git"),
 		filepathfilter.NewPattern(filepath.Join("**", ".git")),
 	}
 
-	fileCh := fastWalkWithExcludeFiles(dir, ".gitignore", excludePaths)
+	fileCh := fastWalkWithOptions(dir, ".gitignore", excludePaths, opts)
 	for file := range fileCh {
 		cb(file.ParentDir, file.Info, file.Err)
 	}
@@ -164,40 +185,64 @@ type fastWalkInfo struct {
 	Err       error
 }
 
+// fastWalker holds the state shared by all the goroutines of a single walk
+type fastWalker struct {
+	excludeFilename string
+	opts            FastWalkOptions
+	fiChan          chan<- fastWalkInfo
+
+	// This waitgroup will be incremented for each nested goroutine
+	waitg sync.WaitGroup
+
+	// visited holds the real paths of directories walked so far, and is only
+	// used when following symlinks
+	visited   map[string]bool
+	visitedMu sync.Mutex
+}
+
 // fastWalkWithExcludeFiles walks the contents of a dir, respecting
 // include/exclude patterns and also loading new exlude patterns from files
 // named excludeFilename in directories walked
 func fastWalkWithExcludeFiles(dir, excludeFilename string,
 	excludePaths []filepathfilter.Pattern) <-chan fastWalkInfo {
+	return fastWalkWithOptions(dir, excludeFilename, excludePaths, FastWalkOptions{})
+}
+
+func fastWalkWithOptions(dir, excludeFilename string,
+	excludePaths []filepathfilter.Pattern, opts FastWalkOptions) <-chan fastWalkInfo {
 	fiChan := make(chan fastWalkInfo, 256)
-	go fastWalkFromRoot(dir, excludeFilename, excludePaths, fiChan)
+	w := &fastWalker{
+		excludeFilename: excludeFilename,
+		opts:            opts,
+		fiChan:          fiChan,
+		visited:         make(map[string]bool),
+	}
+	go w.walkFromRoot(dir, excludePaths)
 	return fiChan
 }
 
-func fastWalkFromRoot(dir string, excludeFilename string,
-	excludePaths []filepathfilter.Pattern, fiChan chan<- fastWalkInfo) {
-
+func (w *fastWalker) walkFromRoot(dir string, excludePaths []filepathfilter.Pattern) {
 	dirFi, err := os.Stat(dir)
 	if err != nil {
-		fiChan <- fastWalkInfo{Err: err}
+		w.fiChan <- fastWalkInfo{Err: err}
+		close(w.fiChan)
 		return
 	}
 
-	// This waitgroup will be incremented for each nested goroutine
-	var waitg sync.WaitGroup
-	fastWalkFileOrDir(filepath.Dir(dir), dirFi, excludeFilename, excludePaths, fiChan, &waitg)
-	waitg.Wait()
-	close(fiChan)
+	w.walkFileOrDir(filepath.Dir(dir), dirFi, excludePaths, 0)
+	w.waitg.Wait()
+	close(w.fiChan)
 }
 
-// fastWalkFileOrDir is the main recursive implementation of fast walk
+// walkFileOrDir is the main recursive implementation of fast walk
 // Sends the file/dir and any contents to the channel so long as it passes the
 // include/exclude filter. If a dir, parses any excludeFilename found and updates
 // the excludePaths with its content before (parallel) recursing into contents
 // Also splits large directories into multiple goroutines.
 // Increments waitg.Add(1) for each new goroutine launched internally
-func fastWalkFileOrDir(parentDir string, itemFi os.FileInfo, excludeFilename string,
-	excludePaths []filepathfilter.Pattern, fiChan chan<- fastWalkInfo, waitg *sync.WaitGroup) {
+// depth is the number of levels itemFi is below the root
+func (w *fastWalker) walkFileOrDir(parentDir string, itemFi os.FileInfo,
+	excludePaths []filepathfilter.Pattern, depth int) {
 
 	fullPath := filepath.Join(parentDir, itemFi.Name())
 
@@ -205,19 +250,34 @@ func fastWalkFileOrDir(parentDir string, itemFi os.FileInfo, excludeFilename str
 		return
 	}
 
-	fiChan <- fastWalkInfo{ParentDir: parentDir, Info: itemFi}
+	if w.opts.FollowSymlinks && itemFi.Mode()&os.ModeSymlink != 0 {
+		if targetFi, err := os.Stat(fullPath); err == nil && targetFi.IsDir() {
+			itemFi = &renamedFileInfo{FileInfo: targetFi, name: itemFi.Name()}
+		}
+	}
+
+	w.fiChan <- fastWalkInfo{ParentDir: parentDir, Info: itemFi}
 
 	if !itemFi.IsDir() {
 		// Nothing more to do if this is not a dir
 		return
 	}
 
-	if len(excludeFilename) > 0 {
-		possibleExcludeFile := filepath.Join(fullPath, excludeFilename)
+	if w.opts.MaxDepth > 0 && depth >= w.opts.MaxDepth {
+		return
+	}
+
+	if w.opts.FollowSymlinks && !w.visit(fullPath) {
+		// Already walked through another symlink
+		return
+	}
+
+	if len(w.excludeFilename) > 0 {
+		possibleExcludeFile := filepath.Join(fullPath, w.excludeFilename)
 		var err error
 		excludePaths, err = loadExcludeFilename(possibleExcludeFile, fullPath, excludePaths)
 		if err != nil {
-			fiChan <- fastWalkInfo{Err: err}
+			w.fiChan <- fastWalkInfo{Err: err}
 		}
 	}
 
@@ -227,7 +287,7 @@ func fastWalkFileOrDir(parentDir string, itemFi os.FileInfo, excludeFilename str
 	// filepath.Walk as a bonus.
 	df, err := os.Open(fullPath)
 	if err != nil {
-		fiChan <- fastWalkInfo{Err: err}
+		w.fiChan <- fastWalkInfo{Err: err}
 		return
 	}
 	defer df.Close()
@@ -236,24 +296,55 @@ func fastWalkFileOrDir(parentDir string, itemFi os.FileInfo, excludeFilename str
 	jobSize := 100
 	for children, err := df.Readdir(jobSize); err == nil; children, err = df.Readdir(jobSize) {
 		// Parallelise all dirs, and chop large dirs into batches
-		waitg.Add(1)
+		w.waitg.Add(1)
 		go func(subitems []os.FileInfo) {
 			for _, childFi := range subitems {
-				fastWalkFileOrDir(fullPath, childFi, excludeFilename, excludePaths, fiChan, waitg)
+				w.walkFileOrDir(fullPath, childFi, excludePaths, depth+1)
 			}
-			waitg.Done()
+			w.waitg.Done()
 		}(children)
 
 	}
 	if err != nil && err != io.EOF {
-		fiChan <- fastWalkInfo{Err: err}
+		w.fiChan <- fastWalkInfo{Err: err}
+	}
+}
+
+// visit records that the directory at path is being walked, returning false if
+// it, or the directory a symlink at path points to, has already been walked.
+func (w *fastWalker) visit(path string) bool {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		realPath = path
+	}
+
+	w.visitedMu.Lock()
+	defer w.visitedMu.Unlock()
+
+	if w.visited[realPath] {
+		return false
 	}
+	w.visited[realPath] = true
+	return true
+}
+
+// renamedFileInfo is the os.FileInfo of a symlink's target, under the name of
+// the symlink
+type renamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi *renamedFileInfo) Name() string {
+	return fi.name
 }
 
 // loadExcludeFilename reads the given file in gitignore format and returns a
 // revised array of exclude paths if there are any changes.
 // If any changes are made a copy of the array is taken so the original is not
 // modified
+// Lines which are not valid patterns are skipped and reported in the returned
+// error, along with the exclude paths from the rest of the file.
 func loadExcludeFilename(filename, parentDir string, excludePaths []filepathfilter.Pattern) ([]filepathfilter.Pattern, error) {
 	f, err := os.OpenFile(filename, os.O_RDONLY, 0644)
 	if err != nil {
@@ -266,15 +357,21 @@ func loadExcludeFilename(filename, parentDir string, excludePaths []filepathfilt
 
 	retPaths := excludePaths
 	modified := false
+	var badLines []string
 
 	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
+	for lineNum := 1; scanner.Scan(); lineNum++ {
 		line := strings.TrimSpace(scanner.Text())
 		// Skip blanks, comments and negations (not supported right now)
 		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
 			continue
 		}
 
+		if _, err := filepath.Match(line, ""); err != nil {
+			badLines = append(badLines, fmt.Sprintf("%d (%q)", lineNum, line))
+			continue
+		}
+
 		if !modified {
 			// copy on write
 			retPaths = make([]filepathfilter.Pattern, len(excludePaths))
@@ -292,5 +389,12 @@ func loadExcludeFilename(filename, parentDir string, excludePaths []filepathfilt
 		retPaths = append(retPaths, filepathfilter.NewPattern(path))
 	}
 
+	if err := scanner.Err(); err != nil {
+		return retPaths, fmt.Errorf("Error reading %s: %v", filename, err)
+	}
+	if len(badLines) > 0 {
+		return retPaths, fmt.Errorf("Invalid pattern in %s on line %s", filename, strings.Join(badLines, ", "))
+	}
+
 	return retPaths, nil
 }
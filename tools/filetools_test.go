@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/git-lfs/git-lfs/subprocess"
@@ -169,6 +170,158 @@ thisisnot.txt
 
 }
 
+func TestFastWalkGitRepoReportsBadGitignore(t *testing.T) {
+	rootDir, err := ioutil.TempDir(os.TempDir(), "GitLfsTestFastWalkGitRepoReportsBadGitignore")
+	if err != nil {
+		assert.FailNow(t, "Unable to get temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir)
+	os.Chdir(rootDir)
+
+	expectedEntries := createFastWalkInputData(3, 3)
+	mainDir := expectedEntries[0]
+
+	// Can't be read as a file
+	unreadable := filepath.Join(mainDir, "folder1", ".gitignore")
+	os.MkdirAll(unreadable, 0755)
+	expectedEntries = append(expectedEntries, unreadable)
+
+	// The valid patterns either side of a bad one still apply
+	invalid := filepath.Join(mainDir, "folder2", ".gitignore")
+	ioutil.WriteFile(invalid, []byte("subfolder1\n[bad\nsubfolder2\n"), 0644)
+	expectedEntries = append(expectedEntries, invalid)
+	var walkedEntries []string
+	for _, entry := range expectedEntries {
+		if !strings.HasPrefix(entry, filepath.Join(mainDir, "folder2", "subfolder1")) &&
+			!strings.HasPrefix(entry, filepath.Join(mainDir, "folder2", "subfolder2")) {
+			walkedEntries = append(walkedEntries, entry)
+		}
+	}
+
+	gotEntries := make([]string, 0, 100)
+	gotErrors := make([]error, 0, 5)
+	FastWalkGitRepo(mainDir, func(parent string, info os.FileInfo, err error) {
+		if err != nil {
+			gotErrors = append(gotErrors, err)
+		} else {
+			gotEntries = append(gotEntries, filepath.Join(parent, info.Name()))
+		}
+	})
+
+	sort.Strings(walkedEntries)
+	sort.Strings(gotEntries)
+	assert.Equal(t, walkedEntries, gotEntries)
+
+	if assert.Len(t, gotErrors, 2) {
+		messages := []string{gotErrors[0].Error(), gotErrors[1].Error()}
+		sort.Strings(messages)
+		assert.Contains(t, messages[0], unreadable)
+		assert.Contains(t, messages[1], invalid)
+		assert.Contains(t, messages[1], `line 2 ("[bad")`)
+	}
+}
+
+func TestFastWalkGitRepoMaxDepth(t *testing.T) {
+	rootDir, err := ioutil.TempDir(os.TempDir(), "GitLfsTestFastWalkGitRepoMaxDepth")
+	if err != nil {
+		assert.FailNow(t, "Unable to get temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir)
+	os.Chdir(rootDir)
+
+	allEntries := createFastWalkInputData(3, 3)
+	mainDir := allEntries[0]
+
+	// Ignored at the first level below the root, which is still walked
+	ioutil.WriteFile(filepath.Join(mainDir, "folder2", ".gitignore"), []byte("subfolder1\n"), 0644)
+	allEntries = append(allEntries, filepath.Join(mainDir, "folder2", ".gitignore"))
+
+	for _, maxDepth := range []int{1, 2, 3} {
+		var expectedEntries []string
+		for _, entry := range allEntries {
+			rel, _ := filepath.Rel(mainDir, entry)
+			if strings.HasPrefix(rel, filepath.Join("folder2", "subfolder1")) {
+				continue
+			}
+			if rel == "." || len(strings.Split(rel, string(filepath.Separator))) <= maxDepth {
+				expectedEntries = append(expectedEntries, entry)
+			}
+		}
+
+		gotEntries := make([]string, 0, 100)
+		gotErrors := make([]error, 0, 5)
+		FastWalkGitRepoWithOptions(mainDir, FastWalkOptions{MaxDepth: maxDepth}, func(parent string, info os.FileInfo, err error) {
+			if err != nil {
+				gotErrors = append(gotErrors, err)
+			} else {
+				gotEntries = append(gotEntries, filepath.Join(parent, info.Name()))
+			}
+		})
+
+		assert.Empty(t, gotErrors)
+
+		sort.Strings(expectedEntries)
+		sort.Strings(gotEntries)
+		assert.Equal(t, expectedEntries, gotEntries, "max depth %d", maxDepth)
+	}
+}
+
+func TestFastWalkGitRepoSymlinks(t *testing.T) {
+	rootDir, err := ioutil.TempDir(os.TempDir(), "GitLfsTestFastWalkGitRepoSymlinks")
+	if err != nil {
+		assert.FailNow(t, "Unable to get temp dir: %v", err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	mainDir := filepath.Join(rootDir, "repo")
+	targetDir := filepath.Join(rootDir, "target")
+	os.MkdirAll(filepath.Join(mainDir, "folder"), 0755)
+	os.MkdirAll(targetDir, 0755)
+	ioutil.WriteFile(filepath.Join(mainDir, "folder", "file.txt"), []byte("TEST"), 0644)
+	ioutil.WriteFile(filepath.Join(targetDir, "target.txt"), []byte("TEST"), 0644)
+
+	// A cycle back up to the root, and a directory outside of it
+	if err := os.Symlink(mainDir, filepath.Join(mainDir, "folder", "loop")); err != nil {
+		t.Skipf("Unable to create symlink: %v", err)
+	}
+	if err := os.Symlink(targetDir, filepath.Join(mainDir, "linked")); err != nil {
+		t.Skipf("Unable to create symlink: %v", err)
+	}
+
+	expectedEntries := []string{
+		mainDir,
+		filepath.Join(mainDir, "folder"),
+		filepath.Join(mainDir, "folder", "file.txt"),
+		filepath.Join(mainDir, "folder", "loop"),
+		filepath.Join(mainDir, "linked"),
+	}
+
+	walk := func(opts FastWalkOptions) ([]string, []error) {
+		gotEntries := make([]string, 0, 10)
+		gotErrors := make([]error, 0, 5)
+		FastWalkGitRepoWithOptions(mainDir, opts, func(parent string, info os.FileInfo, err error) {
+			if err != nil {
+				gotErrors = append(gotErrors, err)
+			} else {
+				gotEntries = append(gotEntries, filepath.Join(parent, info.Name()))
+			}
+		})
+		sort.Strings(gotEntries)
+		return gotEntries, gotErrors
+	}
+
+	gotEntries, gotErrors := walk(FastWalkOptions{})
+	assert.Empty(t, gotErrors)
+	sort.Strings(expectedEntries)
+	assert.Equal(t, expectedEntries, gotEntries)
+
+	gotEntries, gotErrors = walk(FastWalkOptions{FollowSymlinks: true})
+	assert.Empty(t, gotErrors)
+	expectedEntries = append(expectedEntries, filepath.Join(mainDir, "linked", "target.txt"))
+	sort.Strings(expectedEntries)
+	assert.Equal(t, expectedEntries, gotEntries)
+}
+
 // Make test data - ensure you've Chdir'ed into a temp dir first
 // Returns list of files/dirs that are created
 // First entry is the parent dir of all others
@@ -21,6 +21,10 @@ func Link(oldname, newname string) error {
 	return os.Link(fixLongPath(oldname), fixLongPath(newname))
 }
 
+func Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(fixLongPath(name))
+}
+
 func MkdirAll(path string, perm os.FileMode) error {
 	return os.MkdirAll(fixLongPath(path), perm)
 }
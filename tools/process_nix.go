@@ -0,0 +1,25 @@
+// +build !windows
+
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// ProcessExists returns whether a process with the given pid is running.
+func ProcessExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Signal 0 performs error checking only. EPERM means the process exists,
+	// but belongs to someone else.
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
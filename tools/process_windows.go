@@ -0,0 +1,21 @@
+// +build windows
+
+package tools
+
+import "os"
+
+// ProcessExists returns whether a process with the given pid is running.
+func ProcessExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// On Windows, FindProcess opens a handle to the process, which fails if
+	// it does not exist
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -135,7 +135,10 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 
 		// Actual transfer happens here
 		var err error
-		if expAt, expired := t.Object.IsExpired(transferTime); expired {
+		if abortErr := t.abortErr(); abortErr != nil {
+			tracerx.Printf("xfer: adapter %q worker %d found job for %q aborted", a.Name(), workerNum, t.Object.Oid)
+			err = abortErr
+		} else if expAt, expired := t.Object.IsExpired(transferTime); expired {
 			tracerx.Printf("xfer: adapter %q worker %d found job for %q expired, retrying...", a.Name(), workerNum, t.Object.Oid)
 			err = errors.NewRetriableError(errors.Errorf(
 				"lfs/transfer: object %q expires at %s",
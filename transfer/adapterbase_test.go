@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTransfer never finishes a transfer until it is aborted
+type blockingTransfer struct {
+	started chan *Transfer
+}
+
+func (b *blockingTransfer) WorkerStarting(workerNum int) (interface{}, error) { return nil, nil }
+func (b *blockingTransfer) WorkerEnding(workerNum int, ctx interface{})       {}
+
+func (b *blockingTransfer) DoTransfer(ctx interface{}, t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+	b.started <- t
+	<-t.Context().Done()
+	return t.abortErr()
+}
+
+func TestAdapterBaseEndsOnceAbortedTransfersAreGivenUp(t *testing.T) {
+	impl := &blockingTransfer{started: make(chan *Transfer, 1)}
+	a := newAdapterBase("blocking", Download, impl)
+
+	results := make(chan TransferResult, 1)
+	require.Nil(t, a.Begin(1, nil, results))
+
+	tr := NewTransfer("stuck", &api.ObjectResource{Oid: "stuck"}, "")
+	a.Add(tr)
+	<-impl.started
+
+	ended := make(chan struct{})
+	go func() {
+		a.End()
+		close(ended)
+	}()
+
+	select {
+	case <-ended:
+		t.Fatal("adapter ended before its transfer was given up")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tr.Abort()
+
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter never ended after the transfer was aborted")
+	}
+
+	res := <-results
+	assert.Equal(t, tr, res.Transfer)
+	if assert.NotNil(t, res.Error) {
+		assert.Contains(t, res.Error.Error(), `transfer of "stuck" aborted`)
+	}
+}
+
+func TestAdapterBaseSkipsTransfersAbortedBeforeTheyStart(t *testing.T) {
+	impl := &blockingTransfer{started: make(chan *Transfer, 1)}
+	a := newAdapterBase("blocking", Download, impl)
+
+	results := make(chan TransferResult, 1)
+	require.Nil(t, a.Begin(1, nil, results))
+
+	tr := NewTransfer("stuck", &api.ObjectResource{Oid: "stuck"}, "")
+	tr.Abort()
+	a.Add(tr)
+	a.End()
+
+	assert.Empty(t, impl.started)
+
+	res := <-results
+	assert.NotNil(t, res.Error)
+}
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/errors"
@@ -45,12 +49,17 @@ func (a *basicDownloadAdapter) WorkerEnding(workerNum int, ctx interface{}) {
 }
 
 func (a *basicDownloadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	unlock, err := a.lockDownload(t)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	f, fromByte, hashSoFar, err := a.checkResumeDownload(t)
 	if err != nil {
 		return err
 	}
-	return a.download(t, cb, authOkFunc, f, fromByte, hashSoFar)
+	return a.download(t, cb, authOkFunc, f, fromByte, hashSoFar, true)
 }
 
 // Checks to see if a download can be resumed, and if so returns a non-nil locked file, byte start and hash
@@ -84,8 +93,163 @@ func (a *basicDownloadAdapter) downloadFilename(t *Transfer) string {
 	return filepath.Join(a.tempDir(), t.Object.Oid+".tmp")
 }
 
-// download starts or resumes and download. Always closes dlFile if non-nil
-func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback, authOkFunc func(), dlFile *os.File, fromByte int64, hash hash.Hash) error {
+// The advisory lock file guarding downloadFilename against concurrent writers
+func (a *basicDownloadAdapter) lockFilename(t *Transfer) string {
+	return filepath.Join(a.tempDir(), t.Object.Oid+".lock")
+}
+
+// lockDownload takes the advisory lock on the download file for t, which holds
+// the pid of the owning process. A lock left behind by a process which is no
+// longer running is taken over, so that its partial download can be resumed.
+// Returns a func which releases the lock.
+func (a *basicDownloadAdapter) lockDownload(t *Transfer) (func(), error) {
+	return lockDownloadFile(a.lockFilename(t), t.Object.Oid)
+}
+
+// downloadLocks are the download locks held by this process. A lock file only
+// records the pid of its owner, so one holding our own pid which isn't in here
+// was left behind by an earlier download, and is stale.
+var (
+	downloadLocks      = make(map[string]bool)
+	downloadLocksMutex sync.Mutex
+)
+
+// emptyLockGrace is how long a lock without a pid is assumed to still be in
+// the middle of being written. That can only be seen on filesystems without
+// hard links, where the pid has to be written after the lock is created.
+const emptyLockGrace = 10 * time.Second
+
+// lockDownloadFile takes the lock at lockfile for the download of oid. The
+// pid is written to a temporary file which is then linked into place, so that
+// the lock is never seen without its owner. A stale lock is taken over by
+// renaming a fresh one over it.
+func lockDownloadFile(lockfile, oid string) (func(), error) {
+	downloadLocksMutex.Lock()
+	defer downloadLocksMutex.Unlock()
+
+	if downloadLocks[lockfile] {
+		return nil, errors.NewRetriableError(fmt.Errorf("Download of %v is in progress in process %d", oid, os.Getpid()))
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(lockfile), filepath.Base(lockfile)+"-")
+	if err != nil {
+		return nil, err
+	}
+	defer longpathos.Remove(tmp.Name())
+
+	_, err = fmt.Fprintf(tmp, "%d", os.Getpid())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = longpathos.Link(tmp.Name(), lockfile)
+	if err != nil && !os.IsExist(err) {
+		tracerx.Printf("xfer: can't link download lock for %q, creating it instead: %v", oid, err)
+		err = createDownloadLock(lockfile)
+	}
+	if err == nil {
+		return holdDownloadLock(lockfile), nil
+	}
+	if !os.IsExist(err) {
+		return nil, err
+	}
+
+	pid, live, err := downloadLockOwner(lockfile)
+	if err != nil {
+		return nil, err
+	}
+	if live {
+		if pid > 0 {
+			return nil, errors.NewRetriableError(fmt.Errorf("Download of %v is in progress in process %d", oid, pid))
+		}
+		return nil, errors.NewRetriableError(fmt.Errorf("Download of %v is in progress", oid))
+	}
+
+	tracerx.Printf("xfer: taking over stale download lock for %q", oid)
+	if err := longpathos.Rename(tmp.Name(), lockfile); err != nil {
+		return nil, err
+	}
+
+	// Another process may be taking over the same lock, in which case only
+	// the last rename wins
+	if pid, _, err := downloadLockOwner(lockfile); err != nil || pid != os.Getpid() {
+		return nil, errors.NewRetriableError(fmt.Errorf("Could not lock download of %v", oid))
+	}
+	return holdDownloadLock(lockfile), nil
+}
+
+// createDownloadLock creates lockfile exclusively and writes our pid to it,
+// for filesystems which can't link the lock into place.
+func createDownloadLock(lockfile string) error {
+	f, err := longpathos.OpenFile(lockfile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(f, "%d", os.Getpid())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		longpathos.Remove(lockfile)
+	}
+	return err
+}
+
+// downloadLockOwner returns the pid held by the lock at lockfile, and whether
+// that process still owns it. It must be called with downloadLocksMutex held.
+func downloadLockOwner(lockfile string) (int, bool, error) {
+	by, err := ioutil.ReadFile(lockfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(by)))
+	if err != nil {
+		fi, err := longpathos.Stat(lockfile)
+		return 0, err == nil && time.Since(fi.ModTime()) < emptyLockGrace, nil
+	}
+	if pid == os.Getpid() {
+		return pid, downloadLocks[lockfile], nil
+	}
+	return pid, tools.ProcessExists(pid), nil
+}
+
+// holdDownloadLock records that this process holds the lock at lockfile, and
+// returns a func which releases it. It must be called with
+// downloadLocksMutex held.
+func holdDownloadLock(lockfile string) func() {
+	downloadLocks[lockfile] = true
+	return func() {
+		downloadLocksMutex.Lock()
+		defer downloadLocksMutex.Unlock()
+
+		delete(downloadLocks, lockfile)
+		longpathos.Remove(lockfile)
+	}
+}
+
+// cacheHref returns the URL of the object in the read-through cache configured
+// with lfs.cacheurl, or an empty string if no cache is configured.
+func (a *basicDownloadAdapter) cacheHref(t *Transfer) string {
+	cacheUrl, _ := config.Config.Git.Get("lfs.cacheurl")
+	if len(cacheUrl) == 0 {
+		return ""
+	}
+	return strings.TrimRight(cacheUrl, "/") + "/" + t.Object.Oid
+}
+
+// download starts or resumes and download. Always closes dlFile if non-nil.
+// If tryCache is true and lfs.cacheurl is set, the object is requested from
+// the cache first, falling back to the server's download action if the cache
+// misses, fails, or answers with the wrong content.
+func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback, authOkFunc func(), dlFile *os.File, fromByte int64, hash hash.Hash, tryCache bool) error {
 	if dlFile != nil {
 		// ensure we always close dlFile. Note that this does not conflict with the
 		// early close below, as close is idempotent.
@@ -94,13 +258,25 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 
 	rel, ok := t.Object.Rel("download")
 	if !ok {
-		return errors.New("Object not found on the server.")
+		return errors.NewObjectMissingError(errors.New("Object not found on the server."), t.Object.Oid)
+	}
+
+	fromCache := false
+	href, header, needsAuth := rel.Href, rel.Header, t.Object.NeedsAuth()
+	if tryCache {
+		if cacheHref := a.cacheHref(t); len(cacheHref) > 0 {
+			// The action headers & credentials are for the server, don't
+			// leak them to the cache
+			fromCache = true
+			href, header, needsAuth = cacheHref, nil, false
+		}
 	}
 
-	req, err := httputil.NewHttpRequest("GET", rel.Href, rel.Header)
+	req, err := httputil.NewHttpRequest("GET", href, header)
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(t.Context())
 
 	if fromByte > 0 {
 		if dlFile == nil || hash == nil {
@@ -110,18 +286,40 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", fromByte, t.Object.Size-1))
 	}
 
-	res, err := httputil.DoHttpRequest(config.Config, req, t.Object.NeedsAuth())
+	res, err := httputil.DoHttpRequest(config.Config, req, needsAuth)
 	if err != nil {
+		// The cache is only ever a shortcut, so try the server instead
+		if fromCache {
+			if res != nil && res.StatusCode == 404 {
+				tracerx.Printf("xfer: cache miss for %q, downloading from server", t.Object.Oid)
+			} else {
+				tracerx.Printf("xfer: cache failed for %q, downloading from server: %v", t.Object.Oid, err)
+			}
+			return a.download(t, cb, authOkFunc, dlFile, fromByte, hash, false)
+		}
 		// Special-case status code 416 () - fall back
 		if fromByte > 0 && dlFile != nil && res.StatusCode == 416 {
 			tracerx.Printf("xfer: server rejected resume download request for %q from byte %d; re-downloading from start", t.Object.Oid, fromByte)
 			dlFile.Close()
 			longpathos.Remove(dlFile.Name())
-			return a.download(t, cb, authOkFunc, nil, 0, nil)
+			return a.download(t, cb, authOkFunc, nil, 0, nil, tryCache)
 		}
 		return errors.NewRetriableError(err)
 	}
-	httputil.LogTransfer(config.Config, "lfs.data.download", res)
+	t.CacheStatus = httputil.CacheStatus(config.Config, res)
+	if fromCache {
+		tracerx.Printf("xfer: cache hit for %q", t.Object.Oid)
+		httputil.LogTransfer(config.Config, "lfs.data.download.cache", res)
+		if len(t.CacheStatus) == 0 {
+			// lfs.cacheurl answered, so it was a hit even if it doesn't say
+			t.CacheStatus = "hit"
+		}
+	} else {
+		httputil.LogTransfer(config.Config, "lfs.data.download", res)
+	}
+	if len(t.CacheStatus) > 0 {
+		tracerx.Printf("xfer: download of %q has cache status %q", t.Object.Oid, t.CacheStatus)
+	}
 	defer res.Body.Close()
 
 	// Range request must return 206 & content range to confirm
@@ -166,7 +364,7 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 				hash = nil
 			} else {
 				// re-request needed
-				return a.download(t, cb, authOkFunc, nil, 0, nil)
+				return a.download(t, cb, authOkFunc, nil, 0, nil, tryCache)
 			}
 		}
 	}
@@ -198,6 +396,9 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 	dlfilename := dlFile.Name()
 	// Wrap callback to give name context
 	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if err := t.abortErr(); err != nil {
+			return err
+		}
 		if cb != nil {
 			return cb(t.Name, totalSize, readSoFar+fromByte, readSinceLast)
 		}
@@ -212,7 +413,22 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 	}
 
 	if actual := hasher.Hash(); actual != t.Object.Oid {
-		return fmt.Errorf("Expected OID %s, got %s after %d bytes written", t.Object.Oid, actual, written)
+		err := fmt.Errorf("Expected OID %s, got %s after %d bytes written", t.Object.Oid, actual, written)
+		if fromCache {
+			tracerx.Printf("xfer: cache failed for %q, downloading from server: %v", t.Object.Oid, err)
+			longpathos.Remove(dlfilename)
+			// authOkFunc has already been called for the cache's response
+			return a.download(t, cb, nil, nil, 0, nil, false)
+		}
+		if fromByte == 0 {
+			return err
+		}
+
+		// The bytes kept from an earlier attempt may be what's wrong, so
+		// don't resume from them again
+		tracerx.Printf("xfer: discarding partial download of %q resumed from byte %d", t.Object.Oid, fromByte)
+		longpathos.Remove(dlfilename)
+		return errors.NewRetriableError(err)
 	}
 
 	return tools.RenameFileCopyPermissions(dlfilename, t.Path)
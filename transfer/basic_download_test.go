@@ -0,0 +1,106 @@
+package transfer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockDownloadFileIsTakenOnceWhenRacing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	lockfile := filepath.Join(dir, "oid.lock")
+
+	var mu sync.Mutex
+	var unlocks []func()
+	var busy int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := lockDownloadFile(lockfile, "oid")
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				unlocks = append(unlocks, unlock)
+			} else {
+				assert.True(t, errors.IsRetriableError(err), "unexpected error: %v", err)
+				busy++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, unlocks, 1)
+	assert.Equal(t, 19, busy)
+
+	for _, unlock := range unlocks {
+		unlock()
+	}
+
+	// only the lock itself was ever left behind
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Empty(t, files)
+
+	unlock, err := lockDownloadFile(lockfile, "oid")
+	require.Nil(t, err)
+	unlock()
+}
+
+func TestLockDownloadFileTakesOverStaleLocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	lockfile := filepath.Join(dir, "oid.lock")
+
+	// a process which has gone away, and a download of our own which never
+	// released its lock
+	for _, pid := range []int{2147483646, os.Getpid()} {
+		require.Nil(t, ioutil.WriteFile(lockfile, []byte(strconv.Itoa(pid)), 0644))
+
+		unlock, err := lockDownloadFile(lockfile, "oid")
+		require.Nil(t, err, "lock held by %d", pid)
+
+		by, err := ioutil.ReadFile(lockfile)
+		require.Nil(t, err)
+		assert.Equal(t, strconv.Itoa(os.Getpid()), string(by))
+
+		unlock()
+		_, err = os.Stat(lockfile)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestLockDownloadFileLeavesLiveLocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	lockfile := filepath.Join(dir, "oid.lock")
+	owner := strconv.Itoa(os.Getppid())
+	require.Nil(t, ioutil.WriteFile(lockfile, []byte(owner), 0644))
+
+	_, err = lockDownloadFile(lockfile, "oid")
+	require.NotNil(t, err)
+	assert.True(t, errors.IsRetriableError(err))
+	assert.Contains(t, err.Error(), "in progress in process "+owner)
+
+	by, err := ioutil.ReadFile(lockfile)
+	require.Nil(t, err)
+	assert.Equal(t, owner, string(by))
+}
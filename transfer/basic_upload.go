@@ -67,6 +67,7 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 	}
 
 	req.ContentLength = t.Object.Size
+	req = req.WithContext(t.Context())
 
 	f, err := longpathos.OpenFile(t.Path, os.O_RDONLY, 0644)
 	if err != nil {
@@ -77,6 +78,9 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 	// Ensure progress callbacks made while uploading
 	// Wrap callback to give name context
 	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if err := t.abortErr(); err != nil {
+			return err
+		}
 		if cb != nil {
 			return cb(t.Name, totalSize, readSoFar, readSinceLast)
 		}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"os/exec"
@@ -16,6 +15,7 @@ import (
 	"github.com/git-lfs/git-lfs/tools"
 
 	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/subprocess"
 	"github.com/rubyist/tracerx"
 
@@ -270,7 +270,11 @@ func (a *customAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferProg
 
 	rel, ok := t.Object.Rel(a.getOperationName())
 	if !ok {
-		return errors.New("Object not found on the server.")
+		err := errors.New("Object not found on the server.")
+		if a.direction == Download {
+			return errors.NewObjectMissingError(err, t.Object.Oid)
+		}
+		return err
 	}
 	var req *customAdapterTransferRequest
 	if a.direction == Upload {
@@ -1,6 +1,7 @@
 package transfer
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/git-lfs/git-lfs/config"
@@ -9,6 +10,7 @@ import (
 
 type Manifest struct {
 	basicTransfersOnly   bool
+	tusMinSize           int64
 	downloadAdapterFuncs map[string]NewTransferAdapterFunc
 	uploadAdapterFuncs   map[string]NewTransferAdapterFunc
 	mu                   sync.Mutex
@@ -28,6 +30,7 @@ func ConfigureManifest(m *Manifest, cfg *config.Configuration) *Manifest {
 	configureBasicUploadAdapter(m)
 	if cfg.TusTransfersAllowed() {
 		configureTusAdapter(m)
+		m.tusMinSize = cfg.TusMinSize()
 	}
 	configureCustomAdapters(cfg, m)
 	return m
@@ -44,6 +47,25 @@ func (m *Manifest) GetAdapterNames(dir Direction) []string {
 	return nil
 }
 
+// GetAdapterNamesForSize returns the names of the adapters available to
+// transfer an object of the given size. They are those of GetAdapterNames,
+// except tus for objects smaller than lfs.tus.minsize, which aren't worth the
+// overhead of a resumable upload.
+func (m *Manifest) GetAdapterNamesForSize(dir Direction, size int64) []string {
+	names := m.GetAdapterNames(dir)
+	if size >= m.tusMinSize {
+		return names
+	}
+
+	ret := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != TusAdapterName {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
 // GetDownloadAdapterNames returns a list of the names of download adapters available to be created
 func (m *Manifest) GetDownloadAdapterNames() []string {
 	return m.getAdapterNames(m.downloadAdapterFuncs)
@@ -54,7 +76,8 @@ func (m *Manifest) GetUploadAdapterNames() []string {
 	return m.getAdapterNames(m.uploadAdapterFuncs)
 }
 
-// getAdapterNames returns a list of the names of adapters available to be created
+// getAdapterNames returns a sorted list of the names of adapters available to
+// be created
 func (m *Manifest) getAdapterNames(adapters map[string]NewTransferAdapterFunc) []string {
 	if m.basicTransfersOnly {
 		return []string{BasicAdapterName}
@@ -67,6 +90,7 @@ func (m *Manifest) getAdapterNames(adapters map[string]NewTransferAdapterFunc) [
 	for n, _ := range adapters {
 		ret = append(ret, n)
 	}
+	sort.Strings(ret)
 	return ret
 }
 
@@ -2,7 +2,13 @@
 // NOTE: Subject to change, do not rely on this package from outside git-lfs source
 package transfer
 
-import "github.com/git-lfs/git-lfs/api"
+import (
+	"context"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/api"
+	"github.com/git-lfs/git-lfs/errors"
+)
 
 type Direction int
 
@@ -66,11 +72,50 @@ type Transfer struct {
 	// Path for uploads is the source of data to send, for downloads is the
 	// location to place the final result
 	Path string
+	// CacheStatus is set by adapters which can tell whether a cache in front
+	// of the server answered the transfer, "hit" or "miss" usually, or blank
+	// if unknown
+	CacheStatus string
+
+	// ctx is done once Abort is called, and is created on first use by
+	// ctxMutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	ctxMutex sync.Mutex
 }
 
 // NewTransfer creates a new Transfer instance
 func NewTransfer(name string, obj *api.ObjectResource, path string) *Transfer {
-	return &Transfer{name, obj, path}
+	return &Transfer{Name: name, Object: obj, Path: path}
+}
+
+// Context returns a context which is done once the transfer has been aborted.
+// Adapters should make their requests with it, so that aborting the transfer
+// interrupts them.
+func (t *Transfer) Context() context.Context {
+	t.ctxMutex.Lock()
+	defer t.ctxMutex.Unlock()
+
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+	}
+	return t.ctx
+}
+
+// Abort asks the adapter to give up the transfer, which it then finishes
+// with an error as soon as it can. Adapters which can't interrupt a transfer
+// finish it as usual.
+func (t *Transfer) Abort() {
+	t.Context()
+	t.cancel()
+}
+
+// abortErr returns a non-nil error once the transfer has been aborted.
+func (t *Transfer) abortErr() error {
+	if t.Context().Err() == nil {
+		return nil
+	}
+	return errors.Errorf("Git LFS: transfer of %q aborted", t.Object.Oid)
 }
 
 // Result of a transfer returned through CompletionChannel()
@@ -138,3 +138,26 @@ func testAdapterRegButBasicOnly(t *testing.T) {
 	lu := m.GetUploadAdapterNames()
 	assert.Equal([]string{BasicAdapterName}, lu)
 }
+
+func TestTusOnlyOfferedForObjectsOverMinSize(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{
+			"lfs.tustransfers": "true",
+			"lfs.tus.minsize":  "100",
+		},
+	})
+	m := ConfigureManifest(NewManifest(), cfg)
+
+	assert.Equal(t, []string{BasicAdapterName}, m.GetAdapterNamesForSize(Upload, 99))
+	assert.Equal(t, []string{BasicAdapterName, TusAdapterName}, m.GetAdapterNamesForSize(Upload, 100))
+	assert.Equal(t, []string{BasicAdapterName}, m.GetAdapterNamesForSize(Download, 100))
+}
+
+func TestTusOfferedForAllObjectsWithoutMinSize(t *testing.T) {
+	cfg := config.NewFrom(config.Values{
+		Git: map[string]string{"lfs.tustransfers": "true"},
+	})
+	m := ConfigureManifest(NewManifest(), cfg)
+
+	assert.Equal(t, []string{BasicAdapterName, TusAdapterName}, m.GetAdapterNamesForSize(Upload, 0))
+}
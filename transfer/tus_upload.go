@@ -110,10 +110,14 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferP
 	req.Header.Set("Content-Type", "application/offset+octet-stream")
 	req.Header.Set("Content-Length", strconv.FormatInt(t.Object.Size-offset, 10))
 	req.ContentLength = t.Object.Size - offset
+	req = req.WithContext(t.Context())
 
 	// Ensure progress callbacks made while uploading
 	// Wrap callback to give name context
 	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if err := t.abortErr(); err != nil {
+			return err
+		}
 		if cb != nil {
 			return cb(t.Name, totalSize, readSoFar, readSinceLast)
 		}